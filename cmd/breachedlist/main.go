@@ -0,0 +1,69 @@
+// main.go
+// Утилита собирает компактный список утёкших паролей для PASSWORD_BREACHED_LIST_FILE.
+// Читает из stdin по паролю на строку либо, с флагом -sha1, строки формата
+// Pwned Passwords (HEX40 или HEX40:count), и пишет список в файл -o.
+//
+//	go run ./cmd/breachedlist -o breached.bin < passwords.txt
+//	go run ./cmd/breachedlist -sha1 -o breached.bin < pwned-passwords-sha1.txt
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"kvant_task/internal/password"
+)
+
+func main() {
+	out := flag.String("o", "", "файл, в который записывается список")
+	hashed := flag.Bool("sha1", false, "на входе SHA-1 хэши в hex, а не пароли")
+	flag.Parse()
+	if *out == "" {
+		log.Fatal("[breachedlist] не указан файл -o")
+	}
+
+	var hashes [][sha1.Size]byte
+	sc := bufio.NewScanner(os.Stdin)
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		if !*hashed {
+			if text != "" {
+				hashes = append(hashes, sha1.Sum([]byte(text)))
+			}
+			continue
+		}
+		h, _, _ := strings.Cut(strings.TrimSpace(text), ":")
+		if h == "" {
+			continue
+		}
+		b, err := hex.DecodeString(h)
+		if err != nil || len(b) != sha1.Size {
+			log.Fatalf("[breachedlist] строка %d: некорректный SHA-1 %q", line, h)
+		}
+		hashes = append(hashes, [sha1.Size]byte(b))
+	}
+	if err := sc.Err(); err != nil {
+		log.Fatalf("[breachedlist] ошибка чтения: %v", err)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("[breachedlist] %v", err)
+	}
+	w := bufio.NewWriter(f)
+	if err := password.WriteBreachedList(w, hashes); err != nil {
+		log.Fatalf("[breachedlist] ошибка записи: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatalf("[breachedlist] ошибка записи: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("[breachedlist] ошибка записи: %v", err)
+	}
+	log.Printf("[breachedlist] записано паролей: %d", len(hashes))
+}
@@ -11,6 +11,10 @@
 // @in header
 // @name Authorization
 
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+
 package main
 
 import (
@@ -26,7 +30,11 @@ import (
 
 	"kvant_task/internal/bootstrap"
 	"kvant_task/internal/config"
+	"kvant_task/internal/idempotency"
+	"kvant_task/internal/lockout"
+	"kvant_task/internal/revocation"
 	"kvant_task/internal/router"
+	"kvant_task/internal/services"
 )
 
 // main.go
@@ -48,8 +56,45 @@ func main() {
 	}
 	log.Println("[main] Подключение к БД успешно")
 
+	// Хранилище отозванных токенов и его периодическая очистка
+	revoked := bootstrap.RevocationStore(cfg, db)
+	gcCtx, stopGC := context.WithCancel(context.Background())
+	defer stopGC()
+	go revocation.RunGC(gcCtx, revoked, cfg.Revocation.GCInterval)
+
+	// Счётчики неудачных входов и их периодическая очистка
+	attempts := bootstrap.LockoutStore(cfg, db)
+	go lockout.RunGC(gcCtx, attempts, cfg.Lockout.GCInterval)
+
+	// Ключи идемпотентности и их периодическая очистка
+	idem := bootstrap.IdempotencyStore(cfg, db)
+	go idempotency.RunGC(gcCtx, idem, cfg.Idempotency.GCInterval)
+
+	// Единый сервис выпуска и проверки токенов
+	tokens, err := services.NewTokenService(cfg, revoked)
+	if err != nil {
+		log.Fatalf("[main] ошибка ключей JWT: %v", err)
+	}
+
+	// Политика паролей со списком утёкших паролей
+	policy, err := bootstrap.PasswordPolicy(cfg)
+	if err != nil {
+		log.Fatalf("[main] ошибка списка утёкших паролей: %v", err)
+	}
+
+	// Тарифы доставки
+	shipping, err := bootstrap.ShippingCalculator(cfg)
+	if err != nil {
+		log.Fatalf("[main] ошибка тарифов доставки: %v", err)
+	}
+
+	// Платёжный провайдер и сверка зависших платежей
+	payments := bootstrap.PaymentProvider(cfg)
+	go services.NewPaymentService(db, cfg, payments).RunReconcile(gcCtx, cfg.Payments.ReconcileInterval)
+
 	// Инициализация роутера
-	r := router.New(db, cfg)
+	r := router.New(db, cfg, tokens, bootstrap.Notifier(cfg), bootstrap.LoginGuard(cfg, attempts), policy, idem,
+		bootstrap.TaxCalculator(cfg), shipping, payments)
 
 	// HTTP-сервер
 	srv := &http.Server{
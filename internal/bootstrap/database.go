@@ -5,6 +5,7 @@ import (
 
 	"kvant_task/internal/config"
 	"kvant_task/internal/models"
+	"kvant_task/internal/money"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -17,8 +18,18 @@ func Database(cfg *config.Config) (*gorm.DB, error) {
 		return nil, fmt.Errorf("подключение к БД: %w", err)
 	}
 	// Авто-миграция моделей
-	if err := db.AutoMigrate(&models.User{}, &models.Order{}); err != nil {
+	if err := db.AutoMigrate(&models.User{}, &models.Product{}, &models.Coupon{}, &models.Order{}, &models.OrderItem{}, &models.OrderTaxLine{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.OneTimeToken{}, &models.LoginAttempt{}, &models.APIKey{}, &models.Session{}, &models.OrderStatusChange{}, &models.IdempotencyKey{}, &models.CouponRedemption{}, &models.Payment{}, &models.PaymentEvent{}); err != nil {
 		return nil, fmt.Errorf("миграция БД: %w", err)
 	}
+	cur, err := money.Lookup(cfg.Money.DefaultCurrency)
+	if err != nil {
+		return nil, err
+	}
+	if err := ConvertSingleItemOrders(db, cur); err != nil {
+		return nil, fmt.Errorf("перенос заказов в order_items: %w", err)
+	}
+	if err := ConvertMoneyToMinorUnits(db, cur); err != nil {
+		return nil, fmt.Errorf("перевод сумм в минимальные единицы: %w", err)
+	}
 	return db, nil
 }
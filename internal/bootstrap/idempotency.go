@@ -0,0 +1,16 @@
+package bootstrap
+
+import (
+	"kvant_task/internal/config"
+	"kvant_task/internal/idempotency"
+
+	"gorm.io/gorm"
+)
+
+// IdempotencyStore создаёт хранилище ключей идемпотентности согласно конфигурации.
+func IdempotencyStore(cfg *config.Config, db *gorm.DB) idempotency.Store {
+	if cfg.Idempotency.Store == "memory" {
+		return idempotency.NewMemoryStore()
+	}
+	return idempotency.NewPostgresStore(db)
+}
@@ -0,0 +1,27 @@
+package bootstrap
+
+import (
+	"kvant_task/internal/config"
+	"kvant_task/internal/lockout"
+
+	"gorm.io/gorm"
+)
+
+// LockoutStore создаёт хранилище счётчиков неудачных входов согласно конфигурации.
+func LockoutStore(cfg *config.Config, db *gorm.DB) lockout.Store {
+	if cfg.Lockout.Store == "memory" {
+		return lockout.NewMemoryStore()
+	}
+	return lockout.NewPostgresStore(db)
+}
+
+// LoginGuard создаёт защиту от перебора паролей поверх хранилища store.
+func LoginGuard(cfg *config.Config, store lockout.Store) *lockout.Guard {
+	return lockout.NewGuard(store, lockout.Policy{
+		Threshold:   cfg.Lockout.Threshold,
+		IPThreshold: cfg.Lockout.IPThreshold,
+		BaseDelay:   cfg.Lockout.BaseDelay,
+		MaxDelay:    cfg.Lockout.MaxDelay,
+		Window:      cfg.Lockout.Window,
+	})
+}
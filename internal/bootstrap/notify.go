@@ -0,0 +1,14 @@
+package bootstrap
+
+import (
+	"kvant_task/internal/config"
+	"kvant_task/internal/notify"
+)
+
+// Notifier создаёт транспорт уведомлений согласно конфигурации.
+func Notifier(cfg *config.Config) notify.Notifier {
+	if cfg.Notify.Transport == "file" {
+		return notify.NewFileNotifier(cfg.Notify.File)
+	}
+	return notify.NewLogNotifier()
+}
@@ -0,0 +1,81 @@
+package bootstrap
+
+import (
+	"fmt"
+	"log"
+
+	"kvant_task/internal/models"
+	"kvant_task/internal/money"
+
+	"gorm.io/gorm"
+)
+
+// ConvertSingleItemOrders переносит заказы старого формата (product, quantity и price
+// в самой таблице orders) в позиции order_items и удаляет старые колонки.
+// Старые цены считаются ценами в валюте cur.
+// Вызывается после AutoMigrate, который создаёт order_items и колонки сумм.
+// Повторный вызов ничего не делает.
+func ConvertSingleItemOrders(db *gorm.DB, cur money.Currency) error {
+	if !db.Migrator().HasColumn(&models.Order{}, "product") {
+		return nil
+	}
+	log.Println("[bootstrap] converting single-item orders to order_items")
+	scale := cur.Scale()
+	return db.Transaction(func(tx *gorm.DB) error {
+		stmts := []struct {
+			sql  string
+			args []interface{}
+		}{
+			{`INSERT INTO order_items (order_id, product, quantity, price_minor)
+			  SELECT id, product, quantity, ROUND(price * ?) FROM orders`, []interface{}{scale}},
+			{`UPDATE orders SET currency = ?, subtotal_minor = ROUND(quantity * price * ?), total_minor = ROUND(quantity * price * ?)`,
+				[]interface{}{cur.Code, scale, scale}},
+			{`ALTER TABLE orders DROP COLUMN product, DROP COLUMN quantity, DROP COLUMN price`, nil},
+		}
+		for _, q := range stmts {
+			if err := tx.Exec(q.sql, q.args...).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ConvertMoneyToMinorUnits переводит суммы, хранившиеся в numeric с копейками
+// (products.price, order_items.price, orders.subtotal и orders.total), в целые
+// минимальные единицы валюты cur и удаляет старые колонки.
+// Вызывается после AutoMigrate, который создаёт новые колонки. Повторный вызов ничего не делает.
+func ConvertMoneyToMinorUnits(db *gorm.DB, cur money.Currency) error {
+	columns := []struct {
+		model    interface{}
+		table    string
+		from, to string
+		currency bool
+	}{
+		{&models.Product{}, "products", "price", "price_minor", true},
+		{&models.OrderItem{}, "order_items", "price", "price_minor", false},
+		{&models.Order{}, "orders", "subtotal", "subtotal_minor", true},
+		{&models.Order{}, "orders", "total", "total_minor", false},
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, c := range columns {
+			if !tx.Migrator().HasColumn(c.model, c.from) {
+				continue
+			}
+			log.Printf("[bootstrap] converting %s.%s to minor units of %s", c.table, c.from, cur.Code)
+			set := fmt.Sprintf("%s = ROUND(%s * ?)", c.to, c.from)
+			args := []interface{}{cur.Scale()}
+			if c.currency {
+				set += ", currency = ?"
+				args = append(args, cur.Code)
+			}
+			if err := tx.Exec("UPDATE "+c.table+" SET "+set, args...).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("ALTER TABLE " + c.table + " DROP COLUMN " + c.from).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,29 @@
+package bootstrap
+
+import (
+	"kvant_task/internal/config"
+	"kvant_task/internal/password"
+)
+
+// PasswordPolicy создаёт политику паролей согласно конфигурации.
+// Список утёкших паролей читается из файла или берётся встроенный.
+func PasswordPolicy(cfg *config.Config) (*password.Policy, error) {
+	policy := &password.Policy{
+		MinLength:      cfg.Password.MinLength,
+		MinClasses:     cfg.Password.MinCharClasses,
+		RejectPersonal: cfg.Password.RejectPersonal,
+	}
+	if !cfg.Password.BreachedCheck {
+		return policy, nil
+	}
+	if cfg.Password.BreachedListFile == "" {
+		policy.Breached = password.DefaultBreachedList()
+		return policy, nil
+	}
+	list, err := password.LoadBreachedList(cfg.Password.BreachedListFile)
+	if err != nil {
+		return nil, err
+	}
+	policy.Breached = list
+	return policy, nil
+}
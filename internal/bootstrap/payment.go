@@ -0,0 +1,11 @@
+package bootstrap
+
+import (
+	"kvant_task/internal/config"
+	"kvant_task/internal/payment"
+)
+
+// PaymentProvider создаёт платёжного провайдера согласно конфигурации.
+func PaymentProvider(cfg *config.Config) payment.PaymentProvider {
+	return payment.NewFakeProvider(cfg.Payments.WebhookSecret)
+}
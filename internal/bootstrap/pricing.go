@@ -0,0 +1,63 @@
+package bootstrap
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"kvant_task/internal/config"
+	"kvant_task/internal/money"
+	"kvant_task/internal/pricing"
+)
+
+// TaxCalculator создаёт расчёт налога согласно конфигурации.
+func TaxCalculator(cfg *config.Config) pricing.TaxCalculator {
+	switch cfg.Tax.Calculator {
+	case "flat":
+		return pricing.FlatTax{Name: cfg.Tax.Name, Rate: cfg.Tax.Rate}
+	case "category":
+		return pricing.CategoryTax{Name: cfg.Tax.Name, Default: cfg.Tax.Rate, Rates: cfg.Tax.Categories}
+	}
+	return pricing.NoTax{}
+}
+
+// ShippingCalculator создаёт расчёт доставки согласно конфигурации.
+// Тарифы SHIPPING_TIERS имеют вид "1000:199,5000:349,*:599": граница в граммах
+// (для weight) или в валюте тарифов (для price) и стоимость доставки в валюте тарифов.
+func ShippingCalculator(cfg *config.Config) (pricing.ShippingCalculator, error) {
+	if cfg.Shipping.Calculator == "none" || cfg.Shipping.Calculator == "" {
+		return pricing.NoShipping{}, nil
+	}
+	cur, err := money.Lookup(cfg.Shipping.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("SHIPPING_CURRENCY: %w", err)
+	}
+	calc := pricing.TieredShipping{Basis: cfg.Shipping.Calculator, Currency: cur.Code}
+	for _, item := range strings.Split(cfg.Shipping.Tiers, ",") {
+		upTo, fee, ok := strings.Cut(strings.TrimSpace(item), ":")
+		if !ok {
+			return nil, fmt.Errorf("SHIPPING_TIERS: некорректный тариф %q, ожидается граница:стоимость", item)
+		}
+		var t pricing.Tier
+		switch {
+		case upTo == "*":
+			t.UpTo = math.MaxInt64
+		case calc.Basis == pricing.ByWeight:
+			t.UpTo, err = strconv.ParseInt(upTo, 10, 64)
+		default:
+			t.UpTo, err = cur.Parse(upTo)
+		}
+		if err != nil || t.UpTo < 0 {
+			return nil, fmt.Errorf("SHIPPING_TIERS: некорректная граница %q", upTo)
+		}
+		if t.FeeMinor, err = cur.Parse(fee); err != nil || t.FeeMinor < 0 {
+			return nil, fmt.Errorf("SHIPPING_TIERS: некорректная стоимость %q", fee)
+		}
+		if n := len(calc.Tiers); n > 0 && calc.Tiers[n-1].UpTo >= t.UpTo {
+			return nil, fmt.Errorf("SHIPPING_TIERS: границы тарифов должны возрастать")
+		}
+		calc.Tiers = append(calc.Tiers, t)
+	}
+	return calc, nil
+}
@@ -0,0 +1,16 @@
+package bootstrap
+
+import (
+	"kvant_task/internal/config"
+	"kvant_task/internal/revocation"
+
+	"gorm.io/gorm"
+)
+
+// RevocationStore создаёт хранилище отозванных токенов согласно конфигурации.
+func RevocationStore(cfg *config.Config, db *gorm.DB) revocation.Store {
+	if cfg.Revocation.Store == "memory" {
+		return revocation.NewMemoryStore()
+	}
+	return revocation.NewPostgresStore(db)
+}
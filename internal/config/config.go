@@ -7,7 +7,13 @@ package config
 import (
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"kvant_task/internal/money"
 
 	"github.com/joho/godotenv"
 )
@@ -16,11 +22,145 @@ import (
 type Config struct {
 	Server struct {
 		Address string
+		// TrustedProxies — IP и подсети прокси, чьему X-Forwarded-For верить при определении
+		// IP клиента; пусто — заголовок игнорируется и IP берётся из соединения
+		TrustedProxies []string
 	}
 	DB struct {
 		DSN string
 	}
-	JWTSecret string
+	JWT struct {
+		// Algorithm — алгоритм подписи: HS256, RS256 или EdDSA
+		Algorithm string
+		// Secret — ключ подписи HS256
+		Secret string
+		// SigningKeyFile — PEM-файл закрытого ключа для RS256/EdDSA
+		SigningKeyFile string
+		// SigningKeyID — kid ключа подписи; по умолчанию вычисляется из публичного ключа
+		SigningKeyID string
+		// VerificationKeyFiles — дополнительные публичные ключи (kid → PEM-файл),
+		// которые принимаются при проверке во время ротации
+		VerificationKeyFiles map[string]string
+		// Issuer — значение claim'а iss
+		Issuer string
+		// Audience — значение claim'а aud
+		Audience string
+		// AccessTTL — время жизни access-токена
+		AccessTTL time.Duration
+		// RefreshTTL — время жизни refresh-токена
+		RefreshTTL time.Duration
+	}
+	Auth struct {
+		// PasswordResetTTL — время жизни токена сброса пароля
+		PasswordResetTTL time.Duration
+		// EmailVerificationTTL — время жизни токена подтверждения email
+		EmailVerificationTTL time.Duration
+		// RequireVerifiedEmailToLogin — запрещать вход с неподтверждённым email
+		RequireVerifiedEmailToLogin bool
+		// RequireVerifiedEmailToOrder — запрещать заказы пользователям с неподтверждённым email
+		RequireVerifiedEmailToOrder bool
+		// TOTPIssuer — название сервиса в приложении-аутентификаторе
+		TOTPIssuer string
+		// TwoFactorChallengeTTL — время на ввод кода 2FA после проверки пароля
+		TwoFactorChallengeTTL time.Duration
+		// RequireTwoFactorForAdmins — права администратора только при входе со вторым фактором
+		RequireTwoFactorForAdmins bool
+		// SessionTouchInterval — как часто записывается время последней активности сессии
+		SessionTouchInterval time.Duration
+	}
+	Notify struct {
+		// Transport — способ доставки уведомлений: log или file
+		Transport string
+		// File — файл для транспорта file
+		File string
+	}
+	Revocation struct {
+		// Store — хранилище отозванных токенов: postgres или memory
+		Store string
+		// GCInterval — период удаления истёкших записей
+		GCInterval time.Duration
+	}
+	Lockout struct {
+		// Store — хранилище счётчиков неудачных входов: postgres или memory
+		Store string
+		// Threshold — число неудачных попыток на аккаунт до блокировки
+		Threshold int
+		// IPThreshold — число неудачных попыток с одного IP до блокировки
+		IPThreshold int
+		// BaseDelay — длительность первой блокировки; каждая следующая вдвое дольше
+		BaseDelay time.Duration
+		// MaxDelay — верхняя граница длительности блокировки
+		MaxDelay time.Duration
+		// Window — через сколько после последней неудачи счётчик обнуляется
+		Window time.Duration
+		// GCInterval — период удаления устаревших счётчиков
+		GCInterval time.Duration
+	}
+	Idempotency struct {
+		// Store — хранилище ключей идемпотентности: postgres или memory
+		Store string
+		// TTL — сколько хранится ответ на запрос с ключом
+		TTL time.Duration
+		// GCInterval — период удаления устаревших ключей
+		GCInterval time.Duration
+	}
+	Password struct {
+		// Algorithm — алгоритм для новых хэшей паролей: argon2id или bcrypt.
+		// Хэши другого алгоритма по-прежнему проверяются и пересчитываются при входе
+		Algorithm string
+		// Argon2Memory — память Argon2id в КиБ
+		Argon2Memory int
+		// Argon2Iterations — число проходов Argon2id
+		Argon2Iterations int
+		// Argon2Parallelism — число потоков Argon2id
+		Argon2Parallelism int
+		// BcryptCost — стоимость bcrypt
+		BcryptCost int
+		// MinLength — минимальная длина пароля
+		MinLength int
+		// MinCharClasses — сколько типов символов нужно в пароле (строчные, заглавные, цифры, остальные)
+		MinCharClasses int
+		// RejectPersonal — запрещать пароли, содержащие имя или email
+		RejectPersonal bool
+		// BreachedCheck — проверять пароли по списку утёкших
+		BreachedCheck bool
+		// BreachedListFile — файл списка утёкших паролей; пусто — встроенный список
+		BreachedListFile string
+	}
+	Money struct {
+		// DefaultCurrency — валюта товаров, для которых она не указана, и заказов,
+		// созданных до появления валют; код ISO 4217
+		DefaultCurrency string
+	}
+	Tax struct {
+		// Calculator — расчёт налога: none, flat (единая ставка) или category (ставки категорий товаров)
+		Calculator string
+		// Name — название налога по ставке Rate в строках налога заказа
+		Name string
+		// Rate — ставка в сотых долях процента (2000 — 20%); для category — ставка товаров без категории
+		Rate int
+		// Categories — ставки налоговых категорий товаров в сотых долях процента
+		Categories map[string]int
+	}
+	Shipping struct {
+		// Calculator — расчёт доставки: none (бесплатно), weight или price (тарифы по весу или стоимости)
+		Calculator string
+		// Currency — валюта тарифов; заказы в других валютах не доставляются
+		Currency string
+		// Tiers — тарифы "граница:стоимость" через запятую по возрастанию границы,
+		// "*" — без границы; разбираются в bootstrap.ShippingCalculator
+		Tiers string
+	}
+	Payments struct {
+		// Provider — платёжный провайдер; пока только fake (встроенный тестовый)
+		Provider string
+		// WebhookSecret — ключ подписи уведомлений провайдера; обязателен
+		WebhookSecret string
+		// PendingTTL — сколько ждать подтверждения платежа, прежде чем считать его неудавшимся
+		PendingTTL time.Duration
+		// ReconcileInterval — период проверки зависших платежей и невыполненных возвратов
+		ReconcileInterval time.Duration
+	}
 }
 
 // LoadConfig загружает конфигурацию из переменных окружения.
@@ -35,6 +175,14 @@ func LoadConfig() (*Config, error) {
 	cfg := &Config{}
 	// Server
 	cfg.Server.Address = getEnv("SERVER_ADDRESS", ":8080")
+	for _, p := range getList("TRUSTED_PROXIES") {
+		if net.ParseIP(p) == nil {
+			if _, _, err := net.ParseCIDR(p); err != nil {
+				return nil, fmt.Errorf("TRUSTED_PROXIES: некорректный адрес %q", p)
+			}
+		}
+		cfg.Server.TrustedProxies = append(cfg.Server.TrustedProxies, p)
+	}
 
 	// Postgres DSN из отдельных переменных
 	host := getEnv("POSTGRES_HOST", "localhost")
@@ -49,7 +197,209 @@ func LoadConfig() (*Config, error) {
 	)
 
 	// JWT
-	cfg.JWTSecret = getEnv("JWT_SECRET", "secret")
+	cfg.JWT.Algorithm = getEnv("JWT_ALGORITHM", "HS256")
+	cfg.JWT.Secret = getEnv("JWT_SECRET", "secret")
+	cfg.JWT.SigningKeyFile = getEnv("JWT_SIGNING_KEY_FILE", "")
+	cfg.JWT.SigningKeyID = getEnv("JWT_SIGNING_KEY_ID", "")
+	switch cfg.JWT.Algorithm {
+	case "HS256":
+	case "RS256", "EdDSA":
+		if cfg.JWT.SigningKeyFile == "" {
+			return nil, fmt.Errorf("JWT_SIGNING_KEY_FILE обязателен для алгоритма %s", cfg.JWT.Algorithm)
+		}
+	default:
+		return nil, fmt.Errorf("JWT_ALGORITHM: неподдерживаемый алгоритм %q", cfg.JWT.Algorithm)
+	}
+	keys, err := getKeyValueList("JWT_VERIFICATION_KEY_FILES")
+	if err != nil {
+		return nil, err
+	}
+	cfg.JWT.VerificationKeyFiles = keys
+	cfg.JWT.Issuer = getEnv("JWT_ISSUER", "kvant_task")
+	cfg.JWT.Audience = getEnv("JWT_AUDIENCE", "kvant_task_api")
+	if cfg.JWT.AccessTTL, err = getDuration("JWT_ACCESS_TTL", 15*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.JWT.RefreshTTL, err = getDuration("JWT_REFRESH_TTL", 30*24*time.Hour); err != nil {
+		return nil, err
+	}
+
+	// Аутентификация
+	if cfg.Auth.PasswordResetTTL, err = getDuration("PASSWORD_RESET_TTL", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.Auth.EmailVerificationTTL, err = getDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.Auth.RequireVerifiedEmailToLogin, err = getBool("REQUIRE_VERIFIED_EMAIL_TO_LOGIN", false); err != nil {
+		return nil, err
+	}
+	if cfg.Auth.RequireVerifiedEmailToOrder, err = getBool("REQUIRE_VERIFIED_EMAIL_TO_ORDER", false); err != nil {
+		return nil, err
+	}
+	cfg.Auth.TOTPIssuer = getEnv("TOTP_ISSUER", "kvant_task")
+	if cfg.Auth.TwoFactorChallengeTTL, err = getDuration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.Auth.RequireTwoFactorForAdmins, err = getBool("REQUIRE_2FA_FOR_ADMINS", false); err != nil {
+		return nil, err
+	}
+	if cfg.Auth.SessionTouchInterval, err = getDuration("SESSION_TOUCH_INTERVAL", time.Minute); err != nil {
+		return nil, err
+	}
+
+	// Уведомления
+	cfg.Notify.Transport = getEnv("NOTIFY_TRANSPORT", "log")
+	cfg.Notify.File = getEnv("NOTIFY_FILE", "notifications.log")
+	if cfg.Notify.Transport != "log" && cfg.Notify.Transport != "file" {
+		return nil, fmt.Errorf("NOTIFY_TRANSPORT: неизвестный транспорт %q", cfg.Notify.Transport)
+	}
+
+	// Отзыв токенов
+	cfg.Revocation.Store = getEnv("REVOCATION_STORE", "postgres")
+	if cfg.Revocation.Store != "postgres" && cfg.Revocation.Store != "memory" {
+		return nil, fmt.Errorf("REVOCATION_STORE: неизвестное хранилище %q", cfg.Revocation.Store)
+	}
+	if cfg.Revocation.GCInterval, err = getDuration("REVOCATION_GC_INTERVAL", 10*time.Minute); err != nil {
+		return nil, err
+	}
+
+	// Защита от перебора паролей
+	cfg.Lockout.Store = getEnv("LOGIN_LOCKOUT_STORE", "postgres")
+	if cfg.Lockout.Store != "postgres" && cfg.Lockout.Store != "memory" {
+		return nil, fmt.Errorf("LOGIN_LOCKOUT_STORE: неизвестное хранилище %q", cfg.Lockout.Store)
+	}
+	if cfg.Lockout.Threshold, err = getInt("LOGIN_LOCKOUT_THRESHOLD", 5); err != nil {
+		return nil, err
+	}
+	if cfg.Lockout.IPThreshold, err = getInt("LOGIN_LOCKOUT_IP_THRESHOLD", 20); err != nil {
+		return nil, err
+	}
+	if cfg.Lockout.BaseDelay, err = getDuration("LOGIN_LOCKOUT_BASE_DELAY", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.Lockout.MaxDelay, err = getDuration("LOGIN_LOCKOUT_MAX_DELAY", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.Lockout.Window, err = getDuration("LOGIN_LOCKOUT_WINDOW", 15*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.Lockout.GCInterval, err = getDuration("LOGIN_LOCKOUT_GC_INTERVAL", 10*time.Minute); err != nil {
+		return nil, err
+	}
+
+	// Ключи идемпотентности
+	cfg.Idempotency.Store = getEnv("IDEMPOTENCY_STORE", "postgres")
+	if cfg.Idempotency.Store != "postgres" && cfg.Idempotency.Store != "memory" {
+		return nil, fmt.Errorf("IDEMPOTENCY_STORE: неизвестное хранилище %q", cfg.Idempotency.Store)
+	}
+	if cfg.Idempotency.TTL, err = getDuration("IDEMPOTENCY_TTL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.Idempotency.GCInterval, err = getDuration("IDEMPOTENCY_GC_INTERVAL", time.Hour); err != nil {
+		return nil, err
+	}
+
+	// Хэширование паролей; параметры Argon2id по умолчанию — рекомендация OWASP
+	cfg.Password.Algorithm = getEnv("PASSWORD_HASH_ALGORITHM", "argon2id")
+	if cfg.Password.Algorithm != "argon2id" && cfg.Password.Algorithm != "bcrypt" {
+		return nil, fmt.Errorf("PASSWORD_HASH_ALGORITHM: неизвестный алгоритм %q", cfg.Password.Algorithm)
+	}
+	if cfg.Password.Argon2Memory, err = getInt("ARGON2_MEMORY", 19*1024); err != nil {
+		return nil, err
+	}
+	if cfg.Password.Argon2Iterations, err = getInt("ARGON2_ITERATIONS", 2); err != nil {
+		return nil, err
+	}
+	if cfg.Password.Argon2Parallelism, err = getInt("ARGON2_PARALLELISM", 1); err != nil {
+		return nil, err
+	}
+	if cfg.Password.Argon2Parallelism > 255 {
+		return nil, fmt.Errorf("ARGON2_PARALLELISM: не более 255")
+	}
+	if cfg.Password.BcryptCost, err = getInt("BCRYPT_COST", 10); err != nil {
+		return nil, err
+	}
+	if cfg.Password.BcryptCost < 4 || cfg.Password.BcryptCost > 31 {
+		return nil, fmt.Errorf("BCRYPT_COST: допустимы значения от 4 до 31")
+	}
+
+	// Политика паролей
+	if cfg.Password.MinLength, err = getInt("PASSWORD_MIN_LENGTH", 8); err != nil {
+		return nil, err
+	}
+	if cfg.Password.MinCharClasses, err = getInt("PASSWORD_MIN_CHAR_CLASSES", 2); err != nil {
+		return nil, err
+	}
+	if cfg.Password.MinCharClasses > 4 {
+		return nil, fmt.Errorf("PASSWORD_MIN_CHAR_CLASSES: типов символов всего 4")
+	}
+	if cfg.Password.RejectPersonal, err = getBool("PASSWORD_REJECT_PERSONAL", true); err != nil {
+		return nil, err
+	}
+	if cfg.Password.BreachedCheck, err = getBool("PASSWORD_BREACHED_CHECK", true); err != nil {
+		return nil, err
+	}
+	cfg.Password.BreachedListFile = getEnv("PASSWORD_BREACHED_LIST_FILE", "")
+
+	// Деньги
+	cur, err := money.Lookup(getEnv("DEFAULT_CURRENCY", "RUB"))
+	if err != nil {
+		return nil, fmt.Errorf("DEFAULT_CURRENCY: %w", err)
+	}
+	cfg.Money.DefaultCurrency = cur.Code
+
+	// Налог
+	cfg.Tax.Calculator = getEnv("TAX_CALCULATOR", "none")
+	if cfg.Tax.Calculator != "none" && cfg.Tax.Calculator != "flat" && cfg.Tax.Calculator != "category" {
+		return nil, fmt.Errorf("TAX_CALCULATOR: неизвестный расчёт %q", cfg.Tax.Calculator)
+	}
+	cfg.Tax.Name = getEnv("TAX_NAME", "VAT")
+	if cfg.Tax.Rate, err = parseRate("TAX_RATE", getEnv("TAX_RATE", "0")); err != nil {
+		return nil, err
+	}
+	categories, err := getKeyValueList("TAX_CATEGORIES")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Tax.Categories = make(map[string]int, len(categories))
+	for name, v := range categories {
+		if cfg.Tax.Categories[name], err = parseRate("TAX_CATEGORIES", v); err != nil {
+			return nil, err
+		}
+	}
+
+	// Доставка
+	cfg.Shipping.Calculator = getEnv("SHIPPING_CALCULATOR", "none")
+	if cfg.Shipping.Calculator != "none" && cfg.Shipping.Calculator != "weight" && cfg.Shipping.Calculator != "price" {
+		return nil, fmt.Errorf("SHIPPING_CALCULATOR: неизвестный расчёт %q", cfg.Shipping.Calculator)
+	}
+	shipCur, err := money.Lookup(getEnv("SHIPPING_CURRENCY", cfg.Money.DefaultCurrency))
+	if err != nil {
+		return nil, fmt.Errorf("SHIPPING_CURRENCY: %w", err)
+	}
+	cfg.Shipping.Currency = shipCur.Code
+	cfg.Shipping.Tiers = getEnv("SHIPPING_TIERS", "")
+	if cfg.Shipping.Calculator != "none" && cfg.Shipping.Tiers == "" {
+		return nil, fmt.Errorf("SHIPPING_TIERS: для расчёта %q нужны тарифы", cfg.Shipping.Calculator)
+	}
+
+	// Платежи
+	cfg.Payments.Provider = getEnv("PAYMENT_PROVIDER", "fake")
+	if cfg.Payments.Provider != "fake" {
+		return nil, fmt.Errorf("PAYMENT_PROVIDER: неизвестный провайдер %q", cfg.Payments.Provider)
+	}
+	// без секрета уведомления на публичный /payments/webhook можно подделать
+	cfg.Payments.WebhookSecret = getEnv("PAYMENT_WEBHOOK_SECRET", "")
+	if cfg.Payments.WebhookSecret == "" {
+		return nil, fmt.Errorf("PAYMENT_WEBHOOK_SECRET: переменная обязательна")
+	}
+	if cfg.Payments.PendingTTL, err = getDuration("PAYMENT_PENDING_TTL", 30*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.Payments.ReconcileInterval, err = getDuration("PAYMENT_RECONCILE_INTERVAL", 5*time.Minute); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
 
@@ -60,3 +410,83 @@ func getEnv(key, def string) string {
 	}
 	return def
 }
+
+// getDuration читает длительность в формате time.ParseDuration (например, 15m, 720h).
+func getDuration(key string, def time.Duration) (time.Duration, error) {
+	v := getEnv(key, "")
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s: некорректная длительность %q", key, v)
+	}
+	return d, nil
+}
+
+// getInt читает положительное целое число.
+func getInt(key string, def int) (int, error) {
+	v := getEnv(key, "")
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s: некорректное число %q", key, v)
+	}
+	return n, nil
+}
+
+// getBool читает логическое значение (true/false, 1/0).
+func getBool(key string, def bool) (bool, error) {
+	v := getEnv(key, "")
+	if v == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s: некорректное логическое значение %q", key, v)
+	}
+	return b, nil
+}
+
+// parseRate разбирает ставку в процентах ("20", "7.5") в сотые доли процента.
+func parseRate(key, v string) (int, error) {
+	whole, frac, hasDot := strings.Cut(v, ".")
+	if len(frac) > 2 || (hasDot && frac == "") {
+		return 0, fmt.Errorf("%s: некорректная ставка %q", key, v)
+	}
+	n, err := strconv.ParseUint(whole+frac+strings.Repeat("0", 2-len(frac)), 10, 32)
+	if err != nil || n > 10000 {
+		return 0, fmt.Errorf("%s: некорректная ставка %q, ожидается от 0 до 100%%", key, v)
+	}
+	return int(n), nil
+}
+
+// getList читает список значений через запятую; пустые элементы пропускаются.
+func getList(key string) []string {
+	var out []string
+	for _, item := range strings.Split(getEnv(key, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// getKeyValueList читает список вида "k1=v1,k2=v2".
+func getKeyValueList(key string) (map[string]string, error) {
+	out := make(map[string]string)
+	v := getEnv(key, "")
+	if v == "" {
+		return out, nil
+	}
+	for _, item := range strings.Split(v, ",") {
+		k, val, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok || k == "" || val == "" {
+			return nil, fmt.Errorf("%s: некорректный элемент %q, ожидается ключ=значение", key, item)
+		}
+		out[k] = val
+	}
+	return out, nil
+}
@@ -0,0 +1,136 @@
+// api_key_handler.go
+// Этот файл реализует HTTP-слой для API-ключей текущего пользователя.
+// Содержит обработчики выпуска, просмотра, отзыва и ротации ключей.
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"kvant_task/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler — HTTP-слой для API-ключей.
+type APIKeyHandler struct {
+	svc *services.APIKeyService
+}
+
+// NewAPIKeyHandler конструктор для создания нового APIKeyHandler.
+// Сервис общий с middleware Auth, которое проверяет ключи.
+func NewAPIKeyHandler(svc *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{svc: svc}
+}
+
+// Create обрабатывает POST /users/me/api-keys
+// @Summary      Создание API-ключа
+// @Description  Выпускает ключ с указанными правами. Ключ передаётся в заголовке X-API-Key
+// @Description  и показывается только в этом ответе.
+// @Tags         API-ключи
+// @Accept       json
+// @Produce      json
+// @Param        input  body      services.CreateAPIKeyRequest  true  "Название и права ключа"
+// @Success      201    {object}  services.APIKeySecretResponse
+// @Failure      400    {object}  handlers.ValidationErrorResponse
+// @Failure      401    {object}  handlers.ErrorResponse
+// @Failure      403    {object}  handlers.ErrorResponse "Запрос выполнен по API-ключу"
+// @Failure      500    {object}  handlers.ErrorResponse
+// @Security     BearerAuth
+// @Router       /users/me/api-keys [post]
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	var req services.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
+		return
+	}
+	k, err := h.svc.Create(c.Request.Context(), c.GetUint("user_id"), &req)
+	if err != nil {
+		HandleError(c, err, nil, "ошибка при создании API-ключа")
+		return
+	}
+	c.JSON(http.StatusCreated, k)
+}
+
+// List обрабатывает GET /users/me/api-keys
+// @Summary      Список API-ключей
+// @Description  Возвращает ключи текущего пользователя без секретов, включая отозванные.
+// @Tags         API-ключи
+// @Produce      json
+// @Success      200  {array}   services.APIKeyResponse
+// @Failure      401  {object}  handlers.ErrorResponse
+// @Failure      403  {object}  handlers.ErrorResponse "Запрос выполнен по API-ключу"
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Security     BearerAuth
+// @Router       /users/me/api-keys [get]
+func (h *APIKeyHandler) List(c *gin.Context) {
+	keys, err := h.svc.List(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		HandleError(c, err, nil, "ошибка при получении API-ключей")
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// Revoke обрабатывает DELETE /users/me/api-keys/:keyId
+// @Summary      Отзыв API-ключа
+// @Description  Ключ перестаёт приниматься сразу. Запись остаётся в списке с датой отзыва.
+// @Tags         API-ключи
+// @Produce      json
+// @Param        keyId  path      int  true  "ID ключа"
+// @Success      204    {string}  string  "No Content"
+// @Failure      400    {object}  handlers.ErrorResponse
+// @Failure      401    {object}  handlers.ErrorResponse
+// @Failure      403    {object}  handlers.ErrorResponse "Запрос выполнен по API-ключу"
+// @Failure      404    {object}  handlers.ErrorResponse
+// @Failure      500    {object}  handlers.ErrorResponse
+// @Security     BearerAuth
+// @Router       /users/me/api-keys/{keyId} [delete]
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("keyId"))
+	if err != nil || id <= 0 {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("ID должен быть положительным целым числом"))
+		return
+	}
+	if err := h.svc.Revoke(c.Request.Context(), c.GetUint("user_id"), uint(id)); err != nil {
+		HandleError(c, err, services.ErrNotFound, "API-ключ не найден")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Rotate обрабатывает POST /users/me/api-keys/:keyId/rotate
+// @Summary      Ротация API-ключа
+// @Description  Выпускает новое значение ключа с теми же названием и правами. Прежнее значение перестаёт действовать.
+// @Tags         API-ключи
+// @Produce      json
+// @Param        keyId  path      int  true  "ID ключа"
+// @Success      200    {object}  services.APIKeySecretResponse
+// @Failure      400    {object}  handlers.ErrorResponse
+// @Failure      401    {object}  handlers.ErrorResponse
+// @Failure      403    {object}  handlers.ErrorResponse "Запрос выполнен по API-ключу"
+// @Failure      404    {object}  handlers.ErrorResponse
+// @Failure      409    {object}  handlers.ErrorResponse "Ключ отозван"
+// @Failure      500    {object}  handlers.ErrorResponse
+// @Security     BearerAuth
+// @Router       /users/me/api-keys/{keyId}/rotate [post]
+func (h *APIKeyHandler) Rotate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("keyId"))
+	if err != nil || id <= 0 {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("ID должен быть положительным целым числом"))
+		return
+	}
+	k, err := h.svc.Rotate(c.Request.Context(), c.GetUint("user_id"), uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrAPIKeyRevoked) {
+			RespondError(c, http.StatusConflict, err)
+			return
+		}
+		HandleError(c, err, services.ErrNotFound, "API-ключ не найден")
+		return
+	}
+	c.JSON(http.StatusOK, k)
+}
@@ -0,0 +1,173 @@
+// coupon_handler.go
+// Этот файл реализует HTTP-слой промокодов.
+// Управляет промокодами администратор; применяются они при создании заказа.
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"kvant_task/internal/config"
+	"kvant_task/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CouponHandler — HTTP-слой для промокодов.
+type CouponHandler struct {
+	svc *services.CouponService
+}
+
+// NewCouponHandler конструктор для создания нового CouponHandler.
+func NewCouponHandler(db *gorm.DB, cfg *config.Config) *CouponHandler {
+	return &CouponHandler{svc: services.NewCouponService(db, cfg)}
+}
+
+// Create обрабатывает POST /coupons
+// @Summary      Создание промокода
+// @Description  Создаёт промокод с процентной (percent) или фиксированной (fixed) скидкой.
+// @Description  Можно ограничить период действия, минимальную сумму заказа, общее число применений
+// @Description  и число применений одним пользователем. Код хранится в верхнем регистре.
+// @Tags         Промокоды
+// @Accept       json
+// @Produce      json
+// @Param        input  body      services.CreateCouponRequest  true  "Данные промокода"
+// @Success      201    {object}  services.CouponResponse
+// @Failure      400    {object}  handlers.ValidationErrorResponse
+// @Failure      401    {object}  handlers.ErrorResponse
+// @Failure      403    {object}  handlers.ErrorResponse "Требуется роль администратора"
+// @Failure      409    {object}  handlers.ErrorResponse "Код занят"
+// @Failure      500    {object}  handlers.ErrorResponse
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /coupons [post]
+func (h *CouponHandler) Create(c *gin.Context) {
+	var req services.CreateCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
+		return
+	}
+	coupon, err := h.svc.Create(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrCouponCodeTaken) {
+			RespondError(c, http.StatusConflict, err)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidCoupon) {
+			RespondError(c, http.StatusBadRequest, err)
+			return
+		}
+		HandleError(c, err, nil, "ошибка при создании промокода")
+		return
+	}
+	c.JSON(http.StatusCreated, coupon)
+}
+
+// List обрабатывает GET /coupons
+// @Summary      Список промокодов
+// @Description  Возвращает промокоды, включая отключённые, по страницам; новые — первыми.
+// @Tags         Промокоды
+// @Produce      json
+// @Param        page   query     int  false  "Номер страницы"   default(1)
+// @Param        limit  query     int  false  "Размер страницы"  default(20)
+// @Success      200    {object}  handlers.CouponListResponse
+// @Failure      400    {object}  handlers.ErrorResponse
+// @Failure      401    {object}  handlers.ErrorResponse
+// @Failure      403    {object}  handlers.ErrorResponse "Требуется роль администратора"
+// @Failure      500    {object}  handlers.ErrorResponse
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /coupons [get]
+func (h *CouponHandler) List(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("номер страницы должен быть положительным целым числом"))
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("размер страницы должен быть от 1 до 100"))
+		return
+	}
+	list, total, err := h.svc.List(c.Request.Context(), page, limit)
+	if err != nil {
+		HandleError(c, err, nil, "ошибка при получении промокодов")
+		return
+	}
+	c.JSON(http.StatusOK, CouponListResponse{
+		Page:    page,
+		Limit:   limit,
+		Total:   total,
+		Coupons: list,
+	})
+}
+
+// GetByID обрабатывает GET /coupons/:couponId
+// @Summary      Промокод
+// @Description  Возвращает промокод по ID вместе с числом применений.
+// @Tags         Промокоды
+// @Produce      json
+// @Param        couponId  path      int  true  "ID промокода"
+// @Success      200       {object}  services.CouponResponse
+// @Failure      400       {object}  handlers.ErrorResponse
+// @Failure      401       {object}  handlers.ErrorResponse
+// @Failure      403       {object}  handlers.ErrorResponse "Требуется роль администратора"
+// @Failure      404       {object}  handlers.ErrorResponse
+// @Failure      500       {object}  handlers.ErrorResponse
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /coupons/{couponId} [get]
+func (h *CouponHandler) GetByID(c *gin.Context) {
+	id, ok := couponID(c)
+	if !ok {
+		return
+	}
+	coupon, err := h.svc.Get(c.Request.Context(), id)
+	if err != nil {
+		HandleError(c, err, services.ErrCouponNotFound, "промокод не найден")
+		return
+	}
+	c.JSON(http.StatusOK, coupon)
+}
+
+// Delete обрабатывает DELETE /coupons/:couponId
+// @Summary      Отключение промокода
+// @Description  Отключает промокод: к новым заказам он больше не применяется,
+// @Description  оформленные заказы сохраняют скидку.
+// @Tags         Промокоды
+// @Produce      json
+// @Param        couponId  path      int  true  "ID промокода"
+// @Success      204       {string}  string  "No Content"
+// @Failure      400       {object}  handlers.ErrorResponse
+// @Failure      401       {object}  handlers.ErrorResponse
+// @Failure      403       {object}  handlers.ErrorResponse "Требуется роль администратора"
+// @Failure      404       {object}  handlers.ErrorResponse
+// @Failure      500       {object}  handlers.ErrorResponse
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /coupons/{couponId} [delete]
+func (h *CouponHandler) Delete(c *gin.Context) {
+	id, ok := couponID(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.Deactivate(c.Request.Context(), id); err != nil {
+		HandleError(c, err, services.ErrCouponNotFound, "промокод не найден")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// couponID разбирает ID промокода из пути. При ошибке отвечает сам и возвращает ok=false.
+func couponID(c *gin.Context) (uint, bool) {
+	id, err := strconv.Atoi(c.Param("couponId"))
+	if err != nil || id <= 0 {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("ID должен быть положительным целым числом"))
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -10,6 +10,8 @@ import (
 	"log"
 	"net/http"
 
+	"kvant_task/internal/password"
+
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
@@ -39,6 +41,8 @@ func RespondError(c *gin.Context, status int, err error) {
 				msg = fmt.Sprintf("Поле '%s' должно содержать минимум %s символов", fe.Field(), fe.Param())
 			case "gt":
 				msg = fmt.Sprintf("Поле '%s' должно быть больше %s", fe.Field(), fe.Param())
+			case "oneof":
+				msg = fmt.Sprintf("Поле '%s' должно быть одним из: %s", fe.Field(), fe.Param())
 			default:
 				msg = fmt.Sprintf("Поле '%s' не прошло проверку '%s'", fe.Field(), fe.ActualTag())
 			}
@@ -48,12 +52,24 @@ func RespondError(c *gin.Context, status int, err error) {
 		return
 	}
 
+	// Нарушения политики паролей — тоже ошибки валидации, по сообщению на нарушение
+	var pe *password.PolicyError
+	if errors.As(err, &pe) {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{Errors: pe.Violations})
+		return
+	}
+
 	// Обычная ошибка
 	c.JSON(status, ErrorResponse{Error: err.Error()})
 }
 
 // HandleError обрабатывает ошибки и возвращает соответствующий HTTP статус и сообщение.
 func HandleError(c *gin.Context, err error, notFoundErr error, notFoundMsg string) {
+	var pe *password.PolicyError
+	if errors.As(err, &pe) {
+		RespondError(c, http.StatusBadRequest, err)
+		return
+	}
 	if errors.Is(err, notFoundErr) {
 		RespondError(c, http.StatusNotFound, fmt.Errorf("%s", notFoundMsg))
 		return
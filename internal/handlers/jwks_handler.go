@@ -0,0 +1,36 @@
+// jwks_handler.go
+// Этот файл реализует HTTP-слой для публикации публичных ключей JWT.
+// Другие сервисы проверяют наши токены по этим ключам без общего секрета.
+
+package handlers
+
+import (
+	"net/http"
+
+	"kvant_task/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler — HTTP-слой для JWKS.
+type JWKSHandler struct {
+	tokens *services.TokenService
+}
+
+// NewJWKSHandler конструктор для создания нового JWKSHandler.
+func NewJWKSHandler(tokens *services.TokenService) *JWKSHandler {
+	return &JWKSHandler{tokens: tokens}
+}
+
+// Keys обрабатывает GET /.well-known/jwks.json
+// @Summary Публичные ключи JWT
+// @Description Возвращает набор публичных ключей (JWKS) для проверки access-токенов.
+// @Description В режиме HS256 набор пуст.
+// @Tags Аутентификация
+// @Produce json
+// @Success 200 {object} services.JWKSet "Набор ключей"
+// @Router /.well-known/jwks.json [get]
+func (h *JWKSHandler) Keys(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.tokens.JWKS())
+}
@@ -5,10 +5,15 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
+	"kvant_task/internal/config"
+	"kvant_task/internal/payment"
+	"kvant_task/internal/pricing"
 	"kvant_task/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -21,23 +26,32 @@ type OrderHandler struct {
 }
 
 // NewOrderHandler конструктор для создания нового OrderHandler.
-func NewOrderHandler(db *gorm.DB) *OrderHandler {
-	return &OrderHandler{svc: services.NewOrderService(db)}
+func NewOrderHandler(db *gorm.DB, cfg *config.Config, tax pricing.TaxCalculator, shipping pricing.ShippingCalculator, payments payment.PaymentProvider) *OrderHandler {
+	return &OrderHandler{svc: services.NewOrderService(db, cfg, tax, shipping, payments)}
 }
 
 // CreateForUser создаёт заказ для пользователя.
 // @Summary      Создание заказа
-// @Description  Создаёт новый заказ для указанного пользователя.
+// @Description  Создаёт заказ из одной или нескольких позиций для указанного пользователя.
+// @Description  Товар позиции задаётся product_id или sku, цена и валюта берутся из каталога;
+// @Description  все товары заказа должны быть в одной валюте. Суммы в ответе — строки.
+// @Description  Остатки списываются в одной транзакции с созданием заказа; в ответе — позиции, subtotal и total.
+// @Description  С coupon_code скидка по промокоду вычитается из суммы позиций; она показана в discount.
+// @Description  Налог (tax, по ставкам — tax_lines) и доставка (shipping) рассчитываются по настройкам сервера
+// @Description  и входят в total.
 // @Tags         Заказы
 // @Accept       json
 // @Produce      json
 // @Param        id     path      int                     true  "ID пользователя"
 // @Param        input  body      services.CreateOrderRequest true "Данные заказа"
 // @Success      201    {object} services.OrderResponse "Заказ успешно создан"
-// @Failure      400    {object} handlers.ErrorResponse "Некорректный ID пользователя"
+// @Failure      400    {object} handlers.ErrorResponse "Некорректный ID пользователя, товар не найден, промокод не подходит или доставка недоступна"
+// @Failure      403    {object} handlers.ErrorResponse "Email владельца не подтверждён"
+// @Failure      409    {object} handlers.ErrorResponse "Недостаточно товара на складе или лимит промокода исчерпан"
 // @Failure      422    {object} handlers.ValidationErrorResponse "Ошибка валидации данных заказа"
 // @Failure      500    {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
 // @Security     BearerAuth
+// @Security     ApiKeyAuth
 // @Router       /users/{id}/orders [post]
 func (h *OrderHandler) CreateForUser(c *gin.Context) {
 	uid, err := strconv.Atoi(c.Param("id"))
@@ -45,8 +59,7 @@ func (h *OrderHandler) CreateForUser(c *gin.Context) {
 		HandleError(c, fmt.Errorf("ID должен быть положительным целым числом"), nil, "ID должен быть положительным целым числом")
 		return
 	}
-	userSvc := services.NewUserService(h.svc.GetDB(), "")
-	if _, err := userSvc.GetByID(c.Request.Context(), uint(uid)); err != nil {
+	if err := h.svc.CheckUser(c.Request.Context(), uint(uid)); err != nil {
 		HandleError(c, err, gorm.ErrRecordNotFound, "пользователь не найден")
 		return
 	}
@@ -57,6 +70,20 @@ func (h *OrderHandler) CreateForUser(c *gin.Context) {
 	}
 	o, err := h.svc.Create(c.Request.Context(), uint(uid), &req)
 	if err != nil {
+		if errors.Is(err, services.ErrEmailNotVerified) {
+			RespondError(c, http.StatusForbidden, err)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidOrder) || errors.Is(err, services.ErrProductNotFound) || errors.Is(err, services.ErrMixedCurrencies) ||
+			errors.Is(err, services.ErrCouponNotFound) || errors.Is(err, services.ErrCouponNotApplicable) ||
+			errors.Is(err, services.ErrShippingUnavailable) {
+			RespondError(c, http.StatusBadRequest, err)
+			return
+		}
+		if errors.Is(err, services.ErrInsufficientStock) || errors.Is(err, services.ErrCouponExhausted) {
+			RespondError(c, http.StatusConflict, err)
+			return
+		}
 		HandleError(c, err, nil, "ошибка сервера при создании заказа")
 		return
 	}
@@ -65,14 +92,31 @@ func (h *OrderHandler) CreateForUser(c *gin.Context) {
 
 // ListByUser возвращает заказы пользователя.
 // @Summary      Список заказов
-// @Description  Возвращает все заказы указанного пользователя.
+// @Description  Возвращает заказы указанного пользователя по страницам: по номеру (page) или по курсору
+// @Description  из next_cursor предыдущего ответа. Суммы в фильтрах — в валюте currency
+// @Description  (по умолчанию DEFAULT_CURRENCY); с ними выбираются только заказы в этой валюте.
 // @Tags         Заказы
 // @Produce      json
-// @Param        id   path      int  true  "ID пользователя"
-// @Success      200  {array}   services.OrderResponse "Список заказов"
-// @Failure      400  {object}  handlers.ErrorResponse "Некорректный ID пользователя"
+// @Param        id            path      int     true   "ID пользователя"
+// @Param        page          query     int     false  "Номер страницы"                        default(1)
+// @Param        limit         query     int     false  "Размер страницы, до 100"               default(20)
+// @Param        cursor        query     string  false  "Курсор следующей страницы"
+// @Param        created_from  query     string  false  "Создан не раньше (дата или RFC 3339)"
+// @Param        created_to    query     string  false  "Создан не позже (дата или RFC 3339)"
+// @Param        currency      query     string  false  "Валюта заказа"
+// @Param        min_total     query     string  false  "Итого не меньше"
+// @Param        max_total     query     string  false  "Итого не больше"
+// @Param        min_price     query     string  false  "Есть позиция с ценой не меньше"
+// @Param        max_price     query     string  false  "Есть позиция с ценой не больше"
+// @Param        min_quantity  query     int     false  "Единиц товара в заказе не меньше"
+// @Param        max_quantity  query     int     false  "Единиц товара в заказе не больше"
+// @Param        product       query     string  false  "Подстрока названия товара"
+// @Param        sort          query     string  false  "Сортировка; '-' — по убыванию"  Enums(created_at, -created_at, price, -price, total, -total) default(-created_at)
+// @Success      200  {object}  handlers.OrderListResponse "Страница заказов"
+// @Failure      400  {object}  handlers.ErrorResponse "Некорректный ID пользователя или параметры списка"
 // @Failure      500  {object}  handlers.ErrorResponse "Внутренняя ошибка сервера"
 // @Security     BearerAuth
+// @Security     ApiKeyAuth
 // @Router       /users/{id}/orders [get]
 func (h *OrderHandler) ListByUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -80,10 +124,289 @@ func (h *OrderHandler) ListByUser(c *gin.Context) {
 		HandleError(c, fmt.Errorf("ID должен быть положительным целым числом"), nil, "ID должен быть положительным целым числом")
 		return
 	}
-	list, err := h.svc.ListByUser(c.Request.Context(), uint(id))
+	var q services.OrderListQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные параметры: %w", err))
+		return
+	}
+	list, total, next, err := h.svc.ListByUser(c.Request.Context(), uint(id), &q)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidFilter) {
+			RespondError(c, http.StatusBadRequest, err)
+			return
+		}
 		HandleError(c, err, nil, "ошибка сервера при получении заказов")
 		return
 	}
+	c.JSON(http.StatusOK, OrderListResponse{
+		Page:       q.Page,
+		Limit:      q.Limit,
+		Total:      total,
+		NextCursor: next,
+		Orders:     list,
+	})
+}
+
+// Search ищет заказы всех пользователей.
+// @Summary      Поиск заказов
+// @Description  Заказы всех пользователей с фильтрами GET /users/{id}/orders, а также по владельцу,
+// @Description  товару каталога и статусу. Страницы — по номеру или по курсору из next_cursor.
+// @Description  С include_user=true к каждому заказу добавляются данные владельца.
+// @Tags         Заказы
+// @Produce      json
+// @Param        user_id       query     int     false  "ID владельца"
+// @Param        product_id    query     int     false  "ID товара каталога в одной из позиций"
+// @Param        status        query     string  false  "Статус"  Enums(pending, paid, shipped, delivered, cancelled)
+// @Param        include_user  query     bool    false  "Добавить данные владельца"
+// @Param        page          query     int     false  "Номер страницы"                        default(1)
+// @Param        limit         query     int     false  "Размер страницы, до 100"               default(20)
+// @Param        cursor        query     string  false  "Курсор следующей страницы"
+// @Param        created_from  query     string  false  "Создан не раньше (дата или RFC 3339)"
+// @Param        created_to    query     string  false  "Создан не позже (дата или RFC 3339)"
+// @Param        currency      query     string  false  "Валюта заказа"
+// @Param        min_total     query     string  false  "Итого не меньше"
+// @Param        max_total     query     string  false  "Итого не больше"
+// @Param        min_price     query     string  false  "Есть позиция с ценой не меньше"
+// @Param        max_price     query     string  false  "Есть позиция с ценой не больше"
+// @Param        min_quantity  query     int     false  "Единиц товара в заказе не меньше"
+// @Param        max_quantity  query     int     false  "Единиц товара в заказе не больше"
+// @Param        product       query     string  false  "Подстрока названия товара"
+// @Param        sort          query     string  false  "Сортировка; '-' — по убыванию"  Enums(created_at, -created_at, price, -price, total, -total) default(-created_at)
+// @Success      200  {object}  handlers.AdminOrderListResponse "Страница заказов"
+// @Failure      400  {object}  handlers.ErrorResponse "Некорректные параметры"
+// @Failure      401  {object}  handlers.ErrorResponse
+// @Failure      403  {object}  handlers.ErrorResponse "Требуется роль администратора"
+// @Failure      500  {object}  handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /orders [get]
+func (h *OrderHandler) Search(c *gin.Context) {
+	var q services.AdminOrderListQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные параметры: %w", err))
+		return
+	}
+	list, total, next, err := h.svc.Search(c.Request.Context(), &q)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFilter) {
+			RespondError(c, http.StatusBadRequest, err)
+			return
+		}
+		HandleError(c, err, nil, "ошибка сервера при поиске заказов")
+		return
+	}
+	c.JSON(http.StatusOK, AdminOrderListResponse{
+		Page:       q.Page,
+		Limit:      q.Limit,
+		Total:      total,
+		NextCursor: next,
+		Orders:     list,
+	})
+}
+
+// Get возвращает заказ пользователя.
+// @Summary      Заказ
+// @Description  Возвращает заказ с позициями, если он принадлежит указанному пользователю.
+// @Tags         Заказы
+// @Produce      json
+// @Param        id       path      int  true  "ID пользователя"
+// @Param        orderId  path      int  true  "ID заказа"
+// @Success      200      {object}  services.OrderResponse
+// @Failure      400      {object}  handlers.ErrorResponse "Некорректный ID"
+// @Failure      404      {object}  handlers.ErrorResponse "Пользователь не найден или заказ не найден"
+// @Failure      500      {object}  handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /users/{id}/orders/{orderId} [get]
+func (h *OrderHandler) Get(c *gin.Context) {
+	uid, oid, ok := orderParams(c, h.svc.CheckUser)
+	if !ok {
+		return
+	}
+	o, err := h.svc.Get(c.Request.Context(), uid, oid)
+	if err != nil {
+		HandleError(c, err, services.ErrOrderNotFound, "заказ не найден")
+		return
+	}
+	c.JSON(http.StatusOK, o)
+}
+
+// Update меняет состав заказа или отменяет его.
+// @Summary      Изменение заказа
+// @Description  С items заменяет состав неоплаченного заказа: прежние позиции возвращаются на склад,
+// @Description  новые списываются по текущим ценам каталога. Со status=cancelled отменяет заказ
+// @Description  по тем же правилам, что и переход статуса. Состав и статус меняются отдельными запросами.
+// @Description  Скидка по промокоду, налог и доставка пересчитываются от нового состава.
+// @Tags         Заказы
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                          true  "ID пользователя"
+// @Param        orderId  path      int                          true  "ID заказа"
+// @Param        input    body      services.UpdateOrderRequest  true  "Новый состав или отмена"
+// @Success      200      {object}  services.OrderResponse
+// @Failure      400      {object}  handlers.ValidationErrorResponse "Некорректные данные"
+// @Failure      403      {object}  handlers.ErrorResponse "Отмена доступна только администратору"
+// @Failure      404      {object}  handlers.ErrorResponse "Пользователь не найден или заказ не найден"
+// @Failure      409      {object}  handlers.ErrorResponse "Заказ уже нельзя изменить, он оплачивается или товара не хватает"
+// @Failure      500      {object}  handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /users/{id}/orders/{orderId} [patch]
+func (h *OrderHandler) Update(c *gin.Context) {
+	uid, oid, ok := orderParams(c, h.svc.CheckUser)
+	if !ok {
+		return
+	}
+	var req services.UpdateOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
+		return
+	}
+	actor := services.Actor{UserID: c.GetUint("user_id"), Role: c.GetString("role")}
+	o, err := h.svc.Update(c.Request.Context(), uid, oid, actor, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidOrder), errors.Is(err, services.ErrProductNotFound), errors.Is(err, services.ErrMixedCurrencies),
+			errors.Is(err, services.ErrCouponNotApplicable), errors.Is(err, services.ErrShippingUnavailable):
+			RespondError(c, http.StatusBadRequest, err)
+		case errors.Is(err, services.ErrTransitionForbidden):
+			RespondError(c, http.StatusForbidden, err)
+		case errors.Is(err, services.ErrOrderNotEditable), errors.Is(err, services.ErrInsufficientStock),
+			errors.Is(err, services.ErrInvalidTransition), errors.Is(err, services.ErrOrderStatusChanged),
+			errors.Is(err, services.ErrPaymentInProgress):
+			RespondError(c, http.StatusConflict, err)
+		default:
+			HandleError(c, err, services.ErrOrderNotFound, "заказ не найден")
+		}
+		return
+	}
+	c.JSON(http.StatusOK, o)
+}
+
+// Delete удаляет заказ.
+// @Summary      Удаление заказа
+// @Description  Удаляет неоплаченный или отменённый заказ вместе с историей статусов.
+// @Description  Товары неоплаченного заказа возвращаются на склад.
+// @Tags         Заказы
+// @Produce      json
+// @Param        id       path      int  true  "ID пользователя"
+// @Param        orderId  path      int  true  "ID заказа"
+// @Success      204      {string}  string  "No Content"
+// @Failure      400      {object}  handlers.ErrorResponse "Некорректный ID"
+// @Failure      404      {object}  handlers.ErrorResponse "Пользователь не найден или заказ не найден"
+// @Failure      409      {object}  handlers.ErrorResponse "Заказ оплачен или оплачивается"
+// @Failure      500      {object}  handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /users/{id}/orders/{orderId} [delete]
+func (h *OrderHandler) Delete(c *gin.Context) {
+	uid, oid, ok := orderParams(c, h.svc.CheckUser)
+	if !ok {
+		return
+	}
+	if err := h.svc.Delete(c.Request.Context(), uid, oid); err != nil {
+		if errors.Is(err, services.ErrOrderNotDeletable) || errors.Is(err, services.ErrPaymentInProgress) {
+			RespondError(c, http.StatusConflict, err)
+			return
+		}
+		HandleError(c, err, services.ErrOrderNotFound, "заказ не найден")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Transition меняет статус заказа.
+// @Summary      Смена статуса заказа
+// @Description  Переводит заказ в новый статус. Допустимые переходы: pending → paid | cancelled,
+// @Description  paid → shipped | cancelled, shipped → delivered. Владелец может только отменить
+// @Description  неоплаченный заказ, остальные переходы выполняет администратор. Переход записывается в историю.
+// @Description  Пока заказ оплачивается через POST /users/{id}/orders/{orderId}/pay, отменить его или отметить оплаченным нельзя.
+// @Description  Ручная отметка paid записывается платежом провайдера manual; отмена оплаченного заказа возвращает платёж.
+// @Tags         Заказы
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                              true  "ID пользователя"
+// @Param        orderId  path      int                              true  "ID заказа"
+// @Param        input    body      services.OrderTransitionRequest  true  "Новый статус"
+// @Success      200      {object}  services.OrderResponse
+// @Failure      400      {object}  handlers.ValidationErrorResponse "Некорректный ID или статус"
+// @Failure      403      {object}  handlers.ErrorResponse "Переход доступен только администратору"
+// @Failure      404      {object}  handlers.ErrorResponse "Пользователь или заказ не найден"
+// @Failure      409      {object}  handlers.ErrorResponse "Переход из текущего статуса недопустим или заказ оплачивается"
+// @Failure      500      {object}  handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /users/{id}/orders/{orderId}/transitions [post]
+func (h *OrderHandler) Transition(c *gin.Context) {
+	uid, oid, ok := orderParams(c, h.svc.CheckUser)
+	if !ok {
+		return
+	}
+	var req services.OrderTransitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
+		return
+	}
+	actor := services.Actor{UserID: c.GetUint("user_id"), Role: c.GetString("role")}
+	o, err := h.svc.Transition(c.Request.Context(), uid, oid, actor, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTransitionForbidden):
+			RespondError(c, http.StatusForbidden, err)
+		case errors.Is(err, services.ErrInvalidTransition), errors.Is(err, services.ErrOrderStatusChanged),
+			errors.Is(err, services.ErrPaymentInProgress):
+			RespondError(c, http.StatusConflict, err)
+		default:
+			HandleError(c, err, services.ErrOrderNotFound, "заказ не найден")
+		}
+		return
+	}
+	c.JSON(http.StatusOK, o)
+}
+
+// StatusHistory возвращает историю статусов заказа.
+// @Summary      История статусов заказа
+// @Description  Возвращает переходы статусов заказа в хронологическом порядке: кто и когда их выполнил.
+// @Tags         Заказы
+// @Produce      json
+// @Param        id       path      int  true  "ID пользователя"
+// @Param        orderId  path      int  true  "ID заказа"
+// @Success      200      {array}   services.OrderStatusChangeResponse
+// @Failure      400      {object}  handlers.ErrorResponse "Некорректный ID"
+// @Failure      404      {object}  handlers.ErrorResponse "Пользователь или заказ не найден"
+// @Failure      500      {object}  handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /users/{id}/orders/{orderId}/transitions [get]
+func (h *OrderHandler) StatusHistory(c *gin.Context) {
+	uid, oid, ok := orderParams(c, h.svc.CheckUser)
+	if !ok {
+		return
+	}
+	list, err := h.svc.StatusHistory(c.Request.Context(), uid, oid)
+	if err != nil {
+		HandleError(c, err, services.ErrOrderNotFound, "заказ не найден")
+		return
+	}
 	c.JSON(http.StatusOK, list)
 }
+
+// orderParams разбирает ID пользователя и заказа из пути и проверяет, что пользователь существует.
+// Пользователь проверяется функцией checkUser. При ошибке отвечает сам и возвращает ok=false.
+func orderParams(c *gin.Context, checkUser func(context.Context, uint) error) (userID, orderID uint, ok bool) {
+	uid, err := strconv.Atoi(c.Param("id"))
+	if err != nil || uid <= 0 {
+		HandleError(c, fmt.Errorf("ID должен быть положительным целым числом"), nil, "ID должен быть положительным целым числом")
+		return 0, 0, false
+	}
+	oid, err := strconv.Atoi(c.Param("orderId"))
+	if err != nil || oid <= 0 {
+		HandleError(c, fmt.Errorf("ID должен быть положительным целым числом"), nil, "ID должен быть положительным целым числом")
+		return 0, 0, false
+	}
+	if err := checkUser(c.Request.Context(), uint(uid)); err != nil {
+		HandleError(c, err, gorm.ErrRecordNotFound, "пользователь не найден")
+		return 0, 0, false
+	}
+	return uint(uid), uint(oid), true
+}
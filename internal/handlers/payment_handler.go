@@ -0,0 +1,149 @@
+// payment_handler.go
+// Этот файл реализует HTTP-слой оплаты заказов и приём уведомлений платёжного провайдера.
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"kvant_task/internal/config"
+	"kvant_task/internal/payment"
+	"kvant_task/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxWebhookBody — предельный размер тела уведомления провайдера.
+const maxWebhookBody = 64 << 10
+
+// PaymentHandler — HTTP-слой для платежей.
+type PaymentHandler struct {
+	svc *services.PaymentService
+}
+
+// NewPaymentHandler конструктор для создания нового PaymentHandler.
+func NewPaymentHandler(db *gorm.DB, cfg *config.Config, provider payment.PaymentProvider) *PaymentHandler {
+	return &PaymentHandler{svc: services.NewPaymentService(db, cfg, provider)}
+}
+
+// Pay обрабатывает POST /users/:id/orders/:orderId/pay
+// @Summary      Оплата заказа
+// @Description  Оплачивает неоплаченный заказ на сумму total: платёж авторизуется и списывается у провайдера,
+// @Description  заказ переходит в paid. Если провайдер подтверждает платёж асинхронно, возвращается 202
+// @Description  с платежом в статусе pending — заказ станет оплаченным по уведомлению провайдера.
+// @Description  Пока у заказа есть действующий платёж, изменить, удалить или отменить его нельзя.
+// @Description  Платёж без подтверждения дольше PAYMENT_PENDING_TTL считается неудавшимся. Тело запроса необязательно.
+// @Tags         Платежи
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                       true   "ID пользователя"
+// @Param        orderId  path      int                       true   "ID заказа"
+// @Param        input    body      services.PayOrderRequest  false  "Способ оплаты"
+// @Success      200      {object}  services.PaymentResponse  "Заказ оплачен"
+// @Success      202      {object}  services.PaymentResponse  "Платёж ждёт подтверждения провайдера"
+// @Failure      400      {object}  handlers.ErrorResponse "Некорректные данные"
+// @Failure      402      {object}  handlers.ErrorResponse "Платёж отклонён"
+// @Failure      404      {object}  handlers.ErrorResponse "Пользователь или заказ не найден"
+// @Failure      409      {object}  handlers.ErrorResponse "Заказ уже оплачен, отменён или оплачивается"
+// @Failure      500      {object}  handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Failure      502      {object}  handlers.ErrorResponse "Ошибка платёжного провайдера"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /users/{id}/orders/{orderId}/pay [post]
+func (h *PaymentHandler) Pay(c *gin.Context) {
+	uid, oid, ok := orderParams(c, h.svc.CheckUser)
+	if !ok {
+		return
+	}
+	var req services.PayOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
+		return
+	}
+	actor := services.Actor{UserID: c.GetUint("user_id"), Role: c.GetString("role")}
+	p, err := h.svc.Pay(c.Request.Context(), uid, oid, actor, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPaymentDeclined):
+			RespondError(c, http.StatusPaymentRequired, err)
+		case errors.Is(err, services.ErrOrderNotPayable), errors.Is(err, services.ErrPaymentInProgress):
+			RespondError(c, http.StatusConflict, err)
+		case errors.Is(err, services.ErrPaymentProvider):
+			RespondError(c, http.StatusBadGateway, services.ErrPaymentProvider)
+		default:
+			HandleError(c, err, services.ErrOrderNotFound, "заказ не найден")
+		}
+		return
+	}
+	if p.Status == payment.StatusPending {
+		c.JSON(http.StatusAccepted, p)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// ListByOrder обрабатывает GET /users/:id/orders/:orderId/payments
+// @Summary      Платежи заказа
+// @Description  Возвращает все попытки оплаты заказа в порядке создания, включая отклонённые и возвращённые.
+// @Tags         Платежи
+// @Produce      json
+// @Param        id       path      int  true  "ID пользователя"
+// @Param        orderId  path      int  true  "ID заказа"
+// @Success      200      {array}   services.PaymentResponse
+// @Failure      400      {object}  handlers.ErrorResponse "Некорректный ID"
+// @Failure      404      {object}  handlers.ErrorResponse "Пользователь или заказ не найден"
+// @Failure      500      {object}  handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /users/{id}/orders/{orderId}/payments [get]
+func (h *PaymentHandler) ListByOrder(c *gin.Context) {
+	uid, oid, ok := orderParams(c, h.svc.CheckUser)
+	if !ok {
+		return
+	}
+	list, err := h.svc.ListByOrder(c.Request.Context(), uid, oid)
+	if err != nil {
+		HandleError(c, err, services.ErrOrderNotFound, "заказ не найден")
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// Webhook обрабатывает POST /payments/webhook
+// @Summary      Уведомление платёжного провайдера
+// @Description  Принимает уведомление провайдера о списании, отказе или возврате платежа.
+// @Description  Подпись проверяется провайдером, сумма и валюта списания сверяются с платежом;
+// @Description  повторное уведомление с тем же ID ничего не меняет. Если платёж истёк или заказ
+// @Description  уже не ждёт оплаты, списанная сумма возвращается.
+// @Tags         Платежи
+// @Accept       json
+// @Produce      json
+// @Success      204  {string}  string  "No Content"
+// @Failure      400  {object}  handlers.ErrorResponse "Неверная подпись, формат или сумма уведомления"
+// @Failure      404  {object}  handlers.ErrorResponse "Платёж не найден"
+// @Failure      500  {object}  handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Failure      502  {object}  handlers.ErrorResponse "Ошибка платёжного провайдера"
+// @Router       /payments/webhook [post]
+func (h *PaymentHandler) Webhook(c *gin.Context) {
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBody))
+	if err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("%w: %v", services.ErrInvalidWebhook, err))
+		return
+	}
+	if err := h.svc.HandleWebhook(c.Request.Context(), c.Request.Header, payload); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidWebhook):
+			RespondError(c, http.StatusBadRequest, err)
+		case errors.Is(err, services.ErrPaymentProvider):
+			RespondError(c, http.StatusBadGateway, services.ErrPaymentProvider)
+		default:
+			HandleError(c, err, services.ErrPaymentNotFound, "платёж не найден")
+		}
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,214 @@
+// product_handler.go
+// Этот файл реализует HTTP-слой каталога товаров.
+// Просматривать каталог может любой пользователь, изменять — администратор.
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"kvant_task/internal/config"
+	"kvant_task/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ProductHandler — HTTP-слой для товаров.
+type ProductHandler struct {
+	svc *services.ProductService
+}
+
+// NewProductHandler конструктор для создания нового ProductHandler.
+func NewProductHandler(db *gorm.DB, cfg *config.Config) *ProductHandler {
+	return &ProductHandler{svc: services.NewProductService(db, cfg)}
+}
+
+// Create обрабатывает POST /products
+// @Summary      Создание товара
+// @Description  Добавляет товар в каталог. Артикул должен быть уникальным. Цена передаётся строкой
+// @Description  с числом знаков не больше, чем у валюты (ISO 4217); валюта по умолчанию — DEFAULT_CURRENCY.
+// @Tags         Товары
+// @Accept       json
+// @Produce      json
+// @Param        input  body      services.CreateProductRequest  true  "Данные товара"
+// @Success      201    {object}  services.ProductResponse
+// @Failure      400    {object}  handlers.ValidationErrorResponse
+// @Failure      401    {object}  handlers.ErrorResponse
+// @Failure      403    {object}  handlers.ErrorResponse "Требуется роль администратора"
+// @Failure      409    {object}  handlers.ErrorResponse "Артикул занят"
+// @Failure      500    {object}  handlers.ErrorResponse
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /products [post]
+func (h *ProductHandler) Create(c *gin.Context) {
+	var req services.CreateProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
+		return
+	}
+	p, err := h.svc.Create(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrSKUTaken) {
+			RespondError(c, http.StatusConflict, err)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidProduct) {
+			RespondError(c, http.StatusBadRequest, err)
+			return
+		}
+		HandleError(c, err, nil, "ошибка при создании товара")
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+// List обрабатывает GET /products
+// @Summary      Каталог товаров
+// @Description  Возвращает товары с текущими ценами и остатками, по страницам.
+// @Tags         Товары
+// @Produce      json
+// @Param        page   query     int  false  "Номер страницы"   default(1)
+// @Param        limit  query     int  false  "Размер страницы"  default(20)
+// @Success      200    {object}  handlers.ProductListResponse
+// @Failure      400    {object}  handlers.ErrorResponse
+// @Failure      401    {object}  handlers.ErrorResponse
+// @Failure      500    {object}  handlers.ErrorResponse
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /products [get]
+func (h *ProductHandler) List(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("номер страницы должен быть положительным целым числом"))
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("размер страницы должен быть от 1 до 100"))
+		return
+	}
+	list, total, err := h.svc.List(c.Request.Context(), page, limit)
+	if err != nil {
+		HandleError(c, err, nil, "ошибка при получении каталога")
+		return
+	}
+	c.JSON(http.StatusOK, ProductListResponse{
+		Page:     page,
+		Limit:    limit,
+		Total:    total,
+		Products: list,
+	})
+}
+
+// GetByID обрабатывает GET /products/:productId
+// @Summary      Товар
+// @Description  Возвращает товар каталога по ID.
+// @Tags         Товары
+// @Produce      json
+// @Param        productId  path      int  true  "ID товара"
+// @Success      200        {object}  services.ProductResponse
+// @Failure      400        {object}  handlers.ErrorResponse
+// @Failure      401        {object}  handlers.ErrorResponse
+// @Failure      404        {object}  handlers.ErrorResponse
+// @Failure      500        {object}  handlers.ErrorResponse
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /products/{productId} [get]
+func (h *ProductHandler) GetByID(c *gin.Context) {
+	id, ok := productID(c)
+	if !ok {
+		return
+	}
+	p, err := h.svc.Get(c.Request.Context(), id)
+	if err != nil {
+		HandleError(c, err, services.ErrProductNotFound, "товар не найден")
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// Update обрабатывает PUT /products/:productId
+// @Summary      Изменение товара
+// @Description  Меняет заданные поля товара. Новая цена действует для следующих заказов.
+// @Description  При смене валюты нужно передать и цену в новой валюте.
+// @Tags         Товары
+// @Accept       json
+// @Produce      json
+// @Param        productId  path      int                            true  "ID товара"
+// @Param        input      body      services.UpdateProductRequest  true  "Изменяемые поля"
+// @Success      200        {object}  services.ProductResponse
+// @Failure      400        {object}  handlers.ValidationErrorResponse
+// @Failure      401        {object}  handlers.ErrorResponse
+// @Failure      403        {object}  handlers.ErrorResponse "Требуется роль администратора"
+// @Failure      404        {object}  handlers.ErrorResponse
+// @Failure      409        {object}  handlers.ErrorResponse "Артикул занят"
+// @Failure      500        {object}  handlers.ErrorResponse
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /products/{productId} [put]
+func (h *ProductHandler) Update(c *gin.Context) {
+	id, ok := productID(c)
+	if !ok {
+		return
+	}
+	var req services.UpdateProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
+		return
+	}
+	p, err := h.svc.Update(c.Request.Context(), id, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrSKUTaken) {
+			RespondError(c, http.StatusConflict, err)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidProduct) {
+			RespondError(c, http.StatusBadRequest, err)
+			return
+		}
+		HandleError(c, err, services.ErrProductNotFound, "товар не найден")
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// Delete обрабатывает DELETE /products/:productId
+// @Summary      Удаление товара
+// @Description  Удаляет товар из каталога. Позиции оформленных заказов сохраняют его артикул, название и цену.
+// @Tags         Товары
+// @Produce      json
+// @Param        productId  path      int  true  "ID товара"
+// @Success      204        {string}  string  "No Content"
+// @Failure      400        {object}  handlers.ErrorResponse
+// @Failure      401        {object}  handlers.ErrorResponse
+// @Failure      403        {object}  handlers.ErrorResponse "Требуется роль администратора"
+// @Failure      404        {object}  handlers.ErrorResponse
+// @Failure      500        {object}  handlers.ErrorResponse
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /products/{productId} [delete]
+func (h *ProductHandler) Delete(c *gin.Context) {
+	id, ok := productID(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.Delete(c.Request.Context(), id); err != nil {
+		HandleError(c, err, services.ErrProductNotFound, "товар не найден")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// productID разбирает ID товара из пути. При ошибке отвечает сам и возвращает ok=false.
+func productID(c *gin.Context) (uint, bool) {
+	id, err := strconv.Atoi(c.Param("productId"))
+	if err != nil || id <= 0 {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("ID должен быть положительным целым числом"))
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,95 @@
+// report_handler.go
+// Этот файл реализует HTTP-слой отчётов по заказам.
+// Сводку своих заказов видит пользователь, отчёт о выручке — администратор.
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"kvant_task/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReportHandler — HTTP-слой для отчётов.
+type ReportHandler struct {
+	svc *services.ReportService
+}
+
+// NewReportHandler конструктор для создания нового ReportHandler.
+func NewReportHandler(db *gorm.DB) *ReportHandler {
+	return &ReportHandler{svc: services.NewReportService(db)}
+}
+
+// UserSummary обрабатывает GET /users/:id/orders/summary
+// @Summary      Сводка заказов пользователя
+// @Description  Число оплаченных заказов (paid, shipped, delivered), даты первого и последнего
+// @Description  заказа, а по каждой валюте — потрачено всего и средний заказ. Суммы — строки.
+// @Tags         Отчёты
+// @Produce      json
+// @Param        id   path      int  true  "ID пользователя"
+// @Success      200  {object}  services.OrderSummaryResponse
+// @Failure      400  {object}  handlers.ErrorResponse "Некорректный ID пользователя"
+// @Failure      401  {object}  handlers.ErrorResponse
+// @Failure      403  {object}  handlers.ErrorResponse
+// @Failure      404  {object}  handlers.ErrorResponse "Пользователь не найден"
+// @Failure      500  {object}  handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /users/{id}/orders/summary [get]
+func (h *ReportHandler) UserSummary(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("ID должен быть положительным целым числом"))
+		return
+	}
+	summary, err := h.svc.UserSummary(c.Request.Context(), uint(id))
+	if err != nil {
+		HandleError(c, err, gorm.ErrRecordNotFound, "пользователь не найден")
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// Revenue обрабатывает GET /reports/revenue
+// @Summary      Выручка по периодам
+// @Description  Выручка оплаченных заказов (paid, shipped, delivered) по дням, неделям или месяцам (UTC)
+// @Description  за период from–to включительно, по каждой валюте отдельно. С group_by=product выручка
+// @Description  разбита по товарам — это стоимость их позиций. Периоды без заказов не возвращаются.
+// @Tags         Отчёты
+// @Produce      json
+// @Param        from      query     string  true   "Начало периода (дата или RFC 3339)"
+// @Param        to        query     string  true   "Конец периода включительно (дата или RFC 3339)"
+// @Param        period    query     string  false  "Разбивка"               Enums(day, week, month) default(day)
+// @Param        currency  query     string  false  "Только заказы в валюте"
+// @Param        group_by  query     string  false  "Разбить по товарам"     Enums(product)
+// @Success      200       {object}  services.RevenueReport
+// @Failure      400       {object}  handlers.ErrorResponse "Некорректные параметры"
+// @Failure      401       {object}  handlers.ErrorResponse
+// @Failure      403       {object}  handlers.ErrorResponse "Требуется роль администратора"
+// @Failure      500       {object}  handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /reports/revenue [get]
+func (h *ReportHandler) Revenue(c *gin.Context) {
+	var q services.RevenueQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные параметры: %w", err))
+		return
+	}
+	report, err := h.svc.Revenue(c.Request.Context(), &q)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidReport) {
+			RespondError(c, http.StatusBadRequest, err)
+			return
+		}
+		HandleError(c, err, nil, "ошибка при построении отчёта")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,82 @@
+// session_handler.go
+// Этот файл реализует HTTP-слой для сессий текущего пользователя.
+// Содержит обработчики просмотра сессий и их завершения.
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"kvant_task/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSessions обрабатывает GET /users/me/sessions
+// @Summary Активные сессии
+// @Description Возвращает активные сессии текущего пользователя: IP, User-Agent, время входа и последней активности.
+// @Description Сессия текущего токена отмечена полем current.
+// @Tags Сессии
+// @Produce json
+// @Success 200 {array} services.SessionResponse
+// @Failure 401 {object} handlers.ErrorResponse "Неавторизованный доступ"
+// @Failure 403 {object} handlers.ErrorResponse "Запрос выполнен по API-ключу"
+// @Failure 500 {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /users/me/sessions [get]
+func (h *UserHandler) ListSessions(c *gin.Context) {
+	list, err := h.svc.ListSessions(c.Request.Context(), c.GetUint("user_id"), c.GetUint("session_id"))
+	if err != nil {
+		HandleError(c, err, nil, "ошибка при получении сессий")
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// RevokeSession обрабатывает DELETE /users/me/sessions/:sessionId
+// @Summary Завершение сессии
+// @Description Отзывает refresh- и access-токены сессии. Токены этой сессии перестают приниматься сразу.
+// @Tags Сессии
+// @Produce json
+// @Param sessionId path int true "ID сессии"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} handlers.ErrorResponse "Некорректный ID"
+// @Failure 401 {object} handlers.ErrorResponse "Неавторизованный доступ"
+// @Failure 403 {object} handlers.ErrorResponse "Запрос выполнен по API-ключу"
+// @Failure 404 {object} handlers.ErrorResponse "Сессия не найдена"
+// @Failure 500 {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /users/me/sessions/{sessionId} [delete]
+func (h *UserHandler) RevokeSession(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("sessionId"))
+	if err != nil || id <= 0 {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("ID должен быть положительным целым числом"))
+		return
+	}
+	if err := h.svc.RevokeSession(c.Request.Context(), c.GetUint("user_id"), uint(id)); err != nil {
+		HandleError(c, err, services.ErrNotFound, "сессия не найдена")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeAllSessions обрабатывает DELETE /users/me/sessions
+// @Summary Выйти везде
+// @Description Завершает все сессии текущего пользователя, включая текущую.
+// @Tags Сессии
+// @Produce json
+// @Success 204 {string} string "No Content"
+// @Failure 401 {object} handlers.ErrorResponse "Неавторизованный доступ"
+// @Failure 403 {object} handlers.ErrorResponse "Запрос выполнен по API-ключу"
+// @Failure 500 {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /users/me/sessions [delete]
+func (h *UserHandler) RevokeAllSessions(c *gin.Context) {
+	if err := h.svc.RevokeAllSessions(c.Request.Context(), c.GetUint("user_id")); err != nil {
+		HandleError(c, err, nil, "ошибка при завершении сессий")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,170 @@
+// two_factor_handler.go
+// Этот файл реализует HTTP-слой двухфакторной аутентификации:
+// второй шаг входа и управление TOTP текущего пользователя.
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"kvant_task/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginTwoFactor обрабатывает POST /auth/login/2fa
+// @Summary Второй шаг входа
+// @Description Обменивает challenge-токен из /auth/login и код из приложения-аутентификатора
+// @Description (или код восстановления) на access- и refresh-токены.
+// @Tags Аутентификация
+// @Accept json
+// @Produce json
+// @Param input body services.TwoFactorLoginRequest true "Challenge-токен и код"
+// @Success 200 {object} services.TokenResponse "Успешная аутентификация"
+// @Failure 400 {object} handlers.ErrorResponse "Некорректные данные"
+// @Failure 401 {object} handlers.ErrorResponse "Неверный код или недействительный challenge-токен"
+// @Failure 429 {object} handlers.ErrorResponse "Слишком много неудачных попыток; см. заголовок Retry-After"
+// @Failure 500 {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /auth/login/2fa [post]
+func (h *UserHandler) LoginTwoFactor(c *gin.Context) {
+	var req services.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
+		return
+	}
+	tok, err := h.svc.LoginTwoFactor(c.Request.Context(), &req, clientInfo(c))
+	if err != nil {
+		if respondLocked(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidTwoFactorCode) || errors.Is(err, services.ErrInvalidChallenge) {
+			RespondError(c, http.StatusUnauthorized, err)
+			return
+		}
+		HandleError(c, err, nil, "ошибка при входе")
+		return
+	}
+	c.JSON(http.StatusOK, tok)
+}
+
+// EnrollTwoFactor обрабатывает POST /users/me/2fa
+// @Summary Подключение 2FA
+// @Description Создаёт секрет TOTP и otpauth-ссылку для приложения-аутентификатора.
+// @Description Двухфакторная аутентификация включается после подтверждения кодом через /users/me/2fa/verify.
+// @Tags Аутентификация
+// @Produce json
+// @Success 200 {object} services.TwoFactorEnrollResponse "Секрет и otpauth-ссылка"
+// @Failure 401 {object} handlers.ErrorResponse "Неавторизованный доступ"
+// @Failure 409 {object} handlers.ErrorResponse "2FA уже включена"
+// @Failure 500 {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /users/me/2fa [post]
+func (h *UserHandler) EnrollTwoFactor(c *gin.Context) {
+	resp, err := h.svc.EnrollTwoFactor(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ConfirmTwoFactor обрабатывает POST /users/me/2fa/verify
+// @Summary Включение 2FA
+// @Description Проверяет первый код из приложения-аутентификатора, включает 2FA
+// @Description и возвращает одноразовые коды восстановления. Коды показываются один раз.
+// @Tags Аутентификация
+// @Accept json
+// @Produce json
+// @Param input body services.TwoFactorCodeRequest true "Код из приложения"
+// @Success 200 {object} services.RecoveryCodesResponse "Коды восстановления"
+// @Failure 400 {object} handlers.ErrorResponse "Некорректные данные"
+// @Failure 401 {object} handlers.ErrorResponse "Неавторизованный доступ"
+// @Failure 403 {object} handlers.ErrorResponse "Неверный код"
+// @Failure 409 {object} handlers.ErrorResponse "2FA уже включена или не подключена"
+// @Failure 500 {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /users/me/2fa/verify [post]
+func (h *UserHandler) ConfirmTwoFactor(c *gin.Context) {
+	var req services.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
+		return
+	}
+	resp, err := h.svc.ConfirmTwoFactor(c.Request.Context(), c.GetUint("user_id"), &req)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DisableTwoFactor обрабатывает POST /users/me/2fa/disable
+// @Summary Отключение 2FA
+// @Description Отключает двухфакторную аутентификацию. Требует пароль и код из приложения или код восстановления.
+// @Tags Аутентификация
+// @Accept json
+// @Produce json
+// @Param input body services.TwoFactorDisableRequest true "Пароль и код"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} handlers.ErrorResponse "Некорректные данные"
+// @Failure 401 {object} handlers.ErrorResponse "Неавторизованный доступ"
+// @Failure 403 {object} handlers.ErrorResponse "Неверный пароль или код"
+// @Failure 409 {object} handlers.ErrorResponse "2FA не включена"
+// @Failure 500 {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /users/me/2fa/disable [post]
+func (h *UserHandler) DisableTwoFactor(c *gin.Context) {
+	var req services.TwoFactorDisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
+		return
+	}
+	if err := h.svc.DisableTwoFactor(c.Request.Context(), c.GetUint("user_id"), &req); err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes обрабатывает POST /users/me/2fa/recovery-codes
+// @Summary Новые коды восстановления
+// @Description Выдаёт новый набор кодов восстановления; прежние перестают действовать.
+// @Tags Аутентификация
+// @Accept json
+// @Produce json
+// @Param input body services.TwoFactorCodeRequest true "Код из приложения или код восстановления"
+// @Success 200 {object} services.RecoveryCodesResponse "Коды восстановления"
+// @Failure 400 {object} handlers.ErrorResponse "Некорректные данные"
+// @Failure 401 {object} handlers.ErrorResponse "Неавторизованный доступ"
+// @Failure 403 {object} handlers.ErrorResponse "Неверный код"
+// @Failure 409 {object} handlers.ErrorResponse "2FA не включена"
+// @Failure 500 {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /users/me/2fa/recovery-codes [post]
+func (h *UserHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req services.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
+		return
+	}
+	resp, err := h.svc.RegenerateRecoveryCodes(c.Request.Context(), c.GetUint("user_id"), &req)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// respondTwoFactorError переводит ошибки управления 2FA в HTTP-статусы.
+func respondTwoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTwoFactorCode), errors.Is(err, services.ErrWrongPassword):
+		RespondError(c, http.StatusForbidden, err)
+	case errors.Is(err, services.ErrTwoFactorEnabled), errors.Is(err, services.ErrTwoFactorNotEnabled):
+		RespondError(c, http.StatusConflict, err)
+	default:
+		HandleError(c, err, services.ErrNotFound, "пользователь не найден")
+	}
+}
@@ -13,3 +13,39 @@ type UserListResponse struct {
 	Total int64                   `json:"total"`
 	Users []services.UserResponse `json:"users"`
 }
+
+// OrderListResponse — страница заказов.
+type OrderListResponse struct {
+	Page  int   `json:"page"`
+	Limit int   `json:"limit"`
+	Total int64 `json:"total"`
+	// Курсор следующей страницы; пустой на последней странице
+	NextCursor string                   `json:"next_cursor,omitempty"`
+	Orders     []services.OrderResponse `json:"orders"`
+}
+
+// AdminOrderListResponse — страница результатов поиска заказов.
+type AdminOrderListResponse struct {
+	Page  int   `json:"page"`
+	Limit int   `json:"limit"`
+	Total int64 `json:"total"`
+	// Курсор следующей страницы; пустой на последней странице
+	NextCursor string                        `json:"next_cursor,omitempty"`
+	Orders     []services.AdminOrderResponse `json:"orders"`
+}
+
+// ProductListResponse — страница каталога.
+type ProductListResponse struct {
+	Page     int                        `json:"page"`
+	Limit    int                        `json:"limit"`
+	Total    int64                      `json:"total"`
+	Products []services.ProductResponse `json:"products"`
+}
+
+// CouponListResponse — страница промокодов.
+type CouponListResponse struct {
+	Page    int                       `json:"page"`
+	Limit   int                       `json:"limit"`
+	Total   int64                     `json:"total"`
+	Coupons []services.CouponResponse `json:"coupons"`
+}
@@ -5,13 +5,17 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
-	"strings"
+	"time"
 
-	"kvant_task/internal/middleware"
+	"kvant_task/internal/config"
+	"kvant_task/internal/lockout"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/password"
 	"kvant_task/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -24,19 +28,20 @@ type UserHandler struct {
 }
 
 // NewUserHandler конструктор для создания нового UserHandler.
-func NewUserHandler(db *gorm.DB, jwtSecret string) *UserHandler {
-	return &UserHandler{svc: services.NewUserService(db, jwtSecret)}
+func NewUserHandler(db *gorm.DB, cfg *config.Config, tokens *services.TokenService, notifier notify.Notifier, attempts *lockout.Guard, policy *password.Policy) *UserHandler {
+	return &UserHandler{svc: services.NewUserService(db, cfg, tokens, notifier, attempts, policy)}
 }
 
 // CreateUser обрабатывает POST /users
 // @Summary Создать пользователя
 // @Description Создаёт нового пользователя и возвращает его данные.
+// @Description Пароль проверяется политикой паролей; каждое нарушение — отдельный элемент errors.
 // @Tags Пользователи
 // @Accept json
 // @Produce json
 // @Param input body services.RegisterRequest true "Данные пользователя"
 // @Success 201 {object} services.UserResponse "Пользователь успешно создан"
-// @Failure 400 {object} handlers.ErrorResponse "Пользователь с таким email уже существует"
+// @Failure 400 {object} handlers.ValidationErrorResponse "Пароль не соответствует политике"
 // @Failure 422 {object} handlers.ValidationErrorResponse "Ошибка валидации данных"
 // @Failure 500 {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
 // @Router /users [post]
@@ -56,15 +61,19 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 
 // Login обрабатывает POST /login
 // @Summary Аутентификация
-// @Description Возвращает JWT по email и паролю.
+// @Description Возвращает access-токен (JWT) и refresh-токен по email и паролю.
+// @Description Если включена двухфакторная аутентификация, вместо токенов возвращается challenge_token
+// @Description для POST /auth/login/2fa.
 // @Tags Пользователи
 // @Accept json
 // @Produce json
 // @Param input body services.LoginRequest true "Данные для логина"
-// @Success 200 {object} services.TokenResponse "Успешная аутентификация"
+// @Success 200 {object} services.LoginResponse "Успешная аутентификация или требуется второй фактор"
 // @Failure 400 {object} handlers.ErrorResponse "Некорректные данные для входа"
 // @Failure 401 {object} handlers.ErrorResponse "Неверный email или пароль"
+// @Failure 403 {object} handlers.ErrorResponse "Email не подтверждён"
 // @Failure 422 {object} handlers.ValidationErrorResponse "Ошибка валидации данных"
+// @Failure 429 {object} handlers.ErrorResponse "Слишком много неудачных попыток; см. заголовок Retry-After"
 // @Router /auth/login [post]
 func (h *UserHandler) Login(c *gin.Context) {
 	var req services.LoginRequest
@@ -72,14 +81,268 @@ func (h *UserHandler) Login(c *gin.Context) {
 		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
 		return
 	}
-	tok, err := h.svc.Login(c.Request.Context(), &req)
+	tok, err := h.svc.Login(c.Request.Context(), &req, clientInfo(c))
 	if err != nil {
+		if respondLocked(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrEmailNotVerified) {
+			RespondError(c, http.StatusForbidden, err)
+			return
+		}
 		HandleError(c, err, services.ErrInvalidCredentials, "неверный email или пароль")
 		return
 	}
 	c.JSON(http.StatusOK, tok)
 }
 
+// clientInfo собирает сведения о клиенте для записи в сессию.
+func clientInfo(c *gin.Context) services.ClientInfo {
+	return services.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// respondLocked отвечает 429 с заголовком Retry-After, если вход временно заблокирован.
+func respondLocked(c *gin.Context, err error) bool {
+	var locked *lockout.LockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	retry := locked.RetryAfter(time.Now())
+	c.Header("Retry-After", strconv.Itoa(int(retry/time.Second)))
+	RespondError(c, http.StatusTooManyRequests, err)
+	return true
+}
+
+// Refresh обрабатывает POST /auth/refresh
+// @Summary Обновление токенов
+// @Description Обменивает refresh-токен на новую пару токенов. Старый refresh-токен становится недействительным;
+// @Description повторное его использование отзывает все токены этой сессии.
+// @Tags Пользователи
+// @Accept json
+// @Produce json
+// @Param input body services.RefreshRequest true "Refresh-токен"
+// @Success 200 {object} services.TokenResponse "Новая пара токенов"
+// @Failure 400 {object} handlers.ErrorResponse "Некорректные данные"
+// @Failure 401 {object} handlers.ErrorResponse "Refresh-токен недействителен или уже использован"
+// @Failure 500 {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /auth/refresh [post]
+func (h *UserHandler) Refresh(c *gin.Context) {
+	var req services.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
+		return
+	}
+	tok, err := h.svc.Refresh(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			RespondError(c, http.StatusUnauthorized, err)
+			return
+		}
+		HandleError(c, err, nil, "ошибка при обновлении токенов")
+		return
+	}
+	c.JSON(http.StatusOK, tok)
+}
+
+// Logout обрабатывает POST /auth/logout
+// @Summary Выход
+// @Description Отзывает текущий access-токен и завершает его сессию. Если передан refresh-токен,
+// @Description отзывается и его семейство.
+// @Tags Пользователи
+// @Accept json
+// @Produce json
+// @Param input body services.LogoutRequest false "Refresh-токен сессии"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} handlers.ErrorResponse "Некорректные данные"
+// @Failure 401 {object} handlers.ErrorResponse "Неавторизованный доступ или чужой refresh-токен"
+// @Failure 500 {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /auth/logout [post]
+func (h *UserHandler) Logout(c *gin.Context) {
+	var req services.LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
+			return
+		}
+	}
+	err := h.svc.Logout(c.Request.Context(), c.GetUint("user_id"), c.GetUint("session_id"), c.GetString("jti"), c.GetTime("token_exp"), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			RespondError(c, http.StatusUnauthorized, err)
+			return
+		}
+		HandleError(c, err, nil, "ошибка при выходе")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ChangePassword обрабатывает POST /users/me/password
+// @Summary Смена пароля
+// @Description Меняет пароль текущего пользователя. Требует текущий пароль.
+// @Description После смены все сессии пользователя завершаются, нужно войти заново.
+// @Description Новый пароль проверяется политикой паролей.
+// @Tags Пользователи
+// @Accept json
+// @Produce json
+// @Param input body services.ChangePasswordRequest true "Текущий и новый пароль"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} handlers.ValidationErrorResponse "Ошибка валидации данных"
+// @Failure 401 {object} handlers.ErrorResponse "Неавторизованный доступ"
+// @Failure 403 {object} handlers.ErrorResponse "Неверный текущий пароль"
+// @Failure 500 {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /users/me/password [post]
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	var req services.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
+		return
+	}
+	if err := h.svc.ChangePassword(c.Request.Context(), c.GetUint("user_id"), &req); err != nil {
+		if errors.Is(err, services.ErrWrongPassword) {
+			RespondError(c, http.StatusForbidden, err)
+			return
+		}
+		HandleError(c, err, services.ErrNotFound, "пользователь не найден")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RequestPasswordReset обрабатывает POST /auth/password-reset/request
+// @Summary Запрос сброса пароля
+// @Description Отправляет на email одноразовый токен для сброса пароля.
+// @Description Ответ одинаков для зарегистрированных и неизвестных адресов.
+// @Tags Пользователи
+// @Accept json
+// @Produce json
+// @Param input body services.PasswordResetRequest true "Email пользователя"
+// @Success 202 {string} string "Accepted"
+// @Failure 400 {object} handlers.ValidationErrorResponse "Ошибка валидации данных"
+// @Failure 500 {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /auth/password-reset/request [post]
+func (h *UserHandler) RequestPasswordReset(c *gin.Context) {
+	var req services.PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
+		return
+	}
+	if err := h.svc.RequestPasswordReset(c.Request.Context(), &req); err != nil {
+		HandleError(c, err, nil, "ошибка при запросе сброса пароля")
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+// ConfirmPasswordReset обрабатывает POST /auth/password-reset/confirm
+// @Summary Подтверждение сброса пароля
+// @Description Устанавливает новый пароль по токену из письма. Токен одноразовый;
+// @Description после сброса все сессии пользователя завершаются.
+// @Tags Пользователи
+// @Accept json
+// @Produce json
+// @Param input body services.PasswordResetConfirmRequest true "Токен и новый пароль"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} handlers.ValidationErrorResponse "Недействительный токен или пароль не соответствует политике"
+// @Failure 500 {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /auth/password-reset/confirm [post]
+func (h *UserHandler) ConfirmPasswordReset(c *gin.Context) {
+	var req services.PasswordResetConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
+		return
+	}
+	if err := h.svc.ConfirmPasswordReset(c.Request.Context(), &req); err != nil {
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			RespondError(c, http.StatusBadRequest, err)
+			return
+		}
+		HandleError(c, err, nil, "ошибка при сбросе пароля")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// VerifyEmail обрабатывает POST /auth/verify-email
+// @Summary Подтверждение email
+// @Description Подтверждает email по токену из письма. Если токен выдан на новый адрес,
+// @Description указанный через PUT /users/{id}, этот адрес становится основным.
+// @Tags Пользователи
+// @Accept json
+// @Produce json
+// @Param input body services.VerifyEmailRequest true "Токен подтверждения"
+// @Success 200 {object} services.UserResponse "Email подтверждён"
+// @Failure 400 {object} handlers.ErrorResponse "Недействительный токен или адрес уже занят"
+// @Failure 500 {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /auth/verify-email [post]
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	var req services.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
+		return
+	}
+	u, err := h.svc.ConfirmEmail(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidVerificationToken) || errors.Is(err, services.ErrUserExists) {
+			RespondError(c, http.StatusBadRequest, err)
+			return
+		}
+		HandleError(c, err, nil, "ошибка при подтверждении email")
+		return
+	}
+	c.JSON(http.StatusOK, u)
+}
+
+// RequestVerification обрабатывает POST /auth/verify-email/resend
+// @Summary Повторная отправка письма подтверждения без входа
+// @Description Отправляет новый токен подтверждения на неподтверждённый email — для пользователей,
+// @Description которые не могут войти без подтверждения. Ответ всегда 202, зарегистрирован адрес или нет.
+// @Tags Пользователи
+// @Accept json
+// @Produce json
+// @Param input body services.VerificationResendRequest true "Email"
+// @Success 202 {string} string "Accepted"
+// @Failure 400 {object} handlers.ValidationErrorResponse "Некорректный email"
+// @Failure 500 {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Router /auth/verify-email/resend [post]
+func (h *UserHandler) RequestVerification(c *gin.Context) {
+	var req services.VerificationResendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("некорректные данные: %w", err))
+		return
+	}
+	if err := h.svc.RequestVerification(c.Request.Context(), &req); err != nil {
+		HandleError(c, err, nil, "ошибка при отправке письма подтверждения")
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+// ResendVerification обрабатывает POST /users/me/email/verification
+// @Summary Повторная отправка письма подтверждения
+// @Description Отправляет новый токен на ожидающий подтверждения email. Предыдущие токены становятся недействительными.
+// @Tags Пользователи
+// @Produce json
+// @Success 202 {string} string "Accepted"
+// @Failure 401 {object} handlers.ErrorResponse "Неавторизованный доступ"
+// @Failure 409 {object} handlers.ErrorResponse "Email уже подтверждён"
+// @Failure 500 {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
+// @Security BearerAuth
+// @Router /users/me/email/verification [post]
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	if err := h.svc.ResendVerification(c.Request.Context(), c.GetUint("user_id")); err != nil {
+		if errors.Is(err, services.ErrEmailAlreadyVerified) {
+			RespondError(c, http.StatusConflict, err)
+			return
+		}
+		HandleError(c, err, services.ErrNotFound, "пользователь не найден")
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
 // List возвращает пользователей с пагинацией и фильтрацией.
 // @Summary      Список пользователей
 // @Description  Пагинация и фильтрация по возрасту.
@@ -93,6 +356,7 @@ func (h *UserHandler) Login(c *gin.Context) {
 // @Failure      401      {object} handlers.ErrorResponse "Неавторизованный доступ"
 // @Failure      500      {object} handlers.ErrorResponse "Внутренняя ошибка сервера"
 // @Security     BearerAuth
+// @Security     ApiKeyAuth
 // @Router       /users [get]
 func (h *UserHandler) List(c *gin.Context) {
 	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -170,6 +434,7 @@ func (h *UserHandler) List(c *gin.Context) {
 // @Failure      404  {object}  handlers.ErrorResponse
 // @Failure      500  {object}  handlers.ErrorResponse
 // @Security     BearerAuth
+// @Security     ApiKeyAuth
 // @Router       /users/{id} [get]
 func (h *UserHandler) GetByID(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -188,6 +453,7 @@ func (h *UserHandler) GetByID(c *gin.Context) {
 // Update изменяет данные пользователя.
 // @Summary      Обновление пользователя
 // @Description  Обновляет имя, email или возраст.
+// @Description  Новый email вступает в силу только после подтверждения по ссылке из письма.
 // @Tags         Пользователи
 // @Accept       json
 // @Produce      json
@@ -199,6 +465,7 @@ func (h *UserHandler) GetByID(c *gin.Context) {
 // @Failure      404    {object}  handlers.ErrorResponse
 // @Failure      500    {object}  handlers.ErrorResponse
 // @Security     BearerAuth
+// @Security     ApiKeyAuth
 // @Router       /users/{id} [put]
 func (h *UserHandler) Update(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -212,6 +479,46 @@ func (h *UserHandler) Update(c *gin.Context) {
 		return
 	}
 	u, err := h.svc.Update(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrUserExists) {
+			RespondError(c, http.StatusBadRequest, err)
+			return
+		}
+		HandleError(c, err, services.ErrNotFound, "пользователь не найден")
+		return
+	}
+	c.JSON(http.StatusOK, u)
+}
+
+// SetRole меняет роль пользователя.
+// @Summary      Смена роли
+// @Description  Назначает пользователю роль user или admin. Доступно только администраторам.
+// @Tags         Пользователи
+// @Accept       json
+// @Produce      json
+// @Param        id     path      int                   true  "ID пользователя"
+// @Param        input  body      services.RoleRequest  true  "Новая роль"
+// @Success      200    {object}  services.UserResponse
+// @Failure      400    {object}  handlers.ErrorResponse
+// @Failure      401    {object}  handlers.ErrorResponse
+// @Failure      403    {object}  handlers.ErrorResponse
+// @Failure      404    {object}  handlers.ErrorResponse
+// @Failure      500    {object}  handlers.ErrorResponse
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /users/{id}/role [put]
+func (h *UserHandler) SetRole(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("ID должен быть положительным целым числом"))
+		return
+	}
+	var req services.RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		RespondError(c, http.StatusBadRequest, err)
+		return
+	}
+	u, err := h.svc.SetRole(c.Request.Context(), uint(id), &req)
 	if err != nil {
 		HandleError(c, err, services.ErrNotFound, "пользователь не найден")
 		return
@@ -219,6 +526,34 @@ func (h *UserHandler) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, u)
 }
 
+// Unlock снимает блокировку входа.
+// @Summary      Разблокировка входа
+// @Description  Сбрасывает счётчик неудачных попыток входа и блокировку аккаунта. Доступно только администраторам.
+// @Tags         Пользователи
+// @Produce      json
+// @Param        id   path      int  true  "ID пользователя"
+// @Success      204  {string}  string  "No Content"
+// @Failure      400  {object}  handlers.ErrorResponse
+// @Failure      401  {object}  handlers.ErrorResponse
+// @Failure      403  {object}  handlers.ErrorResponse
+// @Failure      404  {object}  handlers.ErrorResponse
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /users/{id}/unlock [post]
+func (h *UserHandler) Unlock(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		RespondError(c, http.StatusBadRequest, fmt.Errorf("ID должен быть положительным целым числом"))
+		return
+	}
+	if err := h.svc.Unlock(c.Request.Context(), uint(id)); err != nil {
+		HandleError(c, err, services.ErrNotFound, "пользователь не найден")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
 // Delete удаляет пользователя по ID.
 // @Summary      Удаление пользователя
 // @Description  Удаляет пользователя по ID.
@@ -231,6 +566,7 @@ func (h *UserHandler) Update(c *gin.Context) {
 // @Failure      404  {object}  handlers.ErrorResponse
 // @Failure      500  {object}  handlers.ErrorResponse
 // @Security     BearerAuth
+// @Security     ApiKeyAuth
 // @Router       /users/{id} [delete]
 func (h *UserHandler) Delete(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -239,12 +575,11 @@ func (h *UserHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	userID, ok := c.Get("user_id")
-	if ok && uint(id) == userID.(uint) {
+	userID := c.GetUint("user_id")
+	self := uint(id) == userID
+	if self {
 		// Логирование для отладки
 		log.Printf("[Delete] Пользователь удаляет свою учетную запись: user_id=%d", userID)
-		// Автоматический выход из авторизации
-		c.Header("Authorization", "")
 	} else {
 		log.Printf("[Delete] Удаление учетной записи другого пользователя: user_id=%d, target_id=%d", userID, id)
 	}
@@ -259,12 +594,10 @@ func (h *UserHandler) Delete(c *gin.Context) {
 		HandleError(c, err, services.ErrNotFound, "пользователь не найден")
 		return
 	}
-	// Invalidate token logic
-	token := c.GetHeader("Authorization")
-	if token != "" {
-		parts := strings.SplitN(token, " ", 2)
-		if len(parts) == 2 && parts[0] == "Bearer" {
-			middleware.InvalidateToken(parts[1])
+	// Автоматический выход: текущий токен больше не действует
+	if self {
+		if err := h.svc.RevokeAccessToken(c.Request.Context(), c.GetString("jti"), c.GetTime("token_exp")); err != nil {
+			log.Printf("[Delete] Не удалось отозвать токен: %v", err)
 		}
 	}
 	log.Printf("[Delete] Учетная запись успешно удалена: id=%d", id)
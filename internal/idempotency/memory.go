@@ -0,0 +1,82 @@
+// memory.go
+// Этот файл содержит in-memory реализацию хранилища ключей идемпотентности.
+// Подходит для одного экземпляра приложения и тестов: данные теряются при рестарте.
+
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	Record
+	expiresAt time.Time
+}
+
+// MemoryStore — потокобезопасное хранилище ключей в памяти.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+// NewMemoryStore создаёт пустой MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+func memoryKey(scope, key string) string {
+	return scope + "\x00" + key
+}
+
+// Begin занимает ключ.
+func (s *MemoryStore) Begin(_ context.Context, scope, key, fingerprint string, now time.Time, lease time.Duration) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := memoryKey(scope, key)
+	if e, ok := s.entries[k]; ok && e.expiresAt.After(now) {
+		return e.Record, false, nil
+	}
+	rec := Record{Fingerprint: fingerprint}
+	s.entries[k] = &memoryEntry{Record: rec, expiresAt: now.Add(lease)}
+	return rec, true, nil
+}
+
+// Complete сохраняет ответ.
+func (s *MemoryStore) Complete(_ context.Context, scope, key string, rec Record, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[memoryKey(scope, key)]
+	if !ok || e.Done() || e.Fingerprint != rec.Fingerprint {
+		return nil
+	}
+	e.Record = rec
+	e.expiresAt = expires
+	return nil
+}
+
+// Release освобождает ключ.
+func (s *MemoryStore) Release(_ context.Context, scope, key, fingerprint string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := memoryKey(scope, key)
+	if e, ok := s.entries[k]; ok && !e.Done() && e.Fingerprint == fingerprint {
+		delete(s.entries, k)
+	}
+	return nil
+}
+
+// Purge удаляет устаревшие ключи.
+func (s *MemoryStore) Purge(_ context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for k, e := range s.entries {
+		if !e.expiresAt.After(now) {
+			delete(s.entries, k)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,102 @@
+// postgres.go
+// Этот файл содержит реализацию хранилища ключей идемпотентности на PostgreSQL.
+// Ключи общие для всех реплик; ключ занимается одним атомарным upsert'ом.
+
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"kvant_task/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// PostgresStore — хранилище ключей в таблице idempotency_keys.
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+// NewPostgresStore создаёт PostgresStore.
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func toRecord(k *models.IdempotencyKey) Record {
+	return Record{
+		Fingerprint: k.Fingerprint,
+		StatusCode:  k.StatusCode,
+		ContentType: k.ContentType,
+		Body:        k.Body,
+	}
+}
+
+// Begin занимает ключ. Устаревшая запись перезаписывается; если ключ освободили
+// между вставкой и чтением, попытка повторяется.
+func (s *PostgresStore) Begin(ctx context.Context, scope, key, fingerprint string, now time.Time, lease time.Duration) (Record, bool, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		var claimed []models.IdempotencyKey
+		err := s.db.WithContext(ctx).Raw(`
+			INSERT INTO idempotency_keys (scope, key, fingerprint, status_code, content_type, body, created_at, expires_at)
+			VALUES (@scope, @key, @fp, 0, '', NULL, @now, @exp)
+			ON CONFLICT (scope, key) DO UPDATE SET
+				fingerprint = EXCLUDED.fingerprint,
+				status_code = 0,
+				content_type = '',
+				body = NULL,
+				created_at = EXCLUDED.created_at,
+				expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= @now
+			RETURNING scope, key, fingerprint, status_code`,
+			map[string]interface{}{"scope": scope, "key": key, "fp": fingerprint, "now": now, "exp": now.Add(lease)},
+		).Scan(&claimed).Error
+		if err != nil {
+			return Record{}, false, err
+		}
+		if len(claimed) == 1 {
+			return toRecord(&claimed[0]), true, nil
+		}
+		var existing models.IdempotencyKey
+		err = s.db.WithContext(ctx).
+			Where("scope = ? AND key = ? AND expires_at > ?", scope, key, now).
+			First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return Record{}, false, err
+		}
+		return toRecord(&existing), false, nil
+	}
+	return Record{}, false, errors.New("не удалось занять ключ идемпотентности")
+}
+
+// Complete сохраняет ответ, если ключ всё ещё занят этим запросом.
+func (s *PostgresStore) Complete(ctx context.Context, scope, key string, rec Record, expires time.Time) error {
+	return s.db.WithContext(ctx).
+		Model(&models.IdempotencyKey{}).
+		Where("scope = ? AND key = ? AND fingerprint = ? AND status_code = 0", scope, key, rec.Fingerprint).
+		Updates(map[string]interface{}{
+			"status_code":  rec.StatusCode,
+			"content_type": rec.ContentType,
+			"body":         rec.Body,
+			"expires_at":   expires,
+		}).Error
+}
+
+// Release удаляет ключ, если он всё ещё занят этим запросом и ответа нет.
+func (s *PostgresStore) Release(ctx context.Context, scope, key, fingerprint string) error {
+	return s.db.WithContext(ctx).
+		Where("scope = ? AND key = ? AND fingerprint = ? AND status_code = 0", scope, key, fingerprint).
+		Delete(&models.IdempotencyKey{}).Error
+}
+
+// Purge удаляет устаревшие ключи.
+func (s *PostgresStore) Purge(ctx context.Context, now time.Time) (int64, error) {
+	res := s.db.WithContext(ctx).
+		Where("expires_at <= ?", now).
+		Delete(&models.IdempotencyKey{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,61 @@
+// store.go
+// Этот файл содержит интерфейс хранилища ключей идемпотентности.
+// Ключ сначала занимается на время выполнения запроса, затем в нём сохраняется ответ.
+
+package idempotency
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Record — состояние ключа: отпечаток запроса и, когда запрос выполнен, ответ.
+type Record struct {
+	// Fingerprint — отпечаток запроса, занявшего ключ
+	Fingerprint string
+	// StatusCode — код ответа; 0, пока запрос выполняется
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// Done сообщает, сохранён ли уже ответ.
+func (r *Record) Done() bool {
+	return r.StatusCode != 0
+}
+
+// Store — хранилище ключей идемпотентности. Ключи разных scope не пересекаются.
+type Store interface {
+	// Begin атомарно занимает свободный или устаревший ключ под запрос с отпечатком
+	// fingerprint до now+lease и возвращает started=true. Если ключ занят другим
+	// запросом или хранит ответ, возвращается его запись и started=false.
+	Begin(ctx context.Context, scope, key, fingerprint string, now time.Time, lease time.Duration) (rec Record, started bool, err error)
+	// Complete сохраняет ответ в ключе, занятом запросом rec.Fingerprint, до expires.
+	Complete(ctx context.Context, scope, key string, rec Record, expires time.Time) error
+	// Release освобождает ключ, занятый запросом fingerprint, не сохраняя ответ.
+	Release(ctx context.Context, scope, key, fingerprint string) error
+	// Purge удаляет ключи, устаревшие к моменту now, и возвращает их число.
+	Purge(ctx context.Context, now time.Time) (int64, error)
+}
+
+// RunGC периодически удаляет из хранилища устаревшие ключи, пока не отменён ctx.
+func RunGC(ctx context.Context, s Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			n, err := s.Purge(ctx, now)
+			if err != nil {
+				log.Printf("[idempotency] ошибка очистки: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("[idempotency] удалено устаревших ключей: %d", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// guard.go
+// Этот файл содержит политику защиты от перебора паролей.
+// Неудачи считаются по аккаунту и по IP; после порога ключ блокируется,
+// и каждая следующая неудача удваивает длительность блокировки.
+
+package lockout
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Policy — параметры блокировки.
+type Policy struct {
+	// Threshold — число неудач на аккаунт до первой блокировки
+	Threshold int
+	// IPThreshold — число неудач с одного IP до первой блокировки
+	IPThreshold int
+	// BaseDelay — длительность первой блокировки
+	BaseDelay time.Duration
+	// MaxDelay — максимальная длительность блокировки
+	MaxDelay time.Duration
+	// Window — период без неудач, после которого счётчик обнуляется
+	Window time.Duration
+}
+
+// LockedError возвращается, если вход временно заблокирован.
+type LockedError struct {
+	// Until — момент окончания блокировки
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return "слишком много неудачных попыток входа, повторите позже"
+}
+
+// RetryAfter возвращает время до окончания блокировки, округлённое вверх до секунды.
+func (e *LockedError) RetryAfter(now time.Time) time.Duration {
+	d := e.Until.Sub(now)
+	if d <= 0 {
+		return 0
+	}
+	return (d + time.Second - 1).Truncate(time.Second)
+}
+
+// Guard применяет Policy к счётчикам из Store.
+type Guard struct {
+	store  Store
+	policy Policy
+	now    func() time.Time
+}
+
+// NewGuard создаёт Guard.
+func NewGuard(store Store, policy Policy) *Guard {
+	return &Guard{store: store, policy: policy, now: time.Now}
+}
+
+// WithClock подменяет источник времени (для тестов).
+func (g *Guard) WithClock(now func() time.Time) *Guard {
+	g.now = now
+	return g
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check возвращает *LockedError, если заблокирован аккаунт или IP.
+// Пустой ip не проверяется.
+func (g *Guard) Check(ctx context.Context, email, ip string) error {
+	now := g.now()
+	var until time.Time
+	for _, key := range g.keys(email, ip) {
+		e, err := g.store.Get(ctx, key, now)
+		if err != nil {
+			return err
+		}
+		if e.LockedUntil.After(until) {
+			until = e.LockedUntil
+		}
+	}
+	if until.After(now) {
+		return &LockedError{Until: until}
+	}
+	return nil
+}
+
+// Fail регистрирует неудачную попытку и при превышении порога блокирует ключ.
+func (g *Guard) Fail(ctx context.Context, email, ip string) error {
+	now := g.now()
+	thresholds := []int{g.policy.Threshold, g.policy.IPThreshold}
+	for i, key := range g.keys(email, ip) {
+		e, err := g.store.Fail(ctx, key, now, g.policy.Window)
+		if err != nil {
+			return err
+		}
+		if over := e.Failures - thresholds[i]; over >= 0 {
+			if err := g.store.Lock(ctx, key, now.Add(g.delay(over))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Succeed сбрасывает счётчик аккаунта после успешного входа.
+// Счётчик IP не сбрасывается: иначе перебор можно маскировать входами в свой аккаунт.
+func (g *Guard) Succeed(ctx context.Context, email string) error {
+	return g.store.Reset(ctx, accountKey(email))
+}
+
+// Unlock снимает блокировку аккаунта.
+func (g *Guard) Unlock(ctx context.Context, email string) error {
+	return g.store.Reset(ctx, accountKey(email))
+}
+
+// delay — длительность блокировки после over неудач сверх порога: BaseDelay * 2^over.
+func (g *Guard) delay(over int) time.Duration {
+	d := g.policy.BaseDelay
+	for i := 0; i < over && d < g.policy.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > g.policy.MaxDelay {
+		d = g.policy.MaxDelay
+	}
+	return d
+}
+
+func (g *Guard) keys(email, ip string) []string {
+	keys := []string{accountKey(email)}
+	if ip != "" {
+		keys = append(keys, ipKey(ip))
+	}
+	return keys
+}
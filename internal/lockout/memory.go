@@ -0,0 +1,92 @@
+// memory.go
+// Этот файл содержит in-memory реализацию хранилища счётчиков неудачных входов.
+// Подходит для одного экземпляра приложения и тестов: данные теряются при рестарте.
+
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	Entry
+	expiresAt time.Time
+}
+
+// MemoryStore — потокобезопасное хранилище счётчиков в памяти.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+// NewMemoryStore создаёт пустой MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+// Get возвращает состояние счётчика.
+func (s *MemoryStore) Get(_ context.Context, key string, now time.Time) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok || !e.expiresAt.After(now) {
+		return Entry{}, nil
+	}
+	return e.Entry, nil
+}
+
+// Fail увеличивает счётчик.
+func (s *MemoryStore) Fail(_ context.Context, key string, now time.Time, window time.Duration) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok || !e.expiresAt.After(now) {
+		e = &memoryEntry{}
+		s.entries[key] = e
+	}
+	e.Failures++
+	if exp := now.Add(window); exp.After(e.expiresAt) {
+		e.expiresAt = exp
+	}
+	return e.Entry, nil
+}
+
+// Lock блокирует ключ до until.
+func (s *MemoryStore) Lock(_ context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		e = &memoryEntry{}
+		s.entries[key] = e
+	}
+	e.LockedUntil = until
+	if until.After(e.expiresAt) {
+		e.expiresAt = until
+	}
+	return nil
+}
+
+// Reset удаляет счётчик.
+func (s *MemoryStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// Purge удаляет устаревшие счётчики.
+func (s *MemoryStore) Purge(_ context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for key, e := range s.entries {
+		if !e.expiresAt.After(now) {
+			delete(s.entries, key)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,94 @@
+// postgres.go
+// Этот файл содержит реализацию хранилища счётчиков неудачных входов на PostgreSQL.
+// Счётчики общие для всех реплик, инкремент выполняется одним атомарным upsert'ом.
+
+package lockout
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"kvant_task/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// PostgresStore — хранилище счётчиков в таблице login_attempts.
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+// NewPostgresStore создаёт PostgresStore.
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func toEntry(a *models.LoginAttempt) Entry {
+	e := Entry{Failures: a.Failures}
+	if a.LockedUntil != nil {
+		e.LockedUntil = *a.LockedUntil
+	}
+	return e
+}
+
+// Get возвращает состояние счётчика.
+func (s *PostgresStore) Get(ctx context.Context, key string, now time.Time) (Entry, error) {
+	var a models.LoginAttempt
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND expires_at > ?", key, now).
+		First(&a).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Entry{}, nil
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+	return toEntry(&a), nil
+}
+
+// Fail увеличивает счётчик; устаревшая запись перезаписывается как первая неудача.
+func (s *PostgresStore) Fail(ctx context.Context, key string, now time.Time, window time.Duration) (Entry, error) {
+	var a models.LoginAttempt
+	err := s.db.WithContext(ctx).Raw(`
+		INSERT INTO login_attempts (key, failures, locked_until, expires_at)
+		VALUES (@key, 1, NULL, @exp)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.expires_at <= @now THEN 1 ELSE login_attempts.failures + 1 END,
+			locked_until = CASE WHEN login_attempts.expires_at <= @now THEN NULL ELSE login_attempts.locked_until END,
+			expires_at = GREATEST(EXCLUDED.expires_at, COALESCE(login_attempts.locked_until, EXCLUDED.expires_at))
+		RETURNING key, failures, locked_until, expires_at`,
+		map[string]interface{}{"key": key, "now": now, "exp": now.Add(window)},
+	).Scan(&a).Error
+	if err != nil {
+		return Entry{}, err
+	}
+	return toEntry(&a), nil
+}
+
+// Lock блокирует ключ до until.
+func (s *PostgresStore) Lock(ctx context.Context, key string, until time.Time) error {
+	return s.db.WithContext(ctx).Exec(`
+		INSERT INTO login_attempts (key, failures, locked_until, expires_at)
+		VALUES (@key, 0, @until, @until)
+		ON CONFLICT (key) DO UPDATE SET
+			locked_until = EXCLUDED.locked_until,
+			expires_at = GREATEST(login_attempts.expires_at, EXCLUDED.expires_at)`,
+		map[string]interface{}{"key": key, "until": until},
+	).Error
+}
+
+// Reset удаляет счётчик.
+func (s *PostgresStore) Reset(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).
+		Where("key = ?", key).
+		Delete(&models.LoginAttempt{}).Error
+}
+
+// Purge удаляет устаревшие счётчики.
+func (s *PostgresStore) Purge(ctx context.Context, now time.Time) (int64, error) {
+	res := s.db.WithContext(ctx).
+		Where("expires_at <= ?", now).
+		Delete(&models.LoginAttempt{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,55 @@
+// store.go
+// Этот файл содержит интерфейс хранилища счётчиков неудачных попыток входа.
+// Политика блокировки (пороги, задержки) реализована в Guard поверх хранилища.
+
+package lockout
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Entry — состояние счётчика по одному ключу.
+type Entry struct {
+	// Failures — число неудачных попыток подряд
+	Failures int
+	// LockedUntil — до какого момента вход заблокирован (нулевое значение — не заблокирован)
+	LockedUntil time.Time
+}
+
+// Store — хранилище счётчиков неудачных попыток.
+type Store interface {
+	// Get возвращает состояние счётчика; для неизвестного или устаревшего ключа — нулевое Entry.
+	Get(ctx context.Context, key string, now time.Time) (Entry, error)
+	// Fail атомарно увеличивает счётчик и продлевает его жизнь до now+window.
+	// Устаревший счётчик начинается заново с единицы.
+	Fail(ctx context.Context, key string, now time.Time, window time.Duration) (Entry, error)
+	// Lock блокирует ключ до момента until.
+	Lock(ctx context.Context, key string, until time.Time) error
+	// Reset удаляет счётчик и блокировку.
+	Reset(ctx context.Context, key string) error
+	// Purge удаляет счётчики, устаревшие к моменту now, и возвращает их число.
+	Purge(ctx context.Context, now time.Time) (int64, error)
+}
+
+// RunGC периодически удаляет из хранилища устаревшие счётчики, пока не отменён ctx.
+func RunGC(ctx context.Context, s Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			n, err := s.Purge(ctx, now)
+			if err != nil {
+				log.Printf("[lockout] ошибка очистки: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("[lockout] удалено устаревших счётчиков: %d", n)
+			}
+		}
+	}
+}
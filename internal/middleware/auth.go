@@ -1,64 +1,87 @@
 // auth.go
 // Этот файл содержит middleware для аутентификации.
-// Реализует проверку JWT токенов для защиты маршрутов.
+// Реализует проверку JWT токенов и API-ключей для защиты маршрутов.
 
 package middleware
 
 import (
-	"fmt"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 
-	"github.com/dgrijalva/jwt-go"
+	"kvant_task/internal/services"
+
 	"github.com/gin-gonic/gin"
 )
 
-var invalidatedTokens = make(map[string]struct{})
-
-// InvalidateToken добавляет токен в список аннулированных.
-func InvalidateToken(token string) {
-	invalidatedTokens[token] = struct{}{}
-}
+// APIKeyHeader — заголовок с API-ключом.
+const APIKeyHeader = "X-API-Key"
 
-// IsTokenInvalidated проверяет, аннулирован ли токен.
-func IsTokenInvalidated(token string) bool {
-	_, exists := invalidatedTokens[token]
-	return exists
-}
-
-// Auth middleware для проверки JWT токенов.
-func Auth(secret string) gin.HandlerFunc {
+// Auth middleware для проверки JWT токенов и API-ключей.
+// Токен проверяется TokenService: алгоритм, подпись, сроки, iss/aud и отзыв по jti.
+// Если заголовка Authorization нет, принимается API-ключ из X-API-Key (когда apiKeys не nil);
+// права такого запроса ограничены scopes ключа, см. RequireScope.
+// Токены отозванных сессий отклоняются по jti; для токенов сессии sessions (если не nil)
+// отмечает время последней активности не чаще раза в интервал трекера.
+func Auth(tokens *services.TokenService, apiKeys *services.APIKeyService, sessions *services.SessionTracker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		header := c.GetHeader("Authorization")
-		fmt.Printf("[DEBUG] Authorization header: %s\n", header)
+		if header == "" && apiKeys != nil && c.GetHeader(APIKeyHeader) != "" {
+			authAPIKey(c, tokens, apiKeys)
+			return
+		}
 		parts := strings.SplitN(header, " ", 2)
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			fmt.Println("[DEBUG] Invalid Authorization header format")
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "требуется авторизация"})
 			return
 		}
-		token, err := jwt.Parse(parts[1], func(t *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		})
-		if err != nil || !token.Valid {
-			fmt.Printf("[DEBUG] Token parsing error: %v\n", err)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "некорректный токен"})
+		claims, err := tokens.Validate(c.Request.Context(), parts[1])
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidToken) || errors.Is(err, services.ErrTokenRevoked) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("Error validating token: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "внутренняя ошибка сервера"})
 			return
 		}
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			fmt.Println("[DEBUG] Invalid token claims")
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "некорректные данные"})
-			return
+		userID, _ := claims.UserID()
+		role := tokens.EffectiveRole(claims)
+		c.Set("user_id", userID)
+		c.Set("role", role)
+		c.Set("jti", claims.Id)
+		c.Set("token_exp", claims.ExpiresAtTime())
+		if claims.SessionID != 0 {
+			c.Set("session_id", claims.SessionID)
+			if sessions != nil {
+				if err := sessions.Touch(c.Request.Context(), claims.SessionID); err != nil {
+					// отметка активности не должна ломать запрос
+					log.Printf("Error touching session %d: %v", claims.SessionID, err)
+				}
+			}
 		}
-		userID, ok := claims["user_id"].(float64)
-		if !ok {
-			fmt.Println("[DEBUG] Missing or invalid user_id in token claims")
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "некорректные данные"})
+		c.Next()
+	}
+}
+
+// authAPIKey аутентифицирует запрос по API-ключу.
+func authAPIKey(c *gin.Context, tokens *services.TokenService, apiKeys *services.APIKeyService) {
+	p, err := apiKeys.Authenticate(c.Request.Context(), c.GetHeader(APIKeyHeader))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAPIKey) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
 		}
-		fmt.Printf("[DEBUG] Token valid, user_id: %v\n", userID)
-		c.Set("user_id", uint(userID))
-		c.Next()
+		log.Printf("Error authenticating API key: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "внутренняя ошибка сервера"})
+		return
 	}
+	// ключ не подтверждает второй фактор: роли, которым он нужен, по ключу не действуют
+	role := tokens.EffectiveRole(&services.Claims{Role: p.Role})
+	c.Set("user_id", p.UserID)
+	c.Set("role", role)
+	c.Set("api_key_id", p.KeyID)
+	c.Set("scopes", p.Scopes)
+	c.Next()
 }
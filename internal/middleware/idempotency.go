@@ -0,0 +1,143 @@
+// idempotency.go
+// Этот файл содержит middleware для заголовка Idempotency-Key.
+// Повтор запроса с тем же ключом получает сохранённый ответ, а не выполняется заново.
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"kvant_task/internal/idempotency"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader — заголовок с ключом идемпотентности.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader — заголовок ответа, отданного из сохранённого.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// idempotencyLease — на сколько ключ занимается выполняющимся запросом; если процесс
+	// упадёт, не сохранив ответ, ключ освободится через это время.
+	idempotencyLease     = time.Minute
+	maxIdempotencyKeyLen = 255
+)
+
+// Idempotency выполняет запрос с заголовком Idempotency-Key не более одного раза за ttl.
+// Ключ принадлежит пользователю из контекста (после Auth), а для публичных маршрутов —
+// IP-адресу клиента. Отпечаток запроса — метод, путь и тело. Повтор с тем же отпечатком
+// получает сохранённый ответ с заголовком Idempotent-Replayed; тот же ключ с другим
+// запросом или пока первый запрос выполняется — 409. Ответы 5xx не сохраняются:
+// такой запрос можно повторить с тем же ключом. Запросы без заголовка проходят как есть.
+func Idempotency(store idempotency.Store, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s длиннее %d символов", IdempotencyKeyHeader, maxIdempotencyKeyLen)})
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "не удалось прочитать тело запроса"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := idempotencyScope(c)
+		fingerprint := requestFingerprint(c.Request.Method, c.Request.URL.Path, body)
+		// ответ сохраняется, даже если клиент не дождался его и отключился
+		ctx := context.WithoutCancel(c.Request.Context())
+
+		rec, started, err := store.Begin(ctx, scope, key, fingerprint, time.Now(), idempotencyLease)
+		if err != nil {
+			log.Printf("Error claiming idempotency key: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "внутренняя ошибка сервера"})
+			return
+		}
+		if !started {
+			switch {
+			case rec.Fingerprint != fingerprint:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "ключ идемпотентности уже использован с другим запросом"})
+			case !rec.Done():
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "запрос с этим ключом идемпотентности ещё выполняется"})
+			default:
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(rec.StatusCode, rec.ContentType, rec.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		completed := false
+		defer func() {
+			// паника или 5xx: ключ освобождается, запрос можно повторить
+			if !completed {
+				if err := store.Release(ctx, scope, key, fingerprint); err != nil {
+					log.Printf("Error releasing idempotency key: %v", err)
+				}
+			}
+		}()
+		c.Next()
+
+		if status := w.Status(); status < http.StatusInternalServerError {
+			err := store.Complete(ctx, scope, key, idempotency.Record{
+				Fingerprint: fingerprint,
+				StatusCode:  status,
+				ContentType: w.Header().Get("Content-Type"),
+				Body:        w.body.Bytes(),
+			}, time.Now().Add(ttl))
+			if err != nil {
+				log.Printf("Error saving idempotent response: %v", err)
+				return
+			}
+			completed = true
+		}
+	}
+}
+
+// idempotencyScope возвращает владельца ключа: пользователя или, без аутентификации, IP.
+func idempotencyScope(c *gin.Context) string {
+	if id := c.GetUint("user_id"); id != 0 {
+		return fmt.Sprintf("user:%d", id)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// requestFingerprint — SHA-256 метода, пути и тела запроса.
+func requestFingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, path)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter копирует тело ответа для сохранения.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,56 @@
+// role.go
+// Этот файл содержит middleware для авторизации по ролям.
+// Роль и ID пользователя берутся из контекста, заполненного middleware Auth.
+
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole пропускает запрос, только если роль пользователя входит в roles.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasRole(c, roles) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "недостаточно прав"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSelfOrRole пропускает запрос, если ID из параметра пути param совпадает
+// с ID текущего пользователя или его роль входит в roles.
+// Некорректный ID пропускается дальше: его отвергнет хендлер с 400.
+func RequireSelfOrRole(param string, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if hasRole(c, roles) {
+			c.Next()
+			return
+		}
+		id, err := strconv.Atoi(c.Param(param))
+		if err != nil || id <= 0 {
+			c.Next()
+			return
+		}
+		if uint(id) != c.GetUint("user_id") {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "недостаточно прав"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// hasRole проверяет роль текущего пользователя.
+func hasRole(c *gin.Context, roles []string) bool {
+	role := c.GetString("role")
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
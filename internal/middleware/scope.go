@@ -0,0 +1,51 @@
+// scope.go
+// Этот файл содержит middleware для проверки прав API-ключей.
+// Запросы с JWT выполняются с полными правами пользователя и проверку scopes проходят всегда.
+
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScope пропускает запрос по API-ключу, только если у ключа есть все scopes.
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, isAPIKey := c.Get("scopes")
+		if !isAPIKey {
+			c.Next()
+			return
+		}
+		granted, _ := v.([]string)
+		for _, want := range scopes {
+			if !contains(granted, want) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "у API-ключа нет права " + want})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireToken отклоняет запросы по API-ключу. Применяется к управлению учётной записью:
+// ключ не должен позволять сменить пароль, 2FA или выпустить себе новые ключи.
+func RequireToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isAPIKey := c.Get("scopes"); isAPIKey {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "операция недоступна по API-ключу"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+// api_key.go
+// Этот файл содержит модель API-ключа для межсервисного доступа.
+// В базе хранится только SHA-256 хэш ключа, сам ключ отдаётся клиенту один раз.
+
+package models
+
+import "time"
+
+// Права (scopes) API-ключей.
+const (
+	// ScopeOrdersRead — чтение заказов
+	ScopeOrdersRead = "orders:read"
+	// ScopeOrdersWrite — создание и изменение заказов
+	ScopeOrdersWrite = "orders:write"
+	// ScopeUsersRead — чтение профилей пользователей
+	ScopeUsersRead = "users:read"
+	// ScopeUsersWrite — изменение профилей пользователей
+	ScopeUsersWrite = "users:write"
+	// ScopeProductsWrite — изменение каталога товаров (ключом администратора)
+	ScopeProductsWrite = "products:write"
+	// ScopeCouponsRead — просмотр промокодов (ключом администратора)
+	ScopeCouponsRead = "coupons:read"
+	// ScopeCouponsWrite — управление промокодами (ключом администратора)
+	ScopeCouponsWrite = "coupons:write"
+)
+
+// APIKey — именованный ключ пользователя с ограниченным набором прав.
+type APIKey struct {
+	ID uint `gorm:"primaryKey"`
+
+	// Владелец ключа: запросы по ключу выполняются от его имени
+	UserID uint `gorm:"not null;index"`
+
+	// Название ключа, например "batch-export"
+	Name string `gorm:"size:100;not null"`
+
+	// Открытая часть ключа: показывается в списках и служит для поиска
+	Prefix string `gorm:"size:16;not null;uniqueIndex"`
+
+	// SHA-256 хэш ключа в hex
+	KeyHash string `gorm:"size:64;not null"`
+
+	// Права через пробел, например "orders:read orders:write"
+	Scopes string `gorm:"size:255;not null"`
+
+	// Время последнего использования (обновляется не чаще раза в минуту)
+	LastUsedAt *time.Time
+
+	// Время отзыва
+	RevokedAt *time.Time
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,72 @@
+// coupon.go
+// Этот файл содержит модели промокодов и их применений к заказам.
+
+package models
+
+import "time"
+
+// Типы скидки промокода.
+const (
+	CouponTypePercent = "percent"
+	CouponTypeFixed   = "fixed"
+)
+
+// Coupon — промокод. Условия промокода после создания не меняются,
+// его можно только отключить.
+type Coupon struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// Код промокода, в верхнем регистре
+	Code string `gorm:"size:64;not null;uniqueIndex" json:"code"`
+
+	// Тип скидки: percent или fixed
+	Type string `gorm:"size:10;not null" json:"type"`
+
+	// Скидка в процентах от суммы позиций (для percent)
+	Percent int `gorm:"not null;default:0" json:"percent"`
+
+	// Скидка в минимальных единицах валюты Currency (для fixed)
+	AmountMinor int64 `gorm:"not null;default:0" json:"amount_minor"`
+
+	// Валюта суммы скидки и минимальной суммы заказа; пусто — процентная скидка в любой валюте
+	Currency string `gorm:"size:3" json:"currency"`
+
+	// Минимальная сумма позиций заказа в минимальных единицах валюты Currency
+	MinOrderMinor int64 `gorm:"not null;default:0" json:"min_order_minor"`
+
+	// Период действия; пусто — без ограничения
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
+
+	// Сколько раз промокод можно применить всего и одному пользователю; пусто — без ограничения
+	MaxRedemptions *int `json:"max_redemptions"`
+	MaxPerUser     *int `json:"max_per_user"`
+
+	// Сколько раз промокод применён (без отменённых и удалённых заказов)
+	Redemptions int `gorm:"not null;default:0;check:chk_coupons_redemptions,redemptions >= 0" json:"redemptions"`
+
+	// Отключённый промокод не применяется к новым заказам
+	Active bool `gorm:"not null;default:true" json:"active"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// CouponRedemption — применение промокода к заказу.
+type CouponRedemption struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	CouponID uint `gorm:"not null;index" json:"coupon_id"`
+	UserID   uint `gorm:"not null;index" json:"user_id"`
+
+	// Заказ, к которому применён промокод; у заказа не больше одного промокода
+	OrderID uint `gorm:"not null;uniqueIndex" json:"order_id"`
+
+	// Скидка в минимальных единицах валюты заказа
+	DiscountMinor int64 `gorm:"not null" json:"discount_minor"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+
+	Coupon *Coupon `gorm:"foreignKey:CouponID;constraint:OnDelete:CASCADE" json:"-"`
+	Order  *Order  `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE" json:"-"`
+}
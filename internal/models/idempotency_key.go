@@ -0,0 +1,31 @@
+// idempotency_key.go
+// Этот файл содержит модель ключа идемпотентности.
+// По ключу хранится отпечаток запроса и ответ, который отдаётся на повторы.
+
+package models
+
+import "time"
+
+// IdempotencyKey — ключ идемпотентности из заголовка Idempotency-Key.
+type IdempotencyKey struct {
+	// Владелец ключа, например "user:42" или "ip:10.0.0.1"
+	Scope string `gorm:"primaryKey;size:64"`
+
+	// Значение заголовка Idempotency-Key
+	Key string `gorm:"primaryKey;size:255"`
+
+	// SHA-256 метода, пути и тела запроса
+	Fingerprint string `gorm:"size:64;not null"`
+
+	// Код ответа; 0, пока запрос выполняется
+	StatusCode int `gorm:"not null;default:0"`
+
+	// Тип и тело ответа
+	ContentType string `gorm:"size:255"`
+	Body        []byte
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	// После этого момента ключ свободен, запись можно удалить
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
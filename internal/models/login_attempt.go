@@ -0,0 +1,22 @@
+// login_attempt.go
+// Этот файл содержит модель счётчика неудачных попыток входа.
+// Счётчики ведутся отдельно по аккаунту и по IP-адресу.
+
+package models
+
+import "time"
+
+// LoginAttempt — неудачные попытки входа по одному ключу (аккаунт или IP).
+type LoginAttempt struct {
+	// Ключ счётчика, например "account:user@example.com" или "ip:10.0.0.1"
+	Key string `gorm:"primaryKey;size:255"`
+
+	// Число неудачных попыток подряд
+	Failures int `gorm:"not null;default:0"`
+
+	// До какого момента вход заблокирован
+	LockedUntil *time.Time
+
+	// После этого момента запись устарела: счётчик начинается заново, запись можно удалить
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
@@ -0,0 +1,40 @@
+// one_time_token.go
+// Этот файл содержит модель одноразового токена для действий пользователя
+// (сброс пароля, подтверждение email). В базе хранится только SHA-256 хэш токена.
+
+package models
+
+import "time"
+
+// Назначения одноразовых токенов.
+const (
+	// TokenPurposePasswordReset — сброс забытого пароля.
+	TokenPurposePasswordReset = "password_reset"
+	// TokenPurposeEmailVerification — подтверждение email.
+	TokenPurposeEmailVerification = "email_verification"
+)
+
+// OneTimeToken — одноразовый токен с ограниченным сроком действия.
+type OneTimeToken struct {
+	ID uint `gorm:"primaryKey"`
+
+	// Владелец токена
+	UserID uint `gorm:"not null;index"`
+
+	// Назначение токена
+	Purpose string `gorm:"size:32;not null"`
+
+	// Данные, к которым привязан токен (например, подтверждаемый email)
+	Payload string `gorm:"size:255"`
+
+	// SHA-256 хэш токена в hex
+	TokenHash string `gorm:"size:64;not null;uniqueIndex"`
+
+	// Срок действия
+	ExpiresAt time.Time `gorm:"not null"`
+
+	// Время использования: повторно токен не принимается
+	UsedAt *time.Time
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -6,29 +6,139 @@ package models
 
 import "time"
 
-// Order — модель заказа.
+// Статусы заказа. Допустимые переходы между ними проверяет OrderService.
+const (
+	OrderStatusPending   = "pending"
+	OrderStatusPaid      = "paid"
+	OrderStatusShipped   = "shipped"
+	OrderStatusDelivered = "delivered"
+	OrderStatusCancelled = "cancelled"
+)
+
+// Order — модель заказа (заголовок). Позиции заказа хранятся в order_items.
+// Составные индексы с (created_at, id) в конце обслуживают списки заказов,
+// отсортированные по дате, в том числе постраничные по курсору.
 // @Description Заказ, привязанный к пользователю.
 type Order struct {
 	// ID заказа
 	// required: true
-	ID uint `gorm:"primaryKey" json:"id"`
+	ID uint `gorm:"primaryKey;index:idx_orders_user_created,priority:3;index:idx_orders_created,priority:2;index:idx_orders_status_created,priority:3;index:idx_orders_currency_total,priority:3" json:"id"`
 
 	// ID пользователя, сделавшего заказ
 	// required: true
-	UserID uint `gorm:"not null" json:"user_id"`
+	UserID uint `gorm:"not null;index:idx_orders_user_created,priority:1" json:"user_id"`
+
+	// Позиции заказа
+	Items []OrderItem `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE" json:"items"`
+
+	// Валюта заказа, код ISO 4217; у всех позиций она одна
+	Currency string `gorm:"size:3;not null;default:RUB;index:idx_orders_currency_total,priority:1" json:"currency"`
+
+	// Сумма позиций в минимальных единицах валюты
+	SubtotalMinor int64 `gorm:"not null;default:0" json:"subtotal_minor"`
+
+	// Промокод заказа
+	CouponID   *uint  `gorm:"index" json:"coupon_id"`
+	CouponCode string `gorm:"size:64" json:"coupon_code"`
+
+	// Скидка по промокоду в минимальных единицах валюты
+	DiscountMinor int64 `gorm:"not null;default:0" json:"discount_minor"`
+
+	// Налог в минимальных единицах валюты; по ставкам — в TaxLines
+	TaxMinor int64 `gorm:"not null;default:0" json:"tax_minor"`
+
+	// Стоимость доставки в минимальных единицах валюты
+	ShippingMinor int64 `gorm:"not null;default:0" json:"shipping_minor"`
+
+	// Строки налога заказа
+	TaxLines []OrderTaxLine `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE" json:"tax_lines"`
+
+	// Итого к оплате (сумма позиций минус скидка, плюс налог и доставка) в минимальных единицах валюты
+	TotalMinor int64 `gorm:"not null;default:0;index:idx_orders_currency_total,priority:2" json:"total_minor"`
+
+	// Статус заказа
+	Status string `gorm:"size:20;not null;default:pending;index;index:idx_orders_status_created,priority:1" json:"status"`
+
+	// Время создания заказа
+	CreatedAt time.Time `gorm:"autoCreateTime;index:idx_orders_user_created,priority:2;index:idx_orders_created,priority:1;index:idx_orders_status_created,priority:2" json:"created_at"`
+
+	// Промокод; нужен для внешнего ключа coupon_id
+	Coupon *Coupon `gorm:"foreignKey:CouponID;constraint:OnDelete:SET NULL" json:"-"`
+}
+
+// OrderItem — позиция заказа.
+type OrderItem struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// ID заказа
+	OrderID uint `gorm:"not null;index" json:"order_id"`
+
+	// Товар каталога; пусто для позиций, созданных до появления каталога
+	// и для удалённых из каталога товаров
+	ProductID *uint `gorm:"index" json:"product_id"`
+
+	// Артикул товара на момент заказа
+	SKU string `gorm:"size:64" json:"sku"`
 
-	// Наименование продукта
+	// Наименование товара на момент заказа
 	// required: true
-	Product string `gorm:"not null" json:"product"`
+	Product string `gorm:"size:255;not null" json:"product"`
 
 	// Количество единиц
 	// required: true
 	Quantity int `gorm:"not null" json:"quantity"`
 
-	// Цена за единицу
+	// Цена за единицу по каталогу на момент заказа, в минимальных единицах валюты заказа
 	// required: true
-	Price float64 `gorm:"not null" json:"price"`
+	PriceMinor int64 `gorm:"not null;default:0" json:"price_minor"`
 
-	// Время создания заказа
+	// Товар каталога; нужен для внешнего ключа product_id
+	CatalogProduct *Product `gorm:"foreignKey:ProductID;constraint:OnDelete:SET NULL" json:"-"`
+}
+
+// OrderTaxLine — строка налога заказа: налог по одной ставке.
+type OrderTaxLine struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// ID заказа
+	OrderID uint `gorm:"not null;index" json:"order_id"`
+
+	// Название налога или налоговой категории
+	Name string `gorm:"size:64;not null" json:"name"`
+
+	// Ставка в сотых долях процента: 2000 — 20%
+	Rate int `gorm:"not null" json:"rate"`
+
+	// Облагаемая сумма в минимальных единицах валюты заказа
+	BaseMinor int64 `gorm:"not null" json:"base_minor"`
+
+	// Сумма налога в минимальных единицах валюты заказа
+	AmountMinor int64 `gorm:"not null" json:"amount_minor"`
+}
+
+// OrderStatusChange — запись истории статусов заказа: кто, когда и из какого статуса в какой перевёл заказ.
+type OrderStatusChange struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// ID заказа
+	OrderID uint `gorm:"not null;index" json:"order_id"`
+
+	// Статус до и после перехода
+	FromStatus string `gorm:"size:20;not null" json:"from_status"`
+	ToStatus   string `gorm:"size:20;not null" json:"to_status"`
+
+	// Кто выполнил переход: ID пользователя и его роль в момент перехода
+	ActorID   uint   `gorm:"not null" json:"actor_id"`
+	ActorRole string `gorm:"size:16;not null" json:"actor_role"`
+
+	// Комментарий к переходу
+	Comment string `gorm:"size:255" json:"comment,omitempty"`
+
+	// Время перехода
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
 }
+
+// TableName задаёт имя таблицы истории статусов.
+func (OrderStatusChange) TableName() string {
+	return "order_status_history"
+}
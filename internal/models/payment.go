@@ -0,0 +1,62 @@
+// payment.go
+// Этот файл содержит модели платежей по заказам и обработанных уведомлений
+// платёжного провайдера.
+
+package models
+
+import "time"
+
+// Статусы платежа.
+const (
+	// PaymentStatusPending — платёж создан или обрабатывается провайдером
+	PaymentStatusPending = "pending"
+	// PaymentStatusAuthorized — сумма заблокирована и ждёт списания
+	PaymentStatusAuthorized = "authorized"
+	// PaymentStatusCaptured — сумма списана, заказ оплачен
+	PaymentStatusCaptured = "captured"
+	// PaymentStatusFailed — платёж не прошёл
+	PaymentStatusFailed = "failed"
+	// PaymentStatusRefunded — сумма возвращена
+	PaymentStatusRefunded = "refunded"
+)
+
+// Payment — попытка оплаты заказа. У заказа может быть много неудачных попыток,
+// но не больше одной действующей (не failed и не refunded).
+type Payment struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	OrderID uint `gorm:"not null;index;uniqueIndex:idx_payments_order_active,where:status <> 'failed' AND status <> 'refunded'" json:"order_id"`
+	UserID  uint `gorm:"not null;index" json:"user_id"`
+
+	// Платёжный провайдер и идентификатор платежа у него
+	Provider    string `gorm:"size:32;not null;index:idx_payments_provider_ref,priority:1" json:"provider"`
+	ProviderRef string `gorm:"size:128;index:idx_payments_provider_ref,priority:2" json:"provider_ref"`
+
+	// Сумма в минимальных единицах валюты Currency
+	AmountMinor int64  `gorm:"not null" json:"amount_minor"`
+	Currency    string `gorm:"size:3;not null" json:"currency"`
+
+	// Статус платежа
+	Status string `gorm:"size:20;not null;default:pending" json:"status"`
+
+	// Причина отказа провайдера
+	FailureReason string `gorm:"size:255" json:"failure_reason,omitempty"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+
+	// Заказ; нужен для внешнего ключа order_id
+	Order *Order `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// PaymentEvent — обработанное уведомление платёжного провайдера.
+// Повторно присланное уведомление с тем же ID не обрабатывается.
+type PaymentEvent struct {
+	Provider string `gorm:"primaryKey;size:32" json:"provider"`
+	EventID  string `gorm:"primaryKey;size:128" json:"event_id"`
+
+	Type      string `gorm:"size:64;not null" json:"type"`
+	PaymentID *uint  `gorm:"index" json:"payment_id"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
@@ -0,0 +1,37 @@
+// product.go
+// Этот файл содержит модель товара каталога.
+// Цена заказа берётся из каталога, а остаток уменьшается при оформлении заказа.
+// Цена хранится целым числом минимальных единиц валюты.
+
+package models
+
+import "time"
+
+// Product — товар каталога.
+type Product struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// Артикул, уникальный в каталоге
+	SKU string `gorm:"size:64;not null;uniqueIndex" json:"sku"`
+
+	// Наименование товара
+	Name string `gorm:"size:255;not null" json:"name"`
+
+	// Текущая цена за единицу в минимальных единицах валюты (копейках, центах)
+	PriceMinor int64 `gorm:"not null;default:0" json:"price_minor"`
+
+	// Валюта цены, код ISO 4217
+	Currency string `gorm:"size:3;not null;default:RUB" json:"currency"`
+
+	// Остаток на складе; не бывает отрицательным
+	Stock int `gorm:"not null;default:0;check:chk_products_stock,stock >= 0" json:"stock"`
+
+	// Налоговая категория; пусто — ставка налога по умолчанию
+	TaxCategory string `gorm:"size:32;not null;default:''" json:"tax_category"`
+
+	// Вес единицы товара в граммах, для расчёта доставки
+	WeightGrams int `gorm:"not null;default:0;check:chk_products_weight,weight_grams >= 0" json:"weight_grams"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
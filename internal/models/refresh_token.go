@@ -0,0 +1,38 @@
+// refresh_token.go
+// Этот файл содержит модель refresh-токена.
+// В базе хранится только SHA-256 хэш токена, сам токен отдаётся клиенту один раз.
+
+package models
+
+import "time"
+
+// RefreshToken — серверная запись refresh-токена.
+// Все токены, полученные ротацией из одного логина, имеют общий FamilyID.
+type RefreshToken struct {
+	ID uint `gorm:"primaryKey"`
+
+	// Владелец токена
+	UserID uint `gorm:"not null;index"`
+
+	// Идентификатор семейства токенов (одна цепочка ротаций)
+	FamilyID string `gorm:"size:64;not null;index"`
+
+	// SHA-256 хэш токена в hex
+	TokenHash string `gorm:"size:64;not null;uniqueIndex"`
+
+	// Срок действия
+	ExpiresAt time.Time `gorm:"not null"`
+
+	// jti и срок действия access-токена, выпущенного вместе с этим refresh-токеном:
+	// нужны, чтобы отозвать все действующие access-токены пользователя
+	AccessJTI       string    `gorm:"size:64"`
+	AccessExpiresAt time.Time `gorm:"index"`
+
+	// Время ротации: токен уже обменян на новый
+	RotatedAt *time.Time
+
+	// Время отзыва: токен или всё семейство отозваны
+	RevokedAt *time.Time
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
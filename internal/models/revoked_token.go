@@ -0,0 +1,16 @@
+// revoked_token.go
+// Этот файл содержит модель отозванного токена.
+// Запись хранится до истечения срока токена, после чего удаляется сборщиком мусора.
+
+package models
+
+import "time"
+
+// RevokedToken — отозванный access-токен.
+type RevokedToken struct {
+	// Идентификатор токена (claim jti)
+	JTI string `gorm:"primaryKey;size:64"`
+
+	// Срок действия токена: после него запись можно удалить
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
@@ -0,0 +1,36 @@
+// session.go
+// Этот файл содержит модель сессии — одного входа пользователя.
+// Сессии соответствует семейство refresh-токенов, полученных ротацией из этого входа.
+
+package models
+
+import "time"
+
+// Session — вход пользователя с конкретного устройства.
+type Session struct {
+	ID uint `gorm:"primaryKey"`
+
+	// Владелец сессии
+	UserID uint `gorm:"not null;index"`
+
+	// Семейство refresh-токенов этой сессии
+	FamilyID string `gorm:"size:64;not null;uniqueIndex"`
+
+	// jti последнего выпущенного access-токена
+	AccessJTI string `gorm:"size:64"`
+
+	// IP-адрес и User-Agent клиента при входе
+	IP        string `gorm:"size:64"`
+	UserAgent string `gorm:"size:255"`
+
+	// Последняя активность: обновляется при запросах не чаще раза в интервал
+	LastSeenAt time.Time `gorm:"not null"`
+
+	// Срок действия последнего refresh-токена: после него сессия неактивна
+	ExpiresAt time.Time `gorm:"not null"`
+
+	// Время отзыва
+	RevokedAt *time.Time
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
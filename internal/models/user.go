@@ -1,9 +1,19 @@
 package models
 
+import "time"
+
 // user.go
 // Этот файл содержит модель пользователя.
 // Модель используется для работы с таблицей пользователей в базе данных.
 
+// Роли пользователей.
+const (
+	// RoleUser — обычный пользователь: доступ только к своему профилю и заказам.
+	RoleUser = "user"
+	// RoleAdmin — администратор: управляет всеми пользователями и заказами.
+	RoleAdmin = "admin"
+)
+
 // User — модель пользователя.
 // @Description Пользователь системы.
 type User struct {
@@ -26,4 +36,25 @@ type User struct {
 	// Хэш пароля
 	// required: true
 	PasswordHash string `gorm:"not null" json:"-"`
+
+	// Роль пользователя (user или admin)
+	Role string `gorm:"size:16;not null;default:user" json:"role"`
+
+	// Время подтверждения текущего email; nil — адрес не подтверждён
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+
+	// Новый email, ожидающий подтверждения; до подтверждения действует прежний
+	PendingEmail *string `gorm:"size:255" json:"pending_email,omitempty"`
+
+	// Секрет TOTP в base32; задаётся при подключении двухфакторной аутентификации
+	TOTPSecret *string `gorm:"column:totp_secret;size:64" json:"-"`
+
+	// Время включения 2FA; nil — второй фактор не включён (или подключение не подтверждено)
+	TOTPEnabledAt *time.Time `gorm:"column:totp_enabled_at" json:"-"`
+
+	// Последний принятый шаг TOTP: код одного шага нельзя использовать дважды
+	TOTPLastStep int64 `gorm:"column:totp_last_step;not null;default:0" json:"-"`
+
+	// Хэши неиспользованных кодов восстановления (JSON-массив)
+	TOTPRecoveryCodes string `gorm:"column:totp_recovery_codes;type:text;not null;default:''" json:"-"`
 }
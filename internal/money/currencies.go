@@ -0,0 +1,34 @@
+// currencies.go
+// Этот файл содержит таблицу валют ISO 4217 и число знаков после точки у каждой.
+
+package money
+
+import "strings"
+
+// Действующие валюты ISO 4217 с минимальными единицами, сгруппированные по числу
+// знаков после точки. Драгоценные металлы и расчётные единицы (XAU, XDR и т. п.)
+// не поддерживаются: у них нет минимальной единицы.
+var currencyCodes = map[int]string{
+	0: "BIF CLP DJF GNF ISK JPY KMF KRW PYG RWF UGX UYI VND VUV XAF XOF XPF",
+	2: "AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BMD BND BOB BOV BRL BSD " +
+		"BTN BWP BYN BZD CAD CDF CHE CHF CHW CNY COP COU CRC CUC CUP CVE CZK DKK DOP DZD " +
+		"EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GTQ GYD HKD HNL HTG HUF IDR ILS INR " +
+		"IRR JMD KES KGS KHR KPW KYD KZT LAK LBP LKR LRD LSL MAD MDL MGA MKD MMK MNT MOP " +
+		"MRU MUR MVR MWK MXN MXV MYR MZN NAD NGN NIO NOK NPR NZD PAB PEN PGK PHP PKR PLN " +
+		"QAR RON RSD RUB SAR SBD SCR SDG SEK SGD SHP SLE SLL SOS SRD SSP STN SVC SYP SZL " +
+		"THB TJS TMT TOP TRY TTD TWD TZS UAH USD USN UYU UZS VED VES WST XCD YER ZAR ZMW " +
+		"ZWG ZWL",
+	3: "BHD IQD JOD KWD LYD OMR TND",
+	4: "CLF UYW",
+}
+
+// exponents — число знаков после точки по коду валюты.
+var exponents = func() map[string]int {
+	m := make(map[string]int)
+	for exp, codes := range currencyCodes {
+		for _, code := range strings.Fields(codes) {
+			m[code] = exp
+		}
+	}
+	return m
+}()
@@ -0,0 +1,121 @@
+// money.go
+// Этот файл содержит работу с денежными суммами без плавающей точки.
+// Сумма хранится целым числом минимальных единиц валюты (копеек, центов),
+// число знаков после точки определяется валютой по ISO 4217.
+
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnknownCurrency ошибка, если код валюты не из ISO 4217.
+	ErrUnknownCurrency = errors.New("неизвестная валюта")
+	// ErrInvalidAmount ошибка, если строка не является суммой в этой валюте.
+	ErrInvalidAmount = errors.New("некорректная сумма")
+	// ErrOverflow ошибка, если сумма не помещается в int64 минимальных единиц.
+	ErrOverflow = errors.New("сумма слишком велика")
+)
+
+// Currency — валюта ISO 4217.
+type Currency struct {
+	// Code — буквенный код, например RUB
+	Code string
+	// Exponent — число знаков после точки (2 для RUB: 1 рубль = 100 копеек)
+	Exponent int
+}
+
+// Lookup возвращает валюту по коду ISO 4217 (регистр не важен).
+func Lookup(code string) (Currency, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	exp, ok := exponents[code]
+	if !ok {
+		return Currency{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	return Currency{Code: code, Exponent: exp}, nil
+}
+
+// Parse разбирает десятичную строку ("19.95") в минимальные единицы валюты.
+// Знаков после точки не может быть больше, чем у валюты; экспонента и знак "+" не принимаются.
+func (c Currency) Parse(s string) (int64, error) {
+	neg := strings.HasPrefix(s, "-")
+	digits := strings.TrimPrefix(s, "-")
+	whole, frac, hasDot := strings.Cut(digits, ".")
+	if whole == "" || (hasDot && frac == "") || !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if len(frac) > c.Exponent {
+		return 0, fmt.Errorf("%w: %q: у %s не больше %d знаков после точки", ErrInvalidAmount, s, c.Code, c.Exponent)
+	}
+	frac += strings.Repeat("0", c.Exponent-len(frac))
+	v, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrOverflow, s)
+	}
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
+
+// Format возвращает сумму в минимальных единицах десятичной строкой с числом знаков валюты.
+func (c Currency) Format(minor int64) string {
+	sign := ""
+	u := uint64(minor)
+	if minor < 0 {
+		sign = "-"
+		u = uint64(-(minor + 1)) + 1
+	}
+	s := strconv.FormatUint(u, 10)
+	if c.Exponent == 0 {
+		return sign + s
+	}
+	if len(s) <= c.Exponent {
+		s = strings.Repeat("0", c.Exponent-len(s)+1) + s
+	}
+	return sign + s[:len(s)-c.Exponent] + "." + s[len(s)-c.Exponent:]
+}
+
+// Scale возвращает число минимальных единиц в одной основной (10^Exponent).
+func (c Currency) Scale() int64 {
+	scale := int64(1)
+	for i := 0; i < c.Exponent; i++ {
+		scale *= 10
+	}
+	return scale
+}
+
+// Mul умножает сумму на количество с проверкой переполнения.
+func Mul(minor int64, n int64) (int64, error) {
+	if minor == 0 || n == 0 {
+		return 0, nil
+	}
+	r := minor * n
+	if r/n != minor || (minor == -1 && n == math.MinInt64) || (n == -1 && minor == math.MinInt64) {
+		return 0, ErrOverflow
+	}
+	return r, nil
+}
+
+// Add складывает суммы с проверкой переполнения.
+func Add(a, b int64) (int64, error) {
+	r := a + b
+	if (b > 0 && r < a) || (b < 0 && r > a) {
+		return 0, ErrOverflow
+	}
+	return r, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
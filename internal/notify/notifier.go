@@ -0,0 +1,69 @@
+// notifier.go
+// Этот файл содержит интерфейс доставки уведомлений пользователям.
+// Реализации: запись в лог и в файл — для локальной разработки; боевой транспорт
+// (SMTP, внешний сервис рассылок) подключается реализацией того же интерфейса.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Message — уведомление для пользователя.
+type Message struct {
+	// Адрес получателя
+	To string
+	// Тема
+	Subject string
+	// Текст
+	Body string
+}
+
+// Notifier доставляет уведомления пользователям.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogNotifier пишет уведомления в лог приложения.
+type LogNotifier struct{}
+
+// NewLogNotifier создаёт LogNotifier.
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+// Send пишет уведомление в лог.
+func (n *LogNotifier) Send(_ context.Context, msg Message) error {
+	log.Printf("[notify] to=%s subject=%q body=%q", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileNotifier дописывает уведомления в файл — удобно смотреть письма при локальной разработке.
+type FileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileNotifier создаёт FileNotifier, пишущий в path.
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+// Send дописывает уведомление в файл.
+func (n *FileNotifier) Send(_ context.Context, msg Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f, err := os.OpenFile(n.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("открытие файла уведомлений: %w", err)
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n---\n",
+		time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+	return err
+}
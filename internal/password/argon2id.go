@@ -0,0 +1,103 @@
+// argon2id.go
+// Этот файл реализует хэширование паролей Argon2id (RFC 9106).
+// Хэш хранится в формате PHC: $argon2id$v=19$m=<KiB>,t=<проходы>,p=<потоки>$<соль>$<хэш>.
+
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2idPrefix = "$argon2id$"
+	saltLength     = 16
+	keyLength      = 32
+)
+
+// Argon2id — параметры Argon2id.
+type Argon2id struct {
+	// Memory — объём памяти в КиБ
+	Memory uint32
+	// Iterations — число проходов
+	Iterations uint32
+	// Parallelism — число потоков
+	Parallelism uint8
+}
+
+// argon2Hash — разобранный PHC-хэш Argon2id.
+type argon2Hash struct {
+	params Argon2id
+	salt   []byte
+	key    []byte
+}
+
+// Hash возвращает PHC-хэш пароля со случайной солью.
+func (a Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, keyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		a.Memory, a.Iterations, a.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify сравнивает пароль с хэшем, используя параметры из самого хэша.
+func (a Argon2id) Verify(password, encoded string) (bool, error) {
+	h, err := parseArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	p := h.params
+	key := argon2.IDKey([]byte(password), h.salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(h.key)))
+	return subtle.ConstantTimeCompare(key, h.key) == 1, nil
+}
+
+// Supports сообщает, является ли хэш хэшем Argon2id.
+func (a Argon2id) Supports(encoded string) bool {
+	return strings.HasPrefix(encoded, argon2idPrefix)
+}
+
+// NeedsRehash сообщает, отличаются ли параметры хэша от текущих.
+func (a Argon2id) NeedsRehash(encoded string) bool {
+	h, err := parseArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return h.params != a || len(h.key) != keyLength
+}
+
+// parseArgon2id разбирает PHC-строку Argon2id.
+func parseArgon2id(encoded string) (*argon2Hash, error) {
+	parts := strings.Split(encoded, "$")
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", соль, хэш
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, ErrUnknownFormat
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrUnknownFormat
+	}
+	var h argon2Hash
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.params.Memory, &h.params.Iterations, &h.params.Parallelism); err != nil {
+		return nil, ErrUnknownFormat
+	}
+	if h.params.Memory == 0 || h.params.Iterations == 0 || h.params.Parallelism == 0 {
+		return nil, ErrUnknownFormat
+	}
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrUnknownFormat
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 {
+		return nil, ErrUnknownFormat
+	}
+	return &h, nil
+}
@@ -0,0 +1,44 @@
+// bcrypt.go
+// Этот файл реализует хэширование паролей bcrypt — алгоритм, которым
+// хэшировались пароли до перехода на Argon2id.
+
+package password
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Bcrypt — параметры bcrypt.
+type Bcrypt struct {
+	// Cost — стоимость (log2 числа раундов)
+	Cost int
+}
+
+// Hash возвращает bcrypt-хэш пароля.
+func (b Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	return string(hash), err
+}
+
+// Verify сравнивает пароль с bcrypt-хэшем.
+func (b Bcrypt) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Supports сообщает, является ли хэш bcrypt-хэшем ($2a$, $2b$, $2y$).
+func (b Bcrypt) Supports(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2")
+}
+
+// NeedsRehash сообщает, отличается ли стоимость хэша от текущей.
+func (b Bcrypt) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != b.Cost
+}
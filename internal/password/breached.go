@@ -0,0 +1,180 @@
+// breached.go
+// Этот файл содержит список утёкших и распространённых паролей.
+// Пароли хранятся как отсортированные усечённые SHA-1 хэши и запрашиваются
+// по 5-символьному префиксу хэша, как в API Pwned Passwords (k-anonymity):
+// источник никогда не получает сам пароль или его полный хэш.
+
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// breachedMagic — сигнатура файла списка
+	breachedMagic = "KVBP\x01"
+	// recordSize — сколько байт SHA-1 хранится на пароль (80 бит)
+	recordSize = 10
+	// PrefixLength — длина префикса запроса в hex-символах
+	PrefixLength = 5
+)
+
+// ErrInvalidPrefix ошибка, если префикс не состоит из PrefixLength hex-символов.
+var ErrInvalidPrefix = errors.New("некорректный префикс хэша")
+
+// BreachedSource — источник утёкших паролей с запросом по префиксу SHA-1.
+type BreachedSource interface {
+	// Range возвращает окончания (в верхнем регистре hex) хэшей, начинающихся с prefix.
+	Range(prefix string) ([]string, error)
+}
+
+// IsBreached сообщает, есть ли пароль в источнике. Источнику передаётся только префикс хэша.
+func IsBreached(src BreachedSource, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	full := strings.ToUpper(hex.EncodeToString(sum[:]))
+	suffixes, err := src.Range(full[:PrefixLength])
+	if err != nil {
+		return false, err
+	}
+	for _, s := range suffixes {
+		if s != "" && strings.HasPrefix(full[PrefixLength:], s) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// BreachedList — список в памяти: отсортированные записи по recordSize байт.
+type BreachedList struct {
+	records []byte
+}
+
+// defaultBreached — встроенный список, собранный из data/common.txt:
+//
+//	go run ./cmd/breachedlist -o internal/password/data/common.bin < internal/password/data/common.txt
+//
+//go:embed data/common.bin
+var defaultBreached []byte
+
+var (
+	defaultOnce sync.Once
+	defaultList *BreachedList
+)
+
+// DefaultBreachedList возвращает встроенный список самых распространённых паролей.
+func DefaultBreachedList() *BreachedList {
+	defaultOnce.Do(func() {
+		l, err := ReadBreachedList(bytes.NewReader(defaultBreached))
+		if err != nil {
+			panic(fmt.Sprintf("password: повреждён встроенный список паролей: %v", err))
+		}
+		defaultList = l
+	})
+	return defaultList
+}
+
+// LoadBreachedList читает список из файла, созданного WriteBreachedList.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadBreachedList(bufio.NewReader(f))
+}
+
+// ReadBreachedList читает список в формате WriteBreachedList.
+func ReadBreachedList(r io.Reader) (*BreachedList, error) {
+	magic := make([]byte, len(breachedMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != breachedMagic {
+		return nil, errors.New("неизвестный формат списка паролей")
+	}
+	records, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(records)%recordSize != 0 {
+		return nil, errors.New("список паролей обрезан")
+	}
+	return &BreachedList{records: records}, nil
+}
+
+// WriteBreachedList записывает SHA-1 хэши паролей в компактном формате:
+// сигнатура и отсортированные без повторов первые recordSize байт каждого хэша.
+func WriteBreachedList(w io.Writer, hashes [][sha1.Size]byte) error {
+	recs := make([][]byte, len(hashes))
+	for i := range hashes {
+		recs[i] = hashes[i][:recordSize]
+	}
+	sort.Slice(recs, func(i, j int) bool { return bytes.Compare(recs[i], recs[j]) < 0 })
+	if _, err := io.WriteString(w, breachedMagic); err != nil {
+		return err
+	}
+	var prev []byte
+	for _, rec := range recs {
+		if bytes.Equal(rec, prev) {
+			continue
+		}
+		if _, err := w.Write(rec); err != nil {
+			return err
+		}
+		prev = rec
+	}
+	return nil
+}
+
+// Len возвращает число паролей в списке.
+func (l *BreachedList) Len() int {
+	return len(l.records) / recordSize
+}
+
+// Range возвращает окончания хэшей с префиксом prefix. Хэши усечены,
+// поэтому окончания короче полного SHA-1; IsBreached сравнивает их как префиксы.
+func (l *BreachedList) Range(prefix string) ([]string, error) {
+	want, err := parsePrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+	n := l.Len()
+	start := sort.Search(n, func(i int) bool { return l.prefixAt(i) >= want })
+	var out []string
+	for i := start; i < n && l.prefixAt(i) == want; i++ {
+		rec := strings.ToUpper(hex.EncodeToString(l.record(i)))
+		out = append(out, rec[PrefixLength:])
+	}
+	return out, nil
+}
+
+// record возвращает i-ю запись.
+func (l *BreachedList) record(i int) []byte {
+	return l.records[i*recordSize : (i+1)*recordSize]
+}
+
+// prefixAt возвращает первые 20 бит i-й записи.
+func (l *BreachedList) prefixAt(i int) uint32 {
+	r := l.record(i)
+	return uint32(r[0])<<12 | uint32(r[1])<<4 | uint32(r[2])>>4
+}
+
+// parsePrefix переводит 5 hex-символов в число.
+func parsePrefix(prefix string) (uint32, error) {
+	if len(prefix) != PrefixLength {
+		return 0, ErrInvalidPrefix
+	}
+	b, err := hex.DecodeString(prefix + "0")
+	if err != nil {
+		return 0, ErrInvalidPrefix
+	}
+	return uint32(b[0])<<12 | uint32(b[1])<<4 | uint32(b[2])>>4, nil
+}
@@ -0,0 +1,56 @@
+// hasher.go
+// Этот файл содержит интерфейс алгоритма хэширования паролей и Manager,
+// который хэширует новым алгоритмом, но проверяет и хэши всех известных алгоритмов.
+
+package password
+
+import "errors"
+
+// ErrUnknownFormat ошибка, если хэш не относится ни к одному из известных алгоритмов.
+var ErrUnknownFormat = errors.New("неизвестный формат хэша пароля")
+
+// Hasher — алгоритм хэширования паролей.
+type Hasher interface {
+	// Hash возвращает хэш пароля вместе с солью и параметрами алгоритма.
+	Hash(password string) (string, error)
+	// Verify сравнивает пароль с хэшем этого алгоритма.
+	Verify(password, encoded string) (bool, error)
+	// Supports сообщает, создан ли хэш этим алгоритмом.
+	Supports(encoded string) bool
+	// NeedsRehash сообщает, созданы ли хэш этого алгоритма с устаревшими параметрами.
+	NeedsRehash(encoded string) bool
+}
+
+// Manager хэширует пароли основным алгоритмом и проверяет хэши основного
+// и прежних алгоритмов. Хэш прежнего алгоритма или с устаревшими параметрами
+// помечается для перехэширования.
+type Manager struct {
+	primary Hasher
+	legacy  []Hasher
+}
+
+// NewManager создаёт Manager с основным алгоритмом primary и прежними legacy.
+func NewManager(primary Hasher, legacy ...Hasher) *Manager {
+	return &Manager{primary: primary, legacy: legacy}
+}
+
+// Hash возвращает хэш пароля основным алгоритмом.
+func (m *Manager) Hash(password string) (string, error) {
+	return m.primary.Hash(password)
+}
+
+// Verify сравнивает пароль с хэшем. rehash истинно, если пароль верен, но хэш
+// следует пересчитать основным алгоритмом с текущими параметрами.
+func (m *Manager) Verify(password, encoded string) (ok, rehash bool, err error) {
+	if m.primary.Supports(encoded) {
+		ok, err = m.primary.Verify(password, encoded)
+		return ok, ok && m.primary.NeedsRehash(encoded), err
+	}
+	for _, h := range m.legacy {
+		if h.Supports(encoded) {
+			ok, err = h.Verify(password, encoded)
+			return ok, ok, err
+		}
+	}
+	return false, false, ErrUnknownFormat
+}
@@ -0,0 +1,112 @@
+// policy.go
+// Этот файл содержит политику паролей: минимальная длина, число типов символов,
+// запрет имени и email в пароле и проверка по списку утёкших паролей.
+
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// personalMinLength — части имени и email короче этого не проверяются:
+// короткие подстроки встречаются в паролях случайно.
+const personalMinLength = 3
+
+// Policy — требования к новому паролю.
+type Policy struct {
+	// MinLength — минимальная длина в символах
+	MinLength int
+	// MinClasses — сколько разных типов символов нужно: строчные, заглавные, цифры, остальные
+	MinClasses int
+	// RejectPersonal — запрещать пароли, содержащие имя или email пользователя
+	RejectPersonal bool
+	// Breached — список утёкших паролей; nil — не проверять
+	Breached BreachedSource
+}
+
+// PolicyError — пароль не соответствует политике. Violations — по сообщению на каждое нарушение.
+type PolicyError struct {
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return "пароль не соответствует требованиям: " + strings.Join(e.Violations, "; ")
+}
+
+// Validate проверяет пароль. personal — имя, email и другие данные пользователя,
+// которых не должно быть в пароле. Нарушения возвращаются как *PolicyError.
+func (p *Policy) Validate(password string, personal ...string) error {
+	var violations []string
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, fmt.Sprintf("Пароль должен содержать минимум %d символов", p.MinLength))
+	}
+	if p.MinClasses > 1 && charClasses(password) < p.MinClasses {
+		violations = append(violations, fmt.Sprintf(
+			"Пароль должен содержать символы минимум %d типов из: строчные буквы, заглавные буквы, цифры, другие символы",
+			p.MinClasses))
+	}
+	if p.RejectPersonal && containsPersonal(password, personal) {
+		violations = append(violations, "Пароль не должен содержать имя или email")
+	}
+	if p.Breached != nil {
+		breached, err := IsBreached(p.Breached, password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violations = append(violations, "Пароль слишком распространён или встречался в утечках")
+		}
+	}
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// charClasses считает, сколько типов символов есть в пароле.
+func charClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	n := 0
+	for _, ok := range []bool{lower, upper, digit, other} {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// containsPersonal сообщает, содержит ли пароль без учёта регистра имя или email
+// из personal. Проверяются значение целиком и отдельные слова: части имени,
+// имя ящика до @ и его части, разделённые точками, дефисами и т. п.
+func containsPersonal(password string, personal []string) bool {
+	pw := strings.ToLower(password)
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		local, _, _ := strings.Cut(value, "@")
+		parts := strings.FieldsFunc(local, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		parts = append(parts, local, value)
+		for _, part := range parts {
+			if utf8.RuneCountInString(part) >= personalMinLength && strings.Contains(pw, part) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+// fake.go
+// Этот файл содержит детерминированный платёжный провайдер для разработки и тестов.
+// Он работает в памяти процесса, результат зависит только от способа оплаты,
+// а уведомления подписываются HMAC-SHA256 общим секретом.
+
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Способы оплаты FakeProvider.
+const (
+	// FakeMethodSuccess — платёж проходит сразу; он же используется, если способ не указан
+	FakeMethodSuccess = "fake_card_ok"
+	// FakeMethodDeclined — платёж отклоняется
+	FakeMethodDeclined = "fake_card_declined"
+	// FakeMethodAsync — платёж остаётся в обработке, результат приходит уведомлением
+	FakeMethodAsync = "fake_card_async"
+)
+
+// FakeSignatureHeader — заголовок с подписью уведомления FakeProvider.
+const FakeSignatureHeader = "Fake-Signature"
+
+// fakePayment — состояние платежа FakeProvider.
+type fakePayment struct {
+	status   string
+	amount   int64
+	currency string
+	captured int64
+	refunded int64
+}
+
+// FakeProvider — платёжный провайдер в памяти процесса.
+type FakeProvider struct {
+	secret   []byte
+	mu       sync.Mutex
+	payments map[string]*fakePayment
+}
+
+// NewFakeProvider создаёт FakeProvider, подписывающий уведомления секретом secret.
+func NewFakeProvider(secret string) *FakeProvider {
+	return &FakeProvider{secret: []byte(secret), payments: make(map[string]*fakePayment)}
+}
+
+// Name возвращает "fake".
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+// Authorize авторизует платёж в зависимости от способа оплаты (FakeMethod*).
+// Идентификатор платежа у провайдера — "fake_" + Reference.
+func (p *FakeProvider) Authorize(_ context.Context, req AuthorizeRequest) (Result, error) {
+	ref := "fake_" + req.Reference
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if fp, ok := p.payments[ref]; ok {
+		return Result{ProviderRef: ref, Status: fp.status}, nil
+	}
+	switch req.Method {
+	case "", FakeMethodSuccess:
+		p.payments[ref] = &fakePayment{status: StatusAuthorized, amount: req.AmountMinor, currency: req.Currency}
+		return Result{ProviderRef: ref, Status: StatusAuthorized}, nil
+	case FakeMethodAsync:
+		p.payments[ref] = &fakePayment{status: StatusPending, amount: req.AmountMinor, currency: req.Currency}
+		return Result{ProviderRef: ref, Status: StatusPending}, nil
+	case FakeMethodDeclined:
+		return Result{}, fmt.Errorf("%w: недостаточно средств", ErrDeclined)
+	}
+	return Result{}, fmt.Errorf("%w: неизвестный способ оплаты %q", ErrDeclined, req.Method)
+}
+
+// Capture списывает авторизованную сумму, не больше авторизованной.
+func (p *FakeProvider) Capture(_ context.Context, providerRef string, amountMinor int64) (Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fp, ok := p.payments[providerRef]
+	if !ok || fp.status != StatusAuthorized || amountMinor > fp.amount {
+		return Result{}, fmt.Errorf("%w: списание %s", ErrInvalidState, providerRef)
+	}
+	fp.status, fp.captured = StatusCaptured, amountMinor
+	return Result{ProviderRef: providerRef, Status: StatusCaptured}, nil
+}
+
+// Refund возвращает списанную сумму, не больше ещё не возвращённой.
+func (p *FakeProvider) Refund(_ context.Context, providerRef string, amountMinor int64) (Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fp, ok := p.payments[providerRef]
+	if !ok || fp.status != StatusCaptured || amountMinor > fp.captured-fp.refunded {
+		return Result{}, fmt.Errorf("%w: возврат %s", ErrInvalidState, providerRef)
+	}
+	fp.refunded += amountMinor
+	if fp.refunded == fp.captured {
+		fp.status = StatusRefunded
+	}
+	return Result{ProviderRef: providerRef, Status: fp.status}, nil
+}
+
+// fakeEvent — тело уведомления FakeProvider.
+type fakeEvent struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	PaymentID   string `json:"payment_id"`
+	AmountMinor int64  `json:"amount_minor"`
+	Currency    string `json:"currency"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// VerifyWebhook проверяет подпись из заголовка FakeSignatureHeader и разбирает уведомление.
+func (p *FakeProvider) VerifyWebhook(header http.Header, payload []byte) (*Event, error) {
+	sig, err := hex.DecodeString(header.Get(FakeSignatureHeader))
+	if err != nil || !hmac.Equal(sig, p.sign(payload)) {
+		return nil, fmt.Errorf("%w: неверная подпись", ErrInvalidWebhook)
+	}
+	var e fakeEvent
+	if err := json.Unmarshal(payload, &e); err != nil || e.ID == "" || e.PaymentID == "" {
+		return nil, fmt.Errorf("%w: некорректное тело", ErrInvalidWebhook)
+	}
+	return &Event{ID: e.ID, Type: e.Type, ProviderRef: e.PaymentID, AmountMinor: e.AmountMinor, Currency: e.Currency, Reason: e.Reason}, nil
+}
+
+// Webhook завершает платёж в обработке, как это сделал бы провайдер, и возвращает
+// подписанное уведомление об этом: тело и заголовки для POST на адрес уведомлений.
+// eventType — EventCaptured или EventFailed.
+func (p *FakeProvider) Webhook(eventID, eventType, providerRef string) ([]byte, http.Header, error) {
+	p.mu.Lock()
+	fp, ok := p.payments[providerRef]
+	if !ok || fp.status != StatusPending {
+		p.mu.Unlock()
+		return nil, nil, fmt.Errorf("%w: уведомление для %s", ErrInvalidState, providerRef)
+	}
+	e := fakeEvent{ID: eventID, Type: eventType, PaymentID: providerRef, AmountMinor: fp.amount, Currency: fp.currency}
+	switch eventType {
+	case EventCaptured:
+		fp.status, fp.captured = StatusCaptured, fp.amount
+	case EventFailed:
+		fp.status, e.Reason = StatusFailed, "платёж отклонён банком"
+	default:
+		p.mu.Unlock()
+		return nil, nil, fmt.Errorf("%w: тип уведомления %q", ErrInvalidState, eventType)
+	}
+	p.mu.Unlock()
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(FakeSignatureHeader, hex.EncodeToString(p.sign(payload)))
+	return payload, header, nil
+}
+
+// sign возвращает HMAC-SHA256 тела уведомления.
+func (p *FakeProvider) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
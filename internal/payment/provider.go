@@ -0,0 +1,91 @@
+// provider.go
+// Этот файл содержит интерфейс платёжного провайдера: авторизация, списание
+// и возврат платежа, проверка уведомлений (webhook). Реализация для разработки
+// и тестов — FakeProvider; настоящий шлюз подключается реализацией того же интерфейса.
+
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+var (
+	// ErrDeclined ошибка, если провайдер отклонил платёж (например, недостаточно средств).
+	ErrDeclined = errors.New("платёж отклонён")
+	// ErrInvalidState ошибка, если операция невозможна в текущем состоянии платежа у провайдера.
+	ErrInvalidState = errors.New("операция недоступна для платежа")
+	// ErrInvalidWebhook ошибка, если уведомление не подписано провайдером или повреждено.
+	ErrInvalidWebhook = errors.New("некорректное уведомление платёжного провайдера")
+)
+
+// Состояния платежа у провайдера.
+const (
+	// StatusPending — провайдер ещё обрабатывает платёж, результат придёт уведомлением
+	StatusPending = "pending"
+	// StatusAuthorized — сумма заблокирована у плательщика и ждёт списания
+	StatusAuthorized = "authorized"
+	// StatusCaptured — сумма списана
+	StatusCaptured = "captured"
+	// StatusRefunded — сумма возвращена плательщику
+	StatusRefunded = "refunded"
+	// StatusFailed — платёж не прошёл
+	StatusFailed = "failed"
+)
+
+// Типы уведомлений провайдера.
+const (
+	EventCaptured = "payment.captured"
+	EventFailed   = "payment.failed"
+	EventRefunded = "payment.refunded"
+)
+
+// AuthorizeRequest данные для авторизации платежа.
+type AuthorizeRequest struct {
+	// Reference — наш идентификатор платежа; повторная авторизация с ним не создаёт новый платёж
+	Reference string
+	// AmountMinor — сумма в минимальных единицах валюты
+	AmountMinor int64
+	// Currency — код валюты ISO 4217
+	Currency string
+	// Method — способ оплаты: токен карты или кошелька, выданный провайдером клиенту
+	Method string
+}
+
+// Result — состояние платежа у провайдера после операции.
+type Result struct {
+	// ProviderRef — идентификатор платежа у провайдера
+	ProviderRef string
+	// Status — StatusPending, StatusAuthorized, StatusCaptured или StatusRefunded
+	Status string
+}
+
+// Event — уведомление провайдера о смене состояния платежа.
+type Event struct {
+	// ID — идентификатор уведомления; провайдер может прислать одно уведомление несколько раз
+	ID string
+	// Type — EventCaptured, EventFailed или EventRefunded
+	Type        string
+	ProviderRef string
+	// AmountMinor и Currency — сумма операции, о которой уведомляет провайдер
+	AmountMinor int64
+	Currency    string
+	// Reason — причина отказа для EventFailed
+	Reason string
+}
+
+// PaymentProvider — платёжный провайдер.
+type PaymentProvider interface {
+	// Name возвращает имя провайдера, под которым хранятся его платежи.
+	Name() string
+	// Authorize блокирует сумму у плательщика. Возвращает ErrDeclined, если платёж отклонён.
+	Authorize(ctx context.Context, req AuthorizeRequest) (Result, error)
+	// Capture списывает авторизованную сумму.
+	Capture(ctx context.Context, providerRef string, amountMinor int64) (Result, error)
+	// Refund возвращает списанную сумму плательщику.
+	Refund(ctx context.Context, providerRef string, amountMinor int64) (Result, error)
+	// VerifyWebhook проверяет подпись уведомления и разбирает его.
+	// Возвращает ErrInvalidWebhook, если подпись неверна.
+	VerifyWebhook(header http.Header, payload []byte) (*Event, error)
+}
@@ -0,0 +1,116 @@
+// pricing.go
+// Этот файл содержит интерфейсы расчёта налога и стоимости доставки заказа.
+// Реализации выбираются конфигурацией (см. bootstrap); другие правила —
+// например, внешний налоговый сервис — подключаются реализацией тех же интерфейсов.
+
+package pricing
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+)
+
+// ErrShippingUnavailable ошибка, если доставку заказа нельзя рассчитать:
+// заказ в валюте без тарифов или тяжелее (дороже) последнего тарифа.
+var ErrShippingUnavailable = errors.New("доставка заказа недоступна")
+
+// Line — позиция заказа для расчёта.
+type Line struct {
+	ProductID uint
+	// TaxCategory — налоговая категория товара; пусто — ставка по умолчанию
+	TaxCategory string
+	Quantity    int
+	// WeightGrams — вес единицы товара в граммах
+	WeightGrams int
+	// AmountMinor — стоимость позиции за вычетом её доли скидки, в минимальных единицах валюты
+	AmountMinor int64
+}
+
+// Order — заказ для расчёта налога и доставки.
+type Order struct {
+	// Currency — валюта заказа, код ISO 4217
+	Currency string
+	Lines    []Line
+}
+
+// AmountMinor возвращает стоимость позиций заказа за вычетом скидки.
+func (o *Order) AmountMinor() int64 {
+	var sum int64
+	for _, l := range o.Lines {
+		sum += l.AmountMinor
+	}
+	return sum
+}
+
+// WeightGrams возвращает вес заказа в граммах.
+func (o *Order) WeightGrams() int64 {
+	var sum int64
+	for _, l := range o.Lines {
+		sum += int64(l.WeightGrams) * int64(l.Quantity)
+	}
+	return sum
+}
+
+// TaxLine — строка налога: налог по одной ставке.
+type TaxLine struct {
+	// Name — название налога или налоговой категории
+	Name string
+	// Rate — ставка в сотых долях процента: 2000 — 20%
+	Rate int
+	// BaseMinor — облагаемая сумма
+	BaseMinor int64
+	// AmountMinor — сумма налога
+	AmountMinor int64
+}
+
+// TaxCalculator рассчитывает налог заказа. Налог начисляется сверх стоимости позиций.
+type TaxCalculator interface {
+	Tax(ctx context.Context, o *Order) ([]TaxLine, error)
+}
+
+// ShippingCalculator рассчитывает стоимость доставки заказа в валюте заказа.
+type ShippingCalculator interface {
+	Shipping(ctx context.Context, o *Order) (int64, error)
+}
+
+// Distribute делит сумму total между позициями пропорционально amounts.
+// Остаток от округления вниз достаётся позициям с наибольшей дробной частью,
+// поэтому доли в сумме всегда равны total.
+func Distribute(total int64, amounts []int64) []int64 {
+	out := make([]int64, len(amounts))
+	var sum int64
+	for _, a := range amounts {
+		sum += a
+	}
+	if total == 0 || sum == 0 {
+		return out
+	}
+	type remainder struct {
+		i int
+		r *big.Int
+	}
+	rems := make([]remainder, len(amounts))
+	left := total
+	bigTotal, bigSum := big.NewInt(total), big.NewInt(sum)
+	for i, a := range amounts {
+		q, r := new(big.Int).QuoRem(new(big.Int).Mul(bigTotal, big.NewInt(a)), bigSum, new(big.Int))
+		out[i] = q.Int64()
+		left -= out[i]
+		rems[i] = remainder{i, r}
+	}
+	sort.SliceStable(rems, func(a, b int) bool { return rems[a].r.Cmp(rems[b].r) > 0 })
+	for k := 0; left > 0; k++ {
+		out[rems[k%len(rems)].i]++
+		left--
+	}
+	return out
+}
+
+// percentOf возвращает rate сотых долей процента от суммы с округлением
+// до минимальной единицы (половина — вверх).
+func percentOf(amount int64, rate int) int64 {
+	r := int64(rate)
+	return amount/10000*r + (amount%10000*r+5000)/10000
+}
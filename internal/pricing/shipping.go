@@ -0,0 +1,61 @@
+// shipping.go
+// Этот файл содержит встроенные расчёты доставки: бесплатная доставка
+// и таблица тарифов по весу или по стоимости заказа.
+
+package pricing
+
+import (
+	"context"
+	"fmt"
+)
+
+// Основа тарифов доставки.
+const (
+	// ByWeight — тариф по весу заказа в граммах
+	ByWeight = "weight"
+	// ByPrice — тариф по стоимости позиций за вычетом скидки
+	ByPrice = "price"
+)
+
+// NoShipping — доставка бесплатна.
+type NoShipping struct{}
+
+// Shipping возвращает нулевую стоимость доставки.
+func (NoShipping) Shipping(context.Context, *Order) (int64, error) {
+	return 0, nil
+}
+
+// Tier — тариф доставки: заказы весом (стоимостью) до UpTo включительно стоят FeeMinor.
+type Tier struct {
+	// UpTo — граница тарифа: граммы для ByWeight, минимальные единицы валюты для ByPrice
+	UpTo int64
+	// FeeMinor — стоимость доставки в минимальных единицах валюты
+	FeeMinor int64
+}
+
+// TieredShipping — таблица тарифов доставки в одной валюте.
+type TieredShipping struct {
+	// Basis — ByWeight или ByPrice
+	Basis string
+	// Currency — валюта границ и стоимости тарифов; заказы в других валютах не доставляются
+	Currency string
+	// Tiers — тарифы по возрастанию UpTo
+	Tiers []Tier
+}
+
+// Shipping возвращает стоимость доставки по первому подходящему тарифу.
+func (s TieredShipping) Shipping(_ context.Context, o *Order) (int64, error) {
+	if o.Currency != s.Currency {
+		return 0, fmt.Errorf("%w: тарифы доставки заданы в %s", ErrShippingUnavailable, s.Currency)
+	}
+	v := o.AmountMinor()
+	if s.Basis == ByWeight {
+		v = o.WeightGrams()
+	}
+	for _, t := range s.Tiers {
+		if v <= t.UpTo {
+			return t.FeeMinor, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: нет тарифа для такого заказа", ErrShippingUnavailable)
+}
@@ -0,0 +1,67 @@
+// tax.go
+// Этот файл содержит встроенные расчёты налога: без налога, единая ставка
+// и ставки по налоговым категориям товаров.
+
+package pricing
+
+import (
+	"context"
+	"sort"
+)
+
+// NoTax — заказы без налога.
+type NoTax struct{}
+
+// Tax возвращает пустой список строк налога.
+func (NoTax) Tax(context.Context, *Order) ([]TaxLine, error) {
+	return nil, nil
+}
+
+// FlatTax — единая ставка налога (например, НДС) для всех товаров.
+type FlatTax struct {
+	// Name — название налога в строке налога
+	Name string
+	// Rate — ставка в сотых долях процента
+	Rate int
+}
+
+// Tax начисляет налог по единой ставке на стоимость позиций.
+func (t FlatTax) Tax(_ context.Context, o *Order) ([]TaxLine, error) {
+	base := o.AmountMinor()
+	return []TaxLine{{Name: t.Name, Rate: t.Rate, BaseMinor: base, AmountMinor: percentOf(base, t.Rate)}}, nil
+}
+
+// CategoryTax — ставки по налоговым категориям товаров.
+type CategoryTax struct {
+	// Name — название строки налога для товаров без категории
+	Name string
+	// Default — ставка для товаров без категории и с неизвестной категорией
+	Default int
+	// Rates — ставки категорий в сотых долях процента
+	Rates map[string]int
+}
+
+// Tax группирует позиции по категориям и начисляет налог на каждую группу:
+// одна строка налога на категорию, налог округляется один раз на строку.
+func (t CategoryTax) Tax(_ context.Context, o *Order) ([]TaxLine, error) {
+	byName := make(map[string]*TaxLine)
+	for _, l := range o.Lines {
+		name, rate := t.Name, t.Default
+		if r, ok := t.Rates[l.TaxCategory]; ok {
+			name, rate = l.TaxCategory, r
+		}
+		line, ok := byName[name]
+		if !ok {
+			line = &TaxLine{Name: name, Rate: rate}
+			byName[name] = line
+		}
+		line.BaseMinor += l.AmountMinor
+	}
+	lines := make([]TaxLine, 0, len(byName))
+	for _, line := range byName {
+		line.AmountMinor = percentOf(line.BaseMinor, line.Rate)
+		lines = append(lines, *line)
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i].Name < lines[j].Name })
+	return lines, nil
+}
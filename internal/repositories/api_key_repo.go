@@ -0,0 +1,71 @@
+// api_key_repo.go
+// Этот файл отвечает за взаимодействие с таблицей API-ключей в базе данных.
+// Реализует методы для выпуска, поиска, ротации и отзыва ключей.
+
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"kvant_task/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyRepo отвечает за работу с таблицей api_keys.
+type APIKeyRepo struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepo создаёт новый APIKeyRepo.
+func NewAPIKeyRepo(db *gorm.DB) *APIKeyRepo {
+	return &APIKeyRepo{db: db}
+}
+
+// Create сохраняет новый ключ.
+func (r *APIKeyRepo) Create(ctx context.Context, k *models.APIKey) error {
+	return r.db.WithContext(ctx).Create(k).Error
+}
+
+// GetByPrefix возвращает ключ по открытой части.
+func (r *APIKeyRepo) GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	var k models.APIKey
+	err := r.db.WithContext(ctx).
+		Where("prefix = ?", prefix).
+		First(&k).Error
+	return &k, err
+}
+
+// GetForUser возвращает ключ пользователя по ID.
+func (r *APIKeyRepo) GetForUser(ctx context.Context, userID, id uint) (*models.APIKey, error) {
+	var k models.APIKey
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&k).Error
+	return &k, err
+}
+
+// ListByUser возвращает ключи пользователя, включая отозванные.
+func (r *APIKeyRepo) ListByUser(ctx context.Context, userID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("id").
+		Find(&keys).Error
+	return keys, err
+}
+
+// Update сохраняет изменения ключа.
+func (r *APIKeyRepo) Update(ctx context.Context, k *models.APIKey) error {
+	return r.db.WithContext(ctx).Save(k).Error
+}
+
+// TouchLastUsed обновляет время использования, если прошлое обновление было раньше since.
+// Так частые запросы по одному ключу не превращаются в запись на каждый запрос.
+func (r *APIKeyRepo) TouchLastUsed(ctx context.Context, id uint, at, since time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, since).
+		Update("last_used_at", at).Error
+}
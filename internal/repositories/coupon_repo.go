@@ -0,0 +1,127 @@
+// coupon_repo.go
+// Этот файл отвечает за взаимодействие с таблицами промокодов и их применений.
+// Применение промокода выполняется внутри транзакции создания или изменения заказа.
+
+package repositories
+
+import (
+	"context"
+
+	"kvant_task/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CouponRepo отвечает за работу с таблицами coupons и coupon_redemptions.
+type CouponRepo struct {
+	db *gorm.DB
+}
+
+// NewCouponRepo создаёт новый CouponRepo.
+func NewCouponRepo(db *gorm.DB) *CouponRepo {
+	return &CouponRepo{db: db}
+}
+
+// Create сохраняет новый промокод.
+func (r *CouponRepo) Create(ctx context.Context, c *models.Coupon) error {
+	return r.db.WithContext(ctx).Create(c).Error
+}
+
+// GetByID возвращает промокод по ID.
+func (r *CouponRepo) GetByID(ctx context.Context, id uint) (*models.Coupon, error) {
+	var c models.Coupon
+	err := r.db.WithContext(ctx).First(&c, id).Error
+	return &c, err
+}
+
+// GetByCode возвращает промокод по коду.
+func (r *CouponRepo) GetByCode(ctx context.Context, code string) (*models.Coupon, error) {
+	var c models.Coupon
+	err := r.db.WithContext(ctx).Where("code = ?", code).First(&c).Error
+	return &c, err
+}
+
+// List возвращает страницу промокодов, новые первыми, и общее число промокодов.
+func (r *CouponRepo) List(ctx context.Context, page, limit int) ([]models.Coupon, int64, error) {
+	var (
+		list  []models.Coupon
+		total int64
+	)
+	q := r.db.WithContext(ctx).Model(&models.Coupon{})
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := q.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&list).Error
+	return list, total, err
+}
+
+// Deactivate отключает промокод. Возвращает gorm.ErrRecordNotFound, если промокода нет.
+func (r *CouponRepo) Deactivate(ctx context.Context, id uint) error {
+	res := r.db.WithContext(ctx).
+		Model(&models.Coupon{}).
+		Where("id = ?", id).
+		Update("active", false)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// LockByCode выбирает промокод по коду с блокировкой строки (FOR UPDATE) до конца
+// транзакции: применения одного промокода выполняются по очереди. Вызывается внутри транзакции.
+func (r *CouponRepo) LockByCode(ctx context.Context, code string) (*models.Coupon, error) {
+	var c models.Coupon
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("code = ?", code).
+		First(&c).Error
+	return &c, err
+}
+
+// LockByID выбирает промокод по ID с блокировкой строки; см. LockByCode.
+func (r *CouponRepo) LockByID(ctx context.Context, id uint) (*models.Coupon, error) {
+	var c models.Coupon
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&c, id).Error
+	return &c, err
+}
+
+// CountUserRedemptions возвращает, сколько раз пользователь применил промокод.
+func (r *CouponRepo) CountUserRedemptions(ctx context.Context, couponID, userID uint) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).
+		Model(&models.CouponRedemption{}).
+		Where("coupon_id = ? AND user_id = ?", couponID, userID).
+		Count(&n).Error
+	return n, err
+}
+
+// Redeem записывает применение промокода и увеличивает счётчик, только если
+// общий лимит не исчерпан. Возвращает false, если лимит исчерпан.
+func (r *CouponRepo) Redeem(ctx context.Context, red *models.CouponRedemption) (bool, error) {
+	redeemed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.Coupon{}).
+			Where("id = ? AND (max_redemptions IS NULL OR redemptions < max_redemptions)", red.CouponID).
+			Update("redemptions", gorm.Expr("redemptions + 1"))
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		redeemed = true
+		return tx.Create(red).Error
+	})
+	return redeemed, err
+}
+
+// UpdateDiscount меняет скидку применения промокода к заказу.
+func (r *CouponRepo) UpdateDiscount(ctx context.Context, orderID uint, discount int64) error {
+	return r.db.WithContext(ctx).
+		Model(&models.CouponRedemption{}).
+		Where("order_id = ?", orderID).
+		Update("discount_minor", discount).Error
+}
@@ -0,0 +1,53 @@
+// one_time_token_repo.go
+// Этот файл отвечает за взаимодействие с таблицей одноразовых токенов в базе данных.
+// Реализует выпуск и атомарное погашение токенов.
+
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"kvant_task/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OneTimeTokenRepo отвечает за работу с таблицей one_time_tokens.
+type OneTimeTokenRepo struct {
+	db *gorm.DB
+}
+
+// NewOneTimeTokenRepo создаёт новый OneTimeTokenRepo.
+func NewOneTimeTokenRepo(db *gorm.DB) *OneTimeTokenRepo {
+	return &OneTimeTokenRepo{db: db}
+}
+
+// Create сохраняет новый токен, погашая ранее выданные токены того же назначения.
+func (r *OneTimeTokenRepo) Create(ctx context.Context, t *models.OneTimeToken) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.OneTimeToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", t.UserID, t.Purpose).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(t).Error
+	})
+}
+
+// Consume атомарно погашает действующий токен и возвращает его.
+// Возвращает gorm.ErrRecordNotFound, если токен не найден, истёк или уже использован.
+func (r *OneTimeTokenRepo) Consume(ctx context.Context, hash, purpose string, now time.Time) (*models.OneTimeToken, error) {
+	var t models.OneTimeToken
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", hash, purpose, now).
+			First(&t).Error; err != nil {
+			return err
+		}
+		t.UsedAt = &now
+		return tx.Model(&t).Update("used_at", now).Error
+	})
+	return &t, err
+}
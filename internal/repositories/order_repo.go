@@ -6,19 +6,31 @@ package repositories
 
 import (
 	"context"
+	"strings"
 	"time"
 
+	"kvant_task/internal/models"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// Order — модель заказа для GORM.
+// Order — модель заказа для GORM: заголовок и позиции.
 type Order struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	UserID    uint      `gorm:"not null;index" json:"user_id"`
-	Product   string    `gorm:"size:255;not null" json:"product"`
-	Quantity  int       `gorm:"not null" json:"quantity"`
-	Price     float64   `gorm:"type:numeric(10,2);not null" json:"price"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ID            uint                  `gorm:"primaryKey;index:idx_orders_user_created,priority:3;index:idx_orders_created,priority:2;index:idx_orders_status_created,priority:3;index:idx_orders_currency_total,priority:3" json:"id"`
+	UserID        uint                  `gorm:"not null;index;index:idx_orders_user_created,priority:1" json:"user_id"`
+	Items         []models.OrderItem    `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE" json:"items"`
+	Currency      string                `gorm:"size:3;not null;default:RUB;index:idx_orders_currency_total,priority:1" json:"currency"`
+	SubtotalMinor int64                 `gorm:"not null;default:0" json:"subtotal_minor"`
+	CouponID      *uint                 `gorm:"index" json:"coupon_id"`
+	CouponCode    string                `gorm:"size:64" json:"coupon_code"`
+	DiscountMinor int64                 `gorm:"not null;default:0" json:"discount_minor"`
+	TaxMinor      int64                 `gorm:"not null;default:0" json:"tax_minor"`
+	ShippingMinor int64                 `gorm:"not null;default:0" json:"shipping_minor"`
+	TaxLines      []models.OrderTaxLine `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE" json:"tax_lines"`
+	TotalMinor    int64                 `gorm:"not null;default:0;index:idx_orders_currency_total,priority:2" json:"total_minor"`
+	Status        string                `gorm:"size:20;not null;default:pending;index;index:idx_orders_status_created,priority:1" json:"status"`
+	CreatedAt     time.Time             `gorm:"autoCreateTime;index:idx_orders_user_created,priority:2;index:idx_orders_created,priority:1;index:idx_orders_status_created,priority:2" json:"created_at"`
 }
 
 // TableName жёстко задаёт имя таблицы (если нужно).
@@ -36,19 +48,367 @@ func NewOrderRepo(db *gorm.DB) *OrderRepo {
 	return &OrderRepo{db: db}
 }
 
-// Create сохраняет новый заказ.
+// Create сохраняет заказ вместе с позициями и строками налога в одной транзакции.
 func (r *OrderRepo) Create(ctx context.Context, o *Order) error {
-	return r.db.WithContext(ctx).Create(o).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Items", "TaxLines").Create(o).Error; err != nil {
+			return err
+		}
+		if len(o.Items) > 0 {
+			for i := range o.Items {
+				o.Items[i].OrderID = o.ID
+			}
+			if err := tx.Create(&o.Items).Error; err != nil {
+				return err
+			}
+		}
+		return createTaxLines(tx, o)
+	})
+}
+
+// createTaxLines сохраняет строки налога заказа.
+func createTaxLines(tx *gorm.DB, o *Order) error {
+	if len(o.TaxLines) == 0 {
+		return nil
+	}
+	for i := range o.TaxLines {
+		o.TaxLines[i].ID = 0
+		o.TaxLines[i].OrderID = o.ID
+	}
+	return tx.Create(&o.TaxLines).Error
+}
+
+// Поля сортировки заказов.
+const (
+	OrderSortCreatedAt = "created_at"
+	OrderSortTotal     = "total"
+	// OrderSortPrice — сортировка по самой высокой цене позиции заказа
+	OrderSortPrice = "price"
+)
+
+// orderSortExpr — SQL-выражения полей сортировки.
+var orderSortExpr = map[string]string{
+	OrderSortCreatedAt: "orders.created_at",
+	OrderSortTotal:     "orders.total_minor",
+	OrderSortPrice:     "(SELECT COALESCE(MAX(oi.price_minor), 0) FROM order_items oi WHERE oi.order_id = orders.id)",
+}
+
+// OrderFilter условия выборки заказов. Пустые поля не ограничивают выборку.
+// Суммы — в минимальных единицах валюты.
+type OrderFilter struct {
+	// UserID — владелец заказов; 0 — заказы всех пользователей
+	UserID uint
+	Status string
+	// CreatedFrom и CreatedTo — период создания, [CreatedFrom, CreatedTo)
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Currency    string
+	MinTotal    *int64
+	MaxTotal    *int64
+	// MinPrice и MaxPrice — в заказе есть позиция с ценой за единицу в этих пределах
+	MinPrice *int64
+	MaxPrice *int64
+	// MinQuantity и MaxQuantity — число единиц товара во всём заказе
+	MinQuantity *int
+	MaxQuantity *int
+	// Product — подстрока названия товара в одной из позиций, без учёта регистра
+	Product string
+	// ProductID — в заказе есть позиция с этим товаром каталога
+	ProductID uint
+
+	// Sort — поле сортировки (OrderSort*), Desc — по убыванию
+	Sort string
+	Desc bool
+	// Offset и Limit — страница выборки
+	Offset int
+	Limit  int
+	// After — курсор: выбираются заказы после этого в порядке сортировки; Offset тогда не нужен
+	After *OrderCursor
+}
+
+// OrderCursor положение в выборке: значение поля сортировки и ID последнего заказа страницы.
+type OrderCursor struct {
+	// CreatedAt — для сортировки по created_at
+	CreatedAt time.Time
+	// Amount — для сортировки по total и price
+	Amount int64
+	ID     uint
+}
+
+// CursorOf возвращает курсор, указывающий на заказ o при сортировке sort.
+func CursorOf(o *Order, sort string) *OrderCursor {
+	c := &OrderCursor{CreatedAt: o.CreatedAt, ID: o.ID}
+	switch sort {
+	case OrderSortTotal:
+		c.Amount = o.TotalMinor
+	case OrderSortPrice:
+		for _, it := range o.Items {
+			if it.PriceMinor > c.Amount {
+				c.Amount = it.PriceMinor
+			}
+		}
+	}
+	return c
 }
 
-// ListByUser возвращает заказы пользователя.
-func (r *OrderRepo) ListByUser(ctx context.Context, userID uint) ([]Order, error) {
+// List возвращает страницу заказов с позициями и общее число заказов, подходящих под фильтр.
+func (r *OrderRepo) List(ctx context.Context, f *OrderFilter) ([]Order, int64, error) {
+	var total int64
+	if err := r.filtered(ctx, f).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	expr, ok := orderSortExpr[f.Sort]
+	if !ok {
+		expr = orderSortExpr[OrderSortCreatedAt]
+	}
+	dir, cmp := "ASC", ">"
+	if f.Desc {
+		dir, cmp = "DESC", "<"
+	}
+	q := r.filtered(ctx, f)
+	if f.After != nil {
+		var v interface{} = f.After.Amount
+		if f.Sort == OrderSortCreatedAt || !ok {
+			v = f.After.CreatedAt
+		}
+		q = q.Where("("+expr+", orders.id) "+cmp+" (?, ?)", v, f.After.ID)
+	}
 	var orders []Order
-	err := r.db.WithContext(ctx).
-		Where("user_id = ?", userID).
-		Order("created_at DESC").
+	err := q.Preload("Items", withItemOrder).
+		Preload("TaxLines", withItemOrder).
+		Order(expr + " " + dir).
+		Order("orders.id " + dir).
+		Offset(f.Offset).
+		Limit(f.Limit).
 		Find(&orders).Error
-	return orders, err
+	return orders, total, err
+}
+
+// ListByUser возвращает страницу заказов пользователя; см. List.
+func (r *OrderRepo) ListByUser(ctx context.Context, userID uint, f *OrderFilter) ([]Order, int64, error) {
+	byUser := *f
+	byUser.UserID = userID
+	return r.List(ctx, &byUser)
+}
+
+// filtered строит запрос заказов с условиями фильтра, без сортировки и страниц.
+func (r *OrderRepo) filtered(ctx context.Context, f *OrderFilter) *gorm.DB {
+	q := r.db.WithContext(ctx).Model(&Order{})
+	if f.UserID != 0 {
+		q = q.Where("orders.user_id = ?", f.UserID)
+	}
+	if f.Status != "" {
+		q = q.Where("orders.status = ?", f.Status)
+	}
+	if f.CreatedFrom != nil {
+		q = q.Where("orders.created_at >= ?", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		q = q.Where("orders.created_at < ?", *f.CreatedTo)
+	}
+	if f.Currency != "" {
+		q = q.Where("orders.currency = ?", f.Currency)
+	}
+	if f.MinTotal != nil {
+		q = q.Where("orders.total_minor >= ?", *f.MinTotal)
+	}
+	if f.MaxTotal != nil {
+		q = q.Where("orders.total_minor <= ?", *f.MaxTotal)
+	}
+	if f.MinPrice != nil || f.MaxPrice != nil || f.Product != "" || f.ProductID != 0 {
+		// все условия на позицию — к одной и той же позиции
+		item := r.db.Table("order_items oi").Select("1").Where("oi.order_id = orders.id")
+		if f.MinPrice != nil {
+			item = item.Where("oi.price_minor >= ?", *f.MinPrice)
+		}
+		if f.MaxPrice != nil {
+			item = item.Where("oi.price_minor <= ?", *f.MaxPrice)
+		}
+		if f.Product != "" {
+			item = item.Where("oi.product ILIKE ?", "%"+escapeLike(f.Product)+"%")
+		}
+		if f.ProductID != 0 {
+			item = item.Where("oi.product_id = ?", f.ProductID)
+		}
+		q = q.Where("EXISTS (?)", item)
+	}
+	if f.MinQuantity != nil || f.MaxQuantity != nil {
+		const quantity = "(SELECT COALESCE(SUM(oi.quantity), 0) FROM order_items oi WHERE oi.order_id = orders.id)"
+		if f.MinQuantity != nil {
+			q = q.Where(quantity+" >= ?", *f.MinQuantity)
+		}
+		if f.MaxQuantity != nil {
+			q = q.Where(quantity+" <= ?", *f.MaxQuantity)
+		}
+	}
+	return q
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// GetByID возвращает заказ с позициями по ID.
+func (r *OrderRepo) GetByID(ctx context.Context, id uint) (*Order, error) {
+	var o Order
+	err := r.db.WithContext(ctx).
+		Preload("Items", withItemOrder).
+		Preload("TaxLines", withItemOrder).
+		First(&o, id).Error
+	return &o, err
+}
+
+// Lock блокирует строку заказа (FOR UPDATE) до конца транзакции,
+// чтобы параллельные изменения одного заказа выполнялись по очереди.
+// Вызывается внутри транзакции.
+func (r *OrderRepo) Lock(ctx context.Context, id uint) error {
+	var o Order
+	return r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		First(&o, id).Error
+}
+
+// Update заменяет позиции и строки налога заказа и пересчитанные суммы — в одной транзакции.
+// Возвращает false, если заказ уже не в статусе status (в том числе после параллельного запроса).
+func (r *OrderRepo) Update(ctx context.Context, o *Order, status string) (bool, error) {
+	changed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Order{}).
+			Where("id = ? AND status = ?", o.ID, status).
+			Updates(map[string]interface{}{
+				"currency":       o.Currency,
+				"subtotal_minor": o.SubtotalMinor,
+				"discount_minor": o.DiscountMinor,
+				"tax_minor":      o.TaxMinor,
+				"shipping_minor": o.ShippingMinor,
+				"total_minor":    o.TotalMinor,
+			})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		changed = true
+		if err := tx.Where("order_id = ?", o.ID).Delete(&models.OrderItem{}).Error; err != nil {
+			return err
+		}
+		for i := range o.Items {
+			o.Items[i].ID = 0
+			o.Items[i].OrderID = o.ID
+		}
+		if err := tx.Create(&o.Items).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("order_id = ?", o.ID).Delete(&models.OrderTaxLine{}).Error; err != nil {
+			return err
+		}
+		return createTaxLines(tx, o)
+	})
+	return changed, err
+}
+
+// Delete удаляет заказ вместе с позициями, строками налога и историей статусов;
+// применение промокода к заказу отменяется.
+// Возвращает gorm.ErrRecordNotFound, если заказа нет.
+func (r *OrderRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := releaseCoupon(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Where("order_id = ?", id).Delete(&models.OrderStatusChange{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("order_id = ?", id).Delete(&models.OrderItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("order_id = ?", id).Delete(&models.OrderTaxLine{}).Error; err != nil {
+			return err
+		}
+		res := tx.Delete(&Order{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// Restock возвращает на склад товары позиций заказа.
+func (r *OrderRepo) Restock(ctx context.Context, orderID uint) error {
+	return restock(r.db.WithContext(ctx), orderID)
+}
+
+// ChangeStatus переводит заказ из статуса change.FromStatus в change.ToStatus
+// и записывает переход в историю — в одной транзакции. При отмене заказа
+// списанные остатки возвращаются на склад, а применение промокода отменяется. Возвращает false,
+// если статус заказа уже изменился (в том числе параллельным запросом).
+func (r *OrderRepo) ChangeStatus(ctx context.Context, change *models.OrderStatusChange) (bool, error) {
+	changed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Order{}).
+			Where("id = ? AND status = ?", change.OrderID, change.FromStatus).
+			Update("status", change.ToStatus)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		changed = true
+		if change.ToStatus == models.OrderStatusCancelled {
+			if err := restock(tx, change.OrderID); err != nil {
+				return err
+			}
+			if err := releaseCoupon(tx, change.OrderID); err != nil {
+				return err
+			}
+		}
+		return tx.Create(change).Error
+	})
+	return changed, err
+}
+
+// restock возвращает на склад товары позиций заказа.
+func restock(tx *gorm.DB, orderID uint) error {
+	return tx.Exec(`UPDATE products p SET stock = p.stock + i.quantity
+		FROM (SELECT product_id, SUM(quantity) AS quantity FROM order_items
+		      WHERE order_id = ? AND product_id IS NOT NULL GROUP BY product_id) i
+		WHERE p.id = i.product_id`, orderID).Error
+}
+
+// releaseCoupon отменяет применение промокода к заказу: удаляет запись
+// и возвращает использование в общий лимит промокода.
+func releaseCoupon(tx *gorm.DB, orderID uint) error {
+	var released []models.CouponRedemption
+	err := tx.Clauses(clause.Returning{Columns: []clause.Column{{Name: "coupon_id"}}}).
+		Where("order_id = ?", orderID).
+		Delete(&released).Error
+	if err != nil {
+		return err
+	}
+	for _, red := range released {
+		err := tx.Model(&models.Coupon{}).
+			Where("id = ?", red.CouponID).
+			Update("redemptions", gorm.Expr("redemptions - 1")).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListStatusHistory возвращает историю статусов заказа в хронологическом порядке.
+func (r *OrderRepo) ListStatusHistory(ctx context.Context, orderID uint) ([]models.OrderStatusChange, error) {
+	var list []models.OrderStatusChange
+	err := r.db.WithContext(ctx).
+		Where("order_id = ?", orderID).
+		Order("id ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// withItemOrder возвращает позиции (и строки налога) в порядке добавления.
+func withItemOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("id ASC")
 }
 
 func (r *OrderRepo) GetDB() *gorm.DB {
@@ -0,0 +1,116 @@
+// payment_repo.go
+// Этот файл отвечает за взаимодействие с таблицами платежей и уведомлений
+// платёжного провайдера в базе данных.
+
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"kvant_task/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// activePaymentStatuses — статусы действующего платежа.
+var activePaymentStatuses = []string{models.PaymentStatusPending, models.PaymentStatusAuthorized, models.PaymentStatusCaptured}
+
+// PaymentRepo предоставляет операции с платежами.
+type PaymentRepo struct {
+	db *gorm.DB
+}
+
+// NewPaymentRepo создаёт новый PaymentRepo.
+func NewPaymentRepo(db *gorm.DB) *PaymentRepo {
+	return &PaymentRepo{db: db}
+}
+
+// Create сохраняет платёж.
+func (r *PaymentRepo) Create(ctx context.Context, p *models.Payment) error {
+	return r.db.WithContext(ctx).Create(p).Error
+}
+
+// GetByID возвращает платёж по ID.
+func (r *PaymentRepo) GetByID(ctx context.Context, id uint) (*models.Payment, error) {
+	var p models.Payment
+	err := r.db.WithContext(ctx).First(&p, id).Error
+	return &p, err
+}
+
+// GetByProviderRef возвращает платёж по идентификатору у провайдера.
+func (r *PaymentRepo) GetByProviderRef(ctx context.Context, provider, ref string) (*models.Payment, error) {
+	var p models.Payment
+	err := r.db.WithContext(ctx).
+		Where("provider = ? AND provider_ref = ?", provider, ref).
+		First(&p).Error
+	return &p, err
+}
+
+// ListByOrder возвращает платежи заказа в порядке создания.
+func (r *PaymentRepo) ListByOrder(ctx context.Context, orderID uint) ([]models.Payment, error) {
+	var list []models.Payment
+	err := r.db.WithContext(ctx).
+		Where("order_id = ?", orderID).
+		Order("id ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// HasActive сообщает, есть ли у заказа действующий платёж (не failed и не refunded).
+func (r *PaymentRepo) HasActive(ctx context.Context, orderID uint) (bool, error) {
+	var n int64
+	err := r.db.WithContext(ctx).
+		Model(&models.Payment{}).
+		Where("order_id = ? AND status IN ?", orderID, activePaymentStatuses).
+		Count(&n).Error
+	return n > 0, err
+}
+
+// ListStale возвращает платежи, которые ждут провайдера (pending или authorized)
+// и не менялись с момента before.
+func (r *PaymentRepo) ListStale(ctx context.Context, before time.Time) ([]models.Payment, error) {
+	var list []models.Payment
+	err := r.db.WithContext(ctx).
+		Where("status IN ? AND updated_at < ?", []string{models.PaymentStatusPending, models.PaymentStatusAuthorized}, before).
+		Order("id ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// ListCapturedForCancelled возвращает списанные платежи отменённых заказов —
+// возвраты, которые ещё не выполнены.
+func (r *PaymentRepo) ListCapturedForCancelled(ctx context.Context) ([]models.Payment, error) {
+	var list []models.Payment
+	err := r.db.WithContext(ctx).
+		Joins("JOIN orders ON orders.id = payments.order_id").
+		Where("payments.status = ? AND orders.status = ?", models.PaymentStatusCaptured, models.OrderStatusCancelled).
+		Order("payments.id ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// UpdateStatus записывает статус, идентификатор у провайдера и причину отказа платежа p,
+// только если платёж ещё в одном из статусов from. Возвращает false, если статус
+// уже изменился (в том числе параллельным запросом или уведомлением).
+func (r *PaymentRepo) UpdateStatus(ctx context.Context, p *models.Payment, from ...string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&models.Payment{}).
+		Where("id = ? AND status IN ?", p.ID, from).
+		Updates(map[string]interface{}{
+			"status":         p.Status,
+			"provider_ref":   p.ProviderRef,
+			"failure_reason": p.FailureReason,
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+// RecordEvent сохраняет уведомление провайдера. Возвращает false, если уведомление
+// с тем же ID уже обработано.
+func (r *PaymentRepo) RecordEvent(ctx context.Context, e *models.PaymentEvent) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(e)
+	return res.RowsAffected > 0, res.Error
+}
@@ -0,0 +1,115 @@
+// product_repo.go
+// Этот файл отвечает за взаимодействие с таблицей товаров в базе данных.
+// Реализует CRUD каталога и блокировку товаров при оформлении заказа.
+
+package repositories
+
+import (
+	"context"
+
+	"kvant_task/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProductRepo отвечает за работу с таблицей products.
+type ProductRepo struct {
+	db *gorm.DB
+}
+
+// NewProductRepo создаёт новый ProductRepo.
+func NewProductRepo(db *gorm.DB) *ProductRepo {
+	return &ProductRepo{db: db}
+}
+
+// Create сохраняет новый товар.
+func (r *ProductRepo) Create(ctx context.Context, p *models.Product) error {
+	return r.db.WithContext(ctx).Create(p).Error
+}
+
+// GetByID возвращает товар по ID.
+func (r *ProductRepo) GetByID(ctx context.Context, id uint) (*models.Product, error) {
+	var p models.Product
+	err := r.db.WithContext(ctx).First(&p, id).Error
+	return &p, err
+}
+
+// GetBySKU возвращает товар по артикулу.
+func (r *ProductRepo) GetBySKU(ctx context.Context, sku string) (*models.Product, error) {
+	var p models.Product
+	err := r.db.WithContext(ctx).Where("sku = ?", sku).First(&p).Error
+	return &p, err
+}
+
+// List возвращает страницу каталога, упорядоченную по ID, и общее число товаров.
+func (r *ProductRepo) List(ctx context.Context, page, limit int) ([]models.Product, int64, error) {
+	var (
+		list  []models.Product
+		total int64
+	)
+	q := r.db.WithContext(ctx).Model(&models.Product{})
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := q.Order("id ASC").Offset((page - 1) * limit).Limit(limit).Find(&list).Error
+	return list, total, err
+}
+
+// LockByID возвращает товар по ID и блокирует его строку (FOR UPDATE) до конца транзакции.
+// Вызывается внутри транзакции.
+func (r *ProductRepo) LockByID(ctx context.Context, id uint) (*models.Product, error) {
+	var p models.Product
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&p, id).Error
+	return &p, err
+}
+
+// Update сохраняет все поля товара. Чтобы не затереть остаток, списанный
+// параллельным заказом, товар должен быть прочитан через LockByID в той же транзакции.
+func (r *ProductRepo) Update(ctx context.Context, p *models.Product) error {
+	return r.db.WithContext(ctx).Save(p).Error
+}
+
+// Delete удаляет товар. Позиции заказов сохраняют его артикул, имя и цену.
+// Возвращает gorm.ErrRecordNotFound, если товара нет.
+func (r *ProductRepo) Delete(ctx context.Context, id uint) error {
+	res := r.db.WithContext(ctx).Delete(&models.Product{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// LockForOrder выбирает товары по ID и артикулам с блокировкой строк (FOR UPDATE)
+// до конца транзакции. Строки блокируются в порядке ID, чтобы параллельные заказы
+// с общими товарами не взаимоблокировались. Вызывается внутри транзакции.
+func (r *ProductRepo) LockForOrder(ctx context.Context, ids []uint, skus []string) ([]models.Product, error) {
+	var list []models.Product
+	if len(ids) == 0 && len(skus) == 0 {
+		return list, nil
+	}
+	q := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"})
+	switch {
+	case len(ids) > 0 && len(skus) > 0:
+		q = q.Where("id IN ? OR sku IN ?", ids, skus)
+	case len(ids) > 0:
+		q = q.Where("id IN ?", ids)
+	default:
+		q = q.Where("sku IN ?", skus)
+	}
+	err := q.Order("id ASC").Find(&list).Error
+	return list, err
+}
+
+// DecrementStock списывает quantity единиц товара, только если их хватает.
+// Возвращает false, если остатка недостаточно.
+func (r *ProductRepo) DecrementStock(ctx context.Context, id uint, quantity int) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&models.Product{}).
+		Where("id = ? AND stock >= ?", id, quantity).
+		Update("stock", gorm.Expr("stock - ?", quantity))
+	return res.RowsAffected > 0, res.Error
+}
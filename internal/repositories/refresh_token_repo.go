@@ -0,0 +1,82 @@
+// refresh_token_repo.go
+// Этот файл отвечает за взаимодействие с таблицей refresh-токенов в базе данных.
+// Реализует методы для выдачи, ротации и отзыва токенов.
+
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"kvant_task/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// RefreshTokenRepo отвечает за работу с таблицей refresh_tokens.
+type RefreshTokenRepo struct {
+	db *gorm.DB
+}
+
+// NewRefreshTokenRepo создаёт новый RefreshTokenRepo.
+func NewRefreshTokenRepo(db *gorm.DB) *RefreshTokenRepo {
+	return &RefreshTokenRepo{db: db}
+}
+
+// Create сохраняет новый refresh-токен.
+func (r *RefreshTokenRepo) Create(ctx context.Context, t *models.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+// GetByHash возвращает токен по его хэшу.
+func (r *RefreshTokenRepo) GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	var t models.RefreshToken
+	err := r.db.WithContext(ctx).
+		Where("token_hash = ?", hash).
+		First(&t).Error
+	return &t, err
+}
+
+// MarkRotated помечает токен обменянным. Возвращает false, если токен уже
+// был обменян или отозван другим запросом — это признак повторного использования.
+func (r *RefreshTokenRepo) MarkRotated(ctx context.Context, id uint, at time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&models.RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", id).
+		Update("rotated_at", at)
+	return res.RowsAffected == 1, res.Error
+}
+
+// RevokeFamily отзывает все ещё не отозванные токены семейства.
+func (r *RefreshTokenRepo) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+}
+
+// ListLiveAccess возвращает записи пользователя, у которых access-токен ещё не истёк.
+func (r *RefreshTokenRepo) ListLiveAccess(ctx context.Context, userID uint, now time.Time) ([]models.RefreshToken, error) {
+	var list []models.RefreshToken
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND access_expires_at > ? AND access_jti <> ''", userID, now).
+		Find(&list).Error
+	return list, err
+}
+
+// ListLiveAccessByFamily возвращает записи семейства, у которых access-токен ещё не истёк.
+func (r *RefreshTokenRepo) ListLiveAccessByFamily(ctx context.Context, familyID string, now time.Time) ([]models.RefreshToken, error) {
+	var list []models.RefreshToken
+	err := r.db.WithContext(ctx).
+		Where("family_id = ? AND access_expires_at > ? AND access_jti <> ''", familyID, now).
+		Find(&list).Error
+	return list, err
+}
+
+// RevokeUser отзывает все ещё не отозванные refresh-токены пользователя.
+func (r *RefreshTokenRepo) RevokeUser(ctx context.Context, userID uint, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
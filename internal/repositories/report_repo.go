@@ -0,0 +1,129 @@
+// report_repo.go
+// Этот файл содержит агрегирующие запросы отчётов по заказам.
+// Суммы считаются в Postgres по каждой валюте отдельно, в минимальных единицах (bigint).
+
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Периоды разбивки отчёта о выручке — единицы date_trunc.
+const (
+	ReportPeriodDay   = "day"
+	ReportPeriodWeek  = "week"
+	ReportPeriodMonth = "month"
+)
+
+// reportPeriods — допустимые периоды; значение подставляется в SQL только из этого набора.
+var reportPeriods = map[string]bool{
+	ReportPeriodDay:   true,
+	ReportPeriodWeek:  true,
+	ReportPeriodMonth: true,
+}
+
+// SpendingRow — траты пользователя в одной валюте.
+type SpendingRow struct {
+	Currency     string
+	OrderCount   int64
+	TotalMinor   int64
+	AverageMinor int64
+	FirstOrderAt time.Time
+	LastOrderAt  time.Time
+}
+
+// RevenueFilter условия отчёта о выручке.
+type RevenueFilter struct {
+	// Statuses — статусы заказов, входящих в выручку
+	Statuses []string
+	// From и To — период создания заказов, [From, To)
+	From time.Time
+	To   time.Time
+	// Period — ReportPeriod*
+	Period string
+	// Currency — только заказы в этой валюте; пусто — все валюты
+	Currency string
+	// ByProduct — разбить выручку по товарам
+	ByProduct bool
+}
+
+// RevenueRow — выручка за один период в одной валюте (и по одному товару при ByProduct).
+type RevenueRow struct {
+	// Start — начало периода, UTC
+	Start      time.Time
+	Currency   string
+	ProductID  *uint
+	SKU        string
+	Product    string
+	OrderCount int64
+	Quantity   int64
+	// RevenueMinor — итого заказов, а при ByProduct — стоимость позиций товара
+	RevenueMinor int64
+}
+
+// ReportRepo выполняет запросы отчётов.
+type ReportRepo struct {
+	db *gorm.DB
+}
+
+// NewReportRepo создаёт новый ReportRepo.
+func NewReportRepo(db *gorm.DB) *ReportRepo {
+	return &ReportRepo{db: db}
+}
+
+// UserSpending возвращает траты пользователя по валютам: число заказов, сумму,
+// средний заказ (с округлением) и даты первого и последнего заказа.
+func (r *ReportRepo) UserSpending(ctx context.Context, userID uint, statuses []string) ([]SpendingRow, error) {
+	var rows []SpendingRow
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT currency,
+		       COUNT(*) AS order_count,
+		       SUM(total_minor)::bigint AS total_minor,
+		       ROUND(AVG(total_minor))::bigint AS average_minor,
+		       MIN(created_at) AS first_order_at,
+		       MAX(created_at) AS last_order_at
+		FROM orders
+		WHERE user_id = ? AND status IN ?
+		GROUP BY currency
+		ORDER BY currency`, userID, statuses).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// Revenue возвращает выручку по периодам, отсортированную по началу периода и валюте;
+// при разбивке по товарам — внутри периода по убыванию выручки. Периоды без заказов не возвращаются.
+func (r *ReportRepo) Revenue(ctx context.Context, f *RevenueFilter) ([]RevenueRow, error) {
+	if !reportPeriods[f.Period] {
+		return nil, fmt.Errorf("неизвестный период отчёта: %q", f.Period)
+	}
+	bucket := fmt.Sprintf("date_trunc('%s', o.created_at AT TIME ZONE 'UTC')", f.Period)
+	q := r.db.WithContext(ctx).
+		Table("orders o").
+		Where("o.status IN ? AND o.created_at >= ? AND o.created_at < ?", f.Statuses, f.From, f.To)
+	if f.Currency != "" {
+		q = q.Where("o.currency = ?", f.Currency)
+	}
+	if f.ByProduct {
+		q = q.Select(bucket + ` AS start, o.currency,
+			oi.product_id, oi.sku, MAX(oi.product) AS product,
+			COUNT(DISTINCT o.id) AS order_count,
+			SUM(oi.quantity)::bigint AS quantity,
+			SUM(oi.price_minor * oi.quantity)::bigint AS revenue_minor`).
+			Joins("JOIN order_items oi ON oi.order_id = o.id").
+			Group("1, 2, 3, 4").
+			Order("1, 2, revenue_minor DESC, 4")
+	} else {
+		q = q.Select(bucket + ` AS start, o.currency,
+			COUNT(*) AS order_count,
+			SUM(o.total_minor)::bigint AS revenue_minor`).
+			Group("1, 2").
+			Order("1, 2")
+	}
+	var rows []RevenueRow
+	err := q.Scan(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,94 @@
+// session_repo.go
+// Этот файл отвечает за взаимодействие с таблицей сессий в базе данных.
+// Реализует методы для создания, просмотра, отметки активности и отзыва сессий.
+
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"kvant_task/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// SessionRepo отвечает за работу с таблицей sessions.
+type SessionRepo struct {
+	db *gorm.DB
+}
+
+// NewSessionRepo создаёт новый SessionRepo.
+func NewSessionRepo(db *gorm.DB) *SessionRepo {
+	return &SessionRepo{db: db}
+}
+
+// Create сохраняет новую сессию.
+func (r *SessionRepo) Create(ctx context.Context, s *models.Session) error {
+	return r.db.WithContext(ctx).Create(s).Error
+}
+
+// Extend записывает access-токен, выпущенный в сессии, и продлевает её до expiresAt.
+// Отозванная сессия не меняется.
+func (r *SessionRepo) Extend(ctx context.Context, id uint, accessJTI string, at, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"access_jti":   accessJTI,
+			"last_seen_at": at,
+			"expires_at":   expiresAt,
+		}).Error
+}
+
+// GetByFamily возвращает сессию по семейству refresh-токенов.
+func (r *SessionRepo) GetByFamily(ctx context.Context, familyID string) (*models.Session, error) {
+	var s models.Session
+	err := r.db.WithContext(ctx).
+		Where("family_id = ?", familyID).
+		First(&s).Error
+	return &s, err
+}
+
+// GetForUser возвращает сессию пользователя по ID.
+func (r *SessionRepo) GetForUser(ctx context.Context, userID, id uint) (*models.Session, error) {
+	var s models.Session
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&s).Error
+	return &s, err
+}
+
+// ListActive возвращает неотозванные и неистёкшие сессии пользователя, последние активные первыми.
+func (r *SessionRepo) ListActive(ctx context.Context, userID uint, now time.Time) ([]models.Session, error) {
+	var list []models.Session
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_seen_at DESC").
+		Find(&list).Error
+	return list, err
+}
+
+// Touch обновляет время активности, если прошлое обновление было раньше since.
+func (r *SessionRepo) Touch(ctx context.Context, id uint, at, since time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("id = ? AND last_seen_at < ?", id, since).
+		Update("last_seen_at", at).Error
+}
+
+// RevokeFamily отзывает сессию семейства refresh-токенов.
+func (r *SessionRepo) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+}
+
+// RevokeUser отзывает все сессии пользователя.
+func (r *SessionRepo) RevokeUser(ctx context.Context, userID uint, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
@@ -49,10 +49,48 @@ func (r *UserRepo) Update(ctx context.Context, u *models.User) error {
 	return r.db.WithContext(ctx).Save(u).Error
 }
 
+// ReplacePasswordHash заменяет хэш пароля, только если пароль не сменили с момента чтения.
+func (r *UserRepo) ReplacePasswordHash(ctx context.Context, id uint, old, hash string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ? AND password_hash = ?", id, old).
+		Update("password_hash", hash).Error
+}
+
+// AdvanceTOTPStep запоминает использованный шаг TOTP, только если он новее последнего.
+// Возвращает false, если код этого шага уже был принят (в том числе параллельным запросом).
+func (r *UserRepo) AdvanceTOTPStep(ctx context.Context, id uint, step int64) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	return res.RowsAffected > 0, res.Error
+}
+
+// ReplaceRecoveryCodes заменяет коды восстановления, только если они не изменились с момента чтения.
+// Возвращает false, если код уже израсходован параллельным запросом.
+func (r *UserRepo) ReplaceRecoveryCodes(ctx context.Context, id uint, old, codes string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ? AND totp_recovery_codes = ?", id, old).
+		Update("totp_recovery_codes", codes)
+	return res.RowsAffected > 0, res.Error
+}
+
 func (r *UserRepo) Delete(ctx context.Context, id uint) error {
 	return r.db.WithContext(ctx).Delete(&models.User{}, id).Error
 }
 
+// ListByIDs возвращает пользователей с указанными ID; отсутствующие пропускаются.
+func (r *UserRepo) ListByIDs(ctx context.Context, ids []uint) ([]models.User, error) {
+	var users []models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
 // List возвращает срез пользователей с фильтрацией по возрасту и пагинацией.
 func (r *UserRepo) List(ctx context.Context, minAge, maxAge string, page, limit int) ([]models.User, error) {
 	q := r.db.WithContext(ctx).Model(&models.User{})
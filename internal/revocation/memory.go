@@ -0,0 +1,52 @@
+// memory.go
+// Этот файл содержит in-memory реализацию хранилища отозванных токенов.
+// Подходит для одного экземпляра приложения и тестов: данные теряются при рестарте.
+
+package revocation
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore — потокобезопасное хранилище отозванных токенов в памяти.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	revoked map[string]time.Time
+}
+
+// NewMemoryStore создаёт пустой MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{revoked: make(map[string]time.Time)}
+}
+
+// Revoke отзывает токен до expiresAt.
+func (s *MemoryStore) Revoke(_ context.Context, jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revoked[jti] = expiresAt
+	return nil
+}
+
+// IsRevoked проверяет, отозван ли токен.
+func (s *MemoryStore) IsRevoked(_ context.Context, jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.revoked[jti]
+	return ok, nil
+}
+
+// Purge удаляет истёкшие записи.
+func (s *MemoryStore) Purge(_ context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for jti, exp := range s.revoked {
+		if !exp.After(now) {
+			delete(s.revoked, jti)
+			n++
+		}
+	}
+	return n, nil
+}
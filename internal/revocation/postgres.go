@@ -0,0 +1,50 @@
+// postgres.go
+// Этот файл содержит реализацию хранилища отозванных токенов на PostgreSQL.
+// Отзыв виден всем репликам приложения и переживает рестарт.
+
+package revocation
+
+import (
+	"context"
+	"time"
+
+	"kvant_task/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresStore — хранилище отозванных токенов в таблице revoked_tokens.
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+// NewPostgresStore создаёт PostgresStore.
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Revoke отзывает токен до expiresAt. Повторный отзыв не является ошибкой.
+func (s *PostgresStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	return s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+}
+
+// IsRevoked проверяет, отозван ли токен.
+func (s *PostgresStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var n int64
+	err := s.db.WithContext(ctx).
+		Model(&models.RevokedToken{}).
+		Where("jti = ?", jti).
+		Count(&n).Error
+	return n > 0, err
+}
+
+// Purge удаляет истёкшие записи.
+func (s *PostgresStore) Purge(ctx context.Context, now time.Time) (int64, error) {
+	res := s.db.WithContext(ctx).
+		Where("expires_at <= ?", now).
+		Delete(&models.RevokedToken{})
+	return res.RowsAffected, res.Error
+}
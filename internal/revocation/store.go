@@ -0,0 +1,42 @@
+// store.go
+// Этот файл содержит интерфейс хранилища отозванных токенов.
+// Токены идентифицируются по claim'у jti и хранятся до истечения своего срока.
+
+package revocation
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Store — хранилище отозванных токенов.
+type Store interface {
+	// Revoke отзывает токен с указанным jti до момента expiresAt.
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	// IsRevoked проверяет, отозван ли токен.
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	// Purge удаляет записи, срок которых истёк к моменту now, и возвращает их число.
+	Purge(ctx context.Context, now time.Time) (int64, error)
+}
+
+// RunGC периодически удаляет из хранилища истёкшие записи, пока не отменён ctx.
+func RunGC(ctx context.Context, s Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			n, err := s.Purge(ctx, now)
+			if err != nil {
+				log.Printf("[revocation] ошибка очистки: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("[revocation] удалено истёкших записей: %d", n)
+			}
+		}
+	}
+}
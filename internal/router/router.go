@@ -7,7 +7,15 @@ package router
 import (
 	"kvant_task/internal/config"
 	"kvant_task/internal/handlers"
+	"kvant_task/internal/idempotency"
+	"kvant_task/internal/lockout"
 	"kvant_task/internal/middleware"
+	"kvant_task/internal/models"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/password"
+	"kvant_task/internal/payment"
+	"kvant_task/internal/pricing"
+	"kvant_task/internal/services"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -16,33 +24,122 @@ import (
 )
 
 // New создаёт Gin-Engine и регистрирует маршруты.
-func New(db *gorm.DB, cfg *config.Config) *gin.Engine {
+// TokenService, транспорт уведомлений, защита от перебора паролей, политика паролей
+// и хранилище ключей идемпотентности общие для middleware и хендлеров; расчёт налога
+// и доставки используется при создании и изменении заказов, платёжный провайдер — при их оплате.
+func New(db *gorm.DB, cfg *config.Config, tokens *services.TokenService, notifier notify.Notifier, attempts *lockout.Guard, policy *password.Policy, idem idempotency.Store, tax pricing.TaxCalculator, shipping pricing.ShippingCalculator, payments payment.PaymentProvider) *gin.Engine {
 	r := gin.Default()
+	// IP клиента (блокировка входа, ключи идемпотентности) берётся из X-Forwarded-For
+	// только от доверенных прокси, иначе заголовок подделывается; адреса проверены в config
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		panic(err)
+	}
 
 	// Swagger UI
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// Хендлеры
-	userH := handlers.NewUserHandler(db, cfg.JWTSecret)
-	orderH := handlers.NewOrderHandler(db)
+	userH := handlers.NewUserHandler(db, cfg, tokens, notifier, attempts, policy)
+	orderH := handlers.NewOrderHandler(db, cfg, tax, shipping, payments)
+	productH := handlers.NewProductHandler(db, cfg)
+	couponH := handlers.NewCouponHandler(db, cfg)
+	paymentH := handlers.NewPaymentHandler(db, cfg, payments)
+	reportH := handlers.NewReportHandler(db)
+	jwksH := handlers.NewJWKSHandler(tokens)
+	apiKeys := services.NewAPIKeyService(db)
+	apiKeyH := handlers.NewAPIKeyHandler(apiKeys)
+	sessions := services.NewSessionTracker(db, cfg.Auth.SessionTouchInterval)
+
+	// Повтор запроса с тем же Idempotency-Key получает сохранённый ответ
+	idempotent := middleware.Idempotency(idem, cfg.Idempotency.TTL)
 
 	// Публичные
-	r.POST("/users", userH.CreateUser)
+	r.POST("/users", idempotent, userH.CreateUser)
 	r.POST("/auth/login", userH.Login) // <- изменённый маршрут
+	r.POST("/auth/login/2fa", userH.LoginTwoFactor)
+	r.POST("/auth/refresh", userH.Refresh)
+	r.POST("/auth/password-reset/request", userH.RequestPasswordReset)
+	r.POST("/auth/password-reset/confirm", userH.ConfirmPasswordReset)
+	r.POST("/auth/verify-email", userH.VerifyEmail)
+	r.POST("/auth/verify-email/resend", userH.RequestVerification)
+	r.GET("/.well-known/jwks.json", jwksH.Keys)
+	// Уведомления платёжного провайдера; подлинность проверяется по подписи
+	r.POST("/payments/webhook", paymentH.Webhook)
 
-	// Защищённые — все ниже требуют Bearer токен
+	// Защищённые — все ниже требуют Bearer токен или API-ключ
 	auth := r.Group("/")
-	auth.Use(middleware.Auth(cfg.JWTSecret))
+	auth.Use(middleware.Auth(tokens, apiKeys, sessions))
+
+	// Права: пользователь работает только со своим профилем и заказами, админ — со всеми
+	adminOnly := middleware.RequireRole(models.RoleAdmin)
+	selfOrAdmin := middleware.RequireSelfOrRole("id", models.RoleAdmin)
+
+	// Права API-ключей; запросы с JWT их не проверяют
+	usersRead := middleware.RequireScope(models.ScopeUsersRead)
+	usersWrite := middleware.RequireScope(models.ScopeUsersWrite)
+	ordersRead := middleware.RequireScope(models.ScopeOrdersRead)
+	ordersWrite := middleware.RequireScope(models.ScopeOrdersWrite)
+	productsWrite := middleware.RequireScope(models.ScopeProductsWrite)
+	couponsRead := middleware.RequireScope(models.ScopeCouponsRead)
+	couponsWrite := middleware.RequireScope(models.ScopeCouponsWrite)
+
+	// Сессия и текущий пользователь — только с JWT
+	session := auth.Group("/", middleware.RequireToken())
+	session.POST("/auth/logout", userH.Logout)
+	session.POST("/users/me/password", userH.ChangePassword)
+	session.POST("/users/me/email/verification", userH.ResendVerification)
+	session.POST("/users/me/2fa", userH.EnrollTwoFactor)
+	session.POST("/users/me/2fa/verify", userH.ConfirmTwoFactor)
+	session.POST("/users/me/2fa/disable", userH.DisableTwoFactor)
+	session.POST("/users/me/2fa/recovery-codes", userH.RegenerateRecoveryCodes)
+
+	// Сессии
+	session.GET("/users/me/sessions", userH.ListSessions)
+	session.DELETE("/users/me/sessions", userH.RevokeAllSessions)
+	session.DELETE("/users/me/sessions/:sessionId", userH.RevokeSession)
+
+	// API-ключи
+	session.POST("/users/me/api-keys", apiKeyH.Create)
+	session.GET("/users/me/api-keys", apiKeyH.List)
+	session.DELETE("/users/me/api-keys/:keyId", apiKeyH.Revoke)
+	session.POST("/users/me/api-keys/:keyId/rotate", apiKeyH.Rotate)
 
 	// Пользователи
-	auth.GET("/users", userH.List)
-	auth.GET("/users/:id", userH.GetByID)
-	auth.PUT("/users/:id", userH.Update)
-	auth.DELETE("/users/:id", userH.Delete)
+	auth.GET("/users", adminOnly, usersRead, userH.List)
+	auth.GET("/users/:id", selfOrAdmin, usersRead, userH.GetByID)
+	auth.PUT("/users/:id", selfOrAdmin, usersWrite, userH.Update)
+	auth.DELETE("/users/:id", selfOrAdmin, usersWrite, userH.Delete)
+	auth.PUT("/users/:id/role", adminOnly, usersWrite, userH.SetRole)
+	auth.POST("/users/:id/unlock", adminOnly, usersWrite, userH.Unlock)
+
+	// Каталог: смотреть могут все, менять — администратор
+	auth.GET("/products", productH.List)
+	auth.GET("/products/:productId", productH.GetByID)
+	auth.POST("/products", adminOnly, productsWrite, idempotent, productH.Create)
+	auth.PUT("/products/:productId", adminOnly, productsWrite, productH.Update)
+	auth.DELETE("/products/:productId", adminOnly, productsWrite, productH.Delete)
+
+	// Промокоды — только администратор; применяются при создании заказа
+	auth.GET("/coupons", adminOnly, couponsRead, couponH.List)
+	auth.GET("/coupons/:couponId", adminOnly, couponsRead, couponH.GetByID)
+	auth.POST("/coupons", adminOnly, couponsWrite, idempotent, couponH.Create)
+	auth.DELETE("/coupons/:couponId", adminOnly, couponsWrite, couponH.Delete)
+
+	// Поиск заказов всех пользователей и отчёты
+	auth.GET("/orders", adminOnly, ordersRead, orderH.Search)
+	auth.GET("/reports/revenue", adminOnly, ordersRead, reportH.Revenue)
 
 	// Заказы вложенно
-	auth.POST("/users/:id/orders", orderH.CreateForUser)
-	auth.GET("/users/:id/orders", orderH.ListByUser)
+	auth.POST("/users/:id/orders", selfOrAdmin, ordersWrite, idempotent, orderH.CreateForUser)
+	auth.GET("/users/:id/orders", selfOrAdmin, ordersRead, orderH.ListByUser)
+	auth.GET("/users/:id/orders/summary", selfOrAdmin, ordersRead, reportH.UserSummary)
+	auth.GET("/users/:id/orders/:orderId", selfOrAdmin, ordersRead, orderH.Get)
+	auth.PATCH("/users/:id/orders/:orderId", selfOrAdmin, ordersWrite, idempotent, orderH.Update)
+	auth.DELETE("/users/:id/orders/:orderId", selfOrAdmin, ordersWrite, orderH.Delete)
+	auth.POST("/users/:id/orders/:orderId/transitions", selfOrAdmin, ordersWrite, idempotent, orderH.Transition)
+	auth.GET("/users/:id/orders/:orderId/transitions", selfOrAdmin, ordersRead, orderH.StatusHistory)
+	auth.POST("/users/:id/orders/:orderId/pay", selfOrAdmin, ordersWrite, idempotent, paymentH.Pay)
+	auth.GET("/users/:id/orders/:orderId/payments", selfOrAdmin, ordersRead, paymentH.ListByOrder)
 
 	return r
 }
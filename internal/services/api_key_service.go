@@ -0,0 +1,236 @@
+// api_key_service.go
+// Этот файл содержит бизнес-логику API-ключей.
+// Ключ имеет вид kv_<prefix>_<secret>: prefix хранится открыто и показывается в списках,
+// от всего ключа хранится только SHA-256 хэш.
+
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"kvant_task/internal/models"
+	"kvant_task/internal/repositories"
+	"kvant_task/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// apiKeyScheme — начало каждого ключа; помогает узнать ключ в логах и сканерах секретов
+	apiKeyScheme = "kv"
+	// apiKeyTouchInterval — как часто обновляется время последнего использования
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	// ErrInvalidAPIKey ошибка, если ключ не найден, отозван или не совпадает.
+	ErrInvalidAPIKey = errors.New("недействительный API-ключ")
+	// ErrAPIKeyRevoked ошибка, если ключ уже отозван.
+	ErrAPIKeyRevoked = errors.New("API-ключ отозван")
+)
+
+// CreateAPIKeyRequest данные для создания ключа
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=orders:read orders:write users:read users:write products:write coupons:read coupons:write"`
+}
+
+// APIKeyResponse данные ключа без секрета
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APIKeySecretResponse данные ключа вместе с самим ключом; ключ показывается один раз
+type APIKeySecretResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// APIKeyPrincipal — от чьего имени и с какими правами выполняется запрос по ключу.
+type APIKeyPrincipal struct {
+	KeyID  uint
+	UserID uint
+	Role   string
+	Scopes []string
+}
+
+// APIKeyService бизнес-логика API-ключей.
+type APIKeyService struct {
+	repo  *repositories.APIKeyRepo
+	users *repositories.UserRepo
+}
+
+// NewAPIKeyService создаёт APIKeyService.
+func NewAPIKeyService(db *gorm.DB) *APIKeyService {
+	return &APIKeyService{
+		repo:  repositories.NewAPIKeyRepo(db),
+		users: repositories.NewUserRepo(db),
+	}
+}
+
+func toAPIKeyResponse(k *models.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     strings.Fields(k.Scopes),
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
+
+// Create выпускает новый ключ пользователя.
+func (s *APIKeyService) Create(ctx context.Context, userID uint, req *CreateAPIKeyRequest) (*APIKeySecretResponse, error) {
+	log.Printf("Attempting to create API key %q for user ID: %d", req.Name, userID)
+	prefix, key, err := newAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	k := &models.APIKey{
+		UserID:  userID,
+		Name:    req.Name,
+		Prefix:  prefix,
+		KeyHash: utils.HashToken(key),
+		Scopes:  normalizeScopes(req.Scopes),
+	}
+	if err := s.repo.Create(ctx, k); err != nil {
+		log.Printf("Error creating API key: %v", err)
+		return nil, err
+	}
+	return &APIKeySecretResponse{APIKeyResponse: toAPIKeyResponse(k), Key: key}, nil
+}
+
+// List возвращает ключи пользователя без секретов.
+func (s *APIKeyService) List(ctx context.Context, userID uint) ([]APIKeyResponse, error) {
+	keys, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]APIKeyResponse, len(keys))
+	for i := range keys {
+		out[i] = toAPIKeyResponse(&keys[i])
+	}
+	return out, nil
+}
+
+// Revoke отзывает ключ пользователя. Возвращает ErrNotFound для чужого или несуществующего ключа.
+func (s *APIKeyService) Revoke(ctx context.Context, userID, id uint) error {
+	k, err := s.repo.GetForUser(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if k.RevokedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	k.RevokedAt = &now
+	log.Printf("Revoking API key %s of user ID: %d", k.Prefix, userID)
+	return s.repo.Update(ctx, k)
+}
+
+// Rotate выпускает новое значение ключа с теми же названием и правами.
+// Прежнее значение перестаёт действовать сразу.
+func (s *APIKeyService) Rotate(ctx context.Context, userID, id uint) (*APIKeySecretResponse, error) {
+	k, err := s.repo.GetForUser(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if k.RevokedAt != nil {
+		return nil, ErrAPIKeyRevoked
+	}
+	prefix, key, err := newAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	k.Prefix = prefix
+	k.KeyHash = utils.HashToken(key)
+	k.LastUsedAt = nil
+	if err := s.repo.Update(ctx, k); err != nil {
+		return nil, err
+	}
+	log.Printf("API key %d of user ID %d rotated", k.ID, userID)
+	return &APIKeySecretResponse{APIKeyResponse: toAPIKeyResponse(k), Key: key}, nil
+}
+
+// Authenticate проверяет ключ из заголовка и отмечает его использование.
+func (s *APIKeyService) Authenticate(ctx context.Context, raw string) (*APIKeyPrincipal, error) {
+	parts := strings.SplitN(raw, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyScheme {
+		return nil, ErrInvalidAPIKey
+	}
+	k, err := s.repo.GetByPrefix(ctx, parts[1])
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(k.KeyHash), []byte(utils.HashToken(raw))) != 1 || k.RevokedAt != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	u, err := s.users.GetByID(ctx, k.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if k.LastUsedAt == nil || k.LastUsedAt.Before(now.Add(-apiKeyTouchInterval)) {
+		if err := s.repo.TouchLastUsed(ctx, k.ID, now, now.Add(-apiKeyTouchInterval)); err != nil {
+			// время использования — справочная информация, запрос из-за него не отклоняем
+			log.Printf("Error updating API key last use: %v", err)
+		}
+	}
+	return &APIKeyPrincipal{
+		KeyID:  k.ID,
+		UserID: u.ID,
+		Role:   u.Role,
+		Scopes: strings.Fields(k.Scopes),
+	}, nil
+}
+
+// newAPIKey создаёт открытую часть и полный ключ.
+func newAPIKey() (prefix, key string, err error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	prefix = hex.EncodeToString(b)
+	secret, err := utils.RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	return prefix, apiKeyScheme + "_" + prefix + "_" + secret, nil
+}
+
+// normalizeScopes убирает повторы и упорядочивает права.
+func normalizeScopes(scopes []string) string {
+	set := make(map[string]struct{}, len(scopes))
+	out := make([]string, 0, len(scopes))
+	for _, sc := range scopes {
+		if _, ok := set[sc]; ok {
+			continue
+		}
+		set[sc] = struct{}{}
+		out = append(out, sc)
+	}
+	sort.Strings(out)
+	return strings.Join(out, " ")
+}
@@ -0,0 +1,331 @@
+// coupon_service.go
+// Этот файл содержит бизнес-логику промокодов: управление ими и применение к заказам.
+// Промокод применяется в транзакции создания заказа; одновременные применения
+// одного промокода выполняются по очереди, поэтому лимиты не превышаются.
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"kvant_task/internal/config"
+	"kvant_task/internal/models"
+	"kvant_task/internal/money"
+	"kvant_task/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrCouponNotFound ошибка, если промокода нет или он отключён.
+	ErrCouponNotFound = errors.New("промокод не найден")
+	// ErrCouponCodeTaken ошибка, если код промокода уже занят.
+	ErrCouponCodeTaken = errors.New("промокод с таким кодом уже существует")
+	// ErrInvalidCoupon ошибка, если условия создаваемого промокода некорректны.
+	ErrInvalidCoupon = errors.New("некорректные данные промокода")
+	// ErrCouponNotApplicable ошибка, если промокод не подходит к заказу: не действует сейчас,
+	// в другой валюте или сумма заказа меньше минимальной.
+	ErrCouponNotApplicable = errors.New("промокод не подходит к заказу")
+	// ErrCouponExhausted ошибка, если исчерпан общий лимит применений или лимит пользователя.
+	ErrCouponExhausted = errors.New("лимит применений промокода исчерпан")
+)
+
+// couponCodePattern — допустимые коды: латиница, цифры, "-" и "_".
+var couponCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,64}$`)
+
+// CreateCouponRequest данные для создания промокода.
+// Для percent задаётся percent, для fixed — amount. Суммы — десятичные строки в валюте
+// currency; без неё fixed-промокод и минимальная сумма — в DEFAULT_CURRENCY, а процентный
+// промокод без минимальной суммы действует для заказов в любой валюте.
+type CreateCouponRequest struct {
+	Code    string `json:"code" binding:"required,max=64" example:"SPRING10"`
+	Type    string `json:"type" binding:"required,oneof=percent fixed"`
+	Percent int    `json:"percent" binding:"omitempty,min=1,max=100" example:"10"`
+	Amount  string `json:"amount" example:"500.00"`
+	// Код валюты ISO 4217
+	Currency       string `json:"currency" binding:"omitempty,len=3" example:"RUB"`
+	MinOrderAmount string `json:"min_order_amount" example:"1000.00"`
+	// Период действия, RFC 3339
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
+	// Сколько раз промокод можно применить всего и одному пользователю
+	MaxRedemptions *int `json:"max_redemptions" binding:"omitempty,min=1"`
+	MaxPerUser     *int `json:"max_per_user" binding:"omitempty,min=1"`
+}
+
+// CouponResponse DTO промокода
+type CouponResponse struct {
+	ID             uint       `json:"id"`
+	Code           string     `json:"code"`
+	Type           string     `json:"type"`
+	Percent        int        `json:"percent,omitempty"`
+	Amount         string     `json:"amount,omitempty" example:"500.00"`
+	Currency       string     `json:"currency,omitempty" example:"RUB"`
+	MinOrderAmount string     `json:"min_order_amount,omitempty" example:"1000.00"`
+	ValidFrom      *time.Time `json:"valid_from"`
+	ValidUntil     *time.Time `json:"valid_until"`
+	MaxRedemptions *int       `json:"max_redemptions"`
+	MaxPerUser     *int       `json:"max_per_user"`
+	// Сколько раз применён (без отменённых и удалённых заказов)
+	Redemptions int       `json:"redemptions"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CouponService управление промокодами.
+type CouponService struct {
+	repo *repositories.CouponRepo
+	// валюта сумм промокода, если она не указана
+	currency string
+}
+
+// NewCouponService создаёт CouponService.
+func NewCouponService(db *gorm.DB, cfg *config.Config) *CouponService {
+	return &CouponService{
+		repo:     repositories.NewCouponRepo(db),
+		currency: cfg.Money.DefaultCurrency,
+	}
+}
+
+func toCouponResponse(c *models.Coupon) CouponResponse {
+	resp := CouponResponse{
+		ID:             c.ID,
+		Code:           c.Code,
+		Type:           c.Type,
+		Percent:        c.Percent,
+		Currency:       c.Currency,
+		ValidFrom:      c.ValidFrom,
+		ValidUntil:     c.ValidUntil,
+		MaxRedemptions: c.MaxRedemptions,
+		MaxPerUser:     c.MaxPerUser,
+		Redemptions:    c.Redemptions,
+		Active:         c.Active,
+		CreatedAt:      c.CreatedAt,
+	}
+	cur := currencyOf(c.Currency)
+	if c.Type == models.CouponTypeFixed {
+		resp.Amount = cur.Format(c.AmountMinor)
+	}
+	if c.MinOrderMinor > 0 {
+		resp.MinOrderAmount = cur.Format(c.MinOrderMinor)
+	}
+	return resp
+}
+
+// Create создаёт промокод.
+func (s *CouponService) Create(ctx context.Context, req *CreateCouponRequest) (*CouponResponse, error) {
+	code := normalizeCouponCode(req.Code)
+	log.Printf("Attempting to create coupon: %s", code)
+	if !couponCodePattern.MatchString(code) {
+		return nil, fmt.Errorf("%w: код — от 3 до 64 латинских букв, цифр, \"-\" или \"_\"", ErrInvalidCoupon)
+	}
+	c := &models.Coupon{
+		Code:           code,
+		Type:           req.Type,
+		ValidFrom:      req.ValidFrom,
+		ValidUntil:     req.ValidUntil,
+		MaxRedemptions: req.MaxRedemptions,
+		MaxPerUser:     req.MaxPerUser,
+		Active:         true,
+	}
+	switch req.Type {
+	case models.CouponTypePercent:
+		if req.Percent == 0 || req.Amount != "" {
+			return nil, fmt.Errorf("%w: для процентной скидки укажите только percent", ErrInvalidCoupon)
+		}
+		c.Percent = req.Percent
+	case models.CouponTypeFixed:
+		if req.Amount == "" || req.Percent != 0 {
+			return nil, fmt.Errorf("%w: для фиксированной скидки укажите только amount", ErrInvalidCoupon)
+		}
+	}
+	if req.Type == models.CouponTypeFixed || req.MinOrderAmount != "" || req.Currency != "" {
+		code := req.Currency
+		if code == "" {
+			code = s.currency
+		}
+		cur, err := money.Lookup(code)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCoupon, err)
+		}
+		c.Currency = cur.Code
+		if req.Amount != "" {
+			if c.AmountMinor, err = cur.Parse(req.Amount); err != nil {
+				return nil, fmt.Errorf("%w: amount: %v", ErrInvalidCoupon, err)
+			}
+			if c.AmountMinor <= 0 {
+				return nil, fmt.Errorf("%w: amount должен быть больше нуля", ErrInvalidCoupon)
+			}
+		}
+		if req.MinOrderAmount != "" {
+			if c.MinOrderMinor, err = cur.Parse(req.MinOrderAmount); err != nil {
+				return nil, fmt.Errorf("%w: min_order_amount: %v", ErrInvalidCoupon, err)
+			}
+			if c.MinOrderMinor < 0 {
+				return nil, fmt.Errorf("%w: min_order_amount не может быть отрицательным", ErrInvalidCoupon)
+			}
+		}
+	}
+	if c.ValidFrom != nil && c.ValidUntil != nil && !c.ValidFrom.Before(*c.ValidUntil) {
+		return nil, fmt.Errorf("%w: valid_from должен быть раньше valid_until", ErrInvalidCoupon)
+	}
+	if _, err := s.repo.GetByCode(ctx, code); err == nil {
+		return nil, ErrCouponCodeTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, c); err != nil {
+		log.Printf("Error creating coupon: %v", err)
+		return nil, err
+	}
+	log.Printf("Coupon created successfully with ID: %d", c.ID)
+	resp := toCouponResponse(c)
+	return &resp, nil
+}
+
+// Get возвращает промокод по ID, в том числе отключённый.
+func (s *CouponService) Get(ctx context.Context, id uint) (*CouponResponse, error) {
+	c, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCouponNotFound
+		}
+		return nil, err
+	}
+	resp := toCouponResponse(c)
+	return &resp, nil
+}
+
+// List возвращает страницу промокодов и их общее число.
+func (s *CouponService) List(ctx context.Context, page, limit int) ([]CouponResponse, int64, error) {
+	list, total, err := s.repo.List(ctx, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	out := make([]CouponResponse, len(list))
+	for i := range list {
+		out[i] = toCouponResponse(&list[i])
+	}
+	return out, total, nil
+}
+
+// Deactivate отключает промокод. Уже оформленные заказы сохраняют скидку.
+func (s *CouponService) Deactivate(ctx context.Context, id uint) error {
+	log.Printf("Attempting to deactivate coupon with ID: %d", id)
+	if err := s.repo.Deactivate(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCouponNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// normalizeCouponCode приводит код к виду, в котором он хранится.
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// applyCoupon проверяет промокод code для заказа o и рассчитывает скидку и итог.
+// Промокод блокируется до конца транзакции tx; применение записывается
+// redeemCoupon после сохранения заказа.
+func applyCoupon(ctx context.Context, tx *gorm.DB, o *repositories.Order, code string, now time.Time) error {
+	coupons := repositories.NewCouponRepo(tx)
+	c, err := coupons.LockByCode(ctx, normalizeCouponCode(code))
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !c.Active) {
+		return ErrCouponNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if c.ValidFrom != nil && now.Before(*c.ValidFrom) {
+		return fmt.Errorf("%w: промокод ещё не действует", ErrCouponNotApplicable)
+	}
+	if c.ValidUntil != nil && !now.Before(*c.ValidUntil) {
+		return fmt.Errorf("%w: срок действия промокода истёк", ErrCouponNotApplicable)
+	}
+	if c.MaxRedemptions != nil && c.Redemptions >= *c.MaxRedemptions {
+		return ErrCouponExhausted
+	}
+	if c.MaxPerUser != nil {
+		n, err := coupons.CountUserRedemptions(ctx, c.ID, o.UserID)
+		if err != nil {
+			return err
+		}
+		if n >= int64(*c.MaxPerUser) {
+			return fmt.Errorf("%w: промокод уже использован вами %d раз", ErrCouponExhausted, n)
+		}
+	}
+	if err := setDiscount(o, c); err != nil {
+		return err
+	}
+	o.CouponID, o.CouponCode = &c.ID, c.Code
+	return nil
+}
+
+// redeemCoupon записывает применение промокода к сохранённому заказу o.
+func redeemCoupon(ctx context.Context, tx *gorm.DB, o *repositories.Order) error {
+	ok, err := repositories.NewCouponRepo(tx).Redeem(ctx, &models.CouponRedemption{
+		CouponID:      *o.CouponID,
+		UserID:        o.UserID,
+		OrderID:       o.ID,
+		DiscountMinor: o.DiscountMinor,
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrCouponExhausted
+	}
+	return nil
+}
+
+// setDiscount рассчитывает скидку промокода c от суммы позиций заказа o и итог.
+// Процентная скидка округляется вниз до минимальной единицы, фиксированная
+// не больше суммы позиций.
+func setDiscount(o *repositories.Order, c *models.Coupon) error {
+	if c.Currency != "" && c.Currency != o.Currency {
+		return fmt.Errorf("%w: промокод действует для заказов в %s", ErrCouponNotApplicable, c.Currency)
+	}
+	if o.SubtotalMinor < c.MinOrderMinor {
+		return fmt.Errorf("%w: минимальная сумма заказа — %s %s", ErrCouponNotApplicable,
+			currencyOf(c.Currency).Format(c.MinOrderMinor), c.Currency)
+	}
+	var discount int64
+	switch c.Type {
+	case models.CouponTypePercent:
+		// без переполнения: сумма делится на 100 до умножения
+		p := int64(c.Percent)
+		discount = o.SubtotalMinor/100*p + o.SubtotalMinor%100*p/100
+	case models.CouponTypeFixed:
+		discount = min(c.AmountMinor, o.SubtotalMinor)
+	}
+	o.DiscountMinor = discount
+	o.TotalMinor = o.SubtotalMinor - discount
+	return nil
+}
+
+// recalcCoupon пересчитывает скидку заказа o с промокодом после смены состава.
+// Срок действия и лимиты погашений не проверяются: промокод уже применён к заказу.
+// Минимальная сумма заказа и валюта проверяются как при оформлении: если новый состав
+// им не соответствует, изменение отклоняется с ErrCouponNotApplicable.
+func recalcCoupon(ctx context.Context, tx *gorm.DB, o *repositories.Order) error {
+	if o.CouponID == nil {
+		return nil
+	}
+	coupons := repositories.NewCouponRepo(tx)
+	c, err := coupons.LockByID(ctx, *o.CouponID)
+	if err != nil {
+		return err
+	}
+	if err := setDiscount(o, c); err != nil {
+		return err
+	}
+	return coupons.UpdateDiscount(ctx, o.ID, o.DiscountMinor)
+}
@@ -0,0 +1,305 @@
+// order_list.go
+// Этот файл содержит список заказов с фильтрами, сортировкой и постраничной выдачей.
+// Страницы выбираются номером (page) или курсором (cursor) — курсор не сбивается,
+// когда между запросами появляются новые заказы.
+
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"kvant_task/internal/money"
+	"kvant_task/internal/repositories"
+)
+
+// ErrInvalidFilter ошибка, если параметры списка заказов некорректны.
+var ErrInvalidFilter = errors.New("некорректные параметры списка заказов")
+
+// Размер страницы списка заказов.
+const (
+	defaultOrderPageSize = 20
+	maxOrderPageSize     = 100
+)
+
+// OrderListQuery параметры списка заказов из query-строки.
+// Суммы — десятичные строки в валюте currency (по умолчанию — DEFAULT_CURRENCY);
+// если задана сумма или валюта, в выдачу попадают только заказы в этой валюте.
+type OrderListQuery struct {
+	Page  int `form:"page" binding:"omitempty,gt=0"`
+	Limit int `form:"limit" binding:"omitempty,gt=0,max=100"`
+	// Курсор следующей страницы из next_cursor; вместе с page не используется
+	Cursor string `form:"cursor" binding:"max=200"`
+	// Начало и конец периода создания включительно: дата (2024-01-31) или RFC 3339
+	CreatedFrom string `form:"created_from"`
+	CreatedTo   string `form:"created_to"`
+	Currency    string `form:"currency" binding:"omitempty,len=3"`
+	// Пределы итоговой суммы заказа
+	MinTotal string `form:"min_total"`
+	MaxTotal string `form:"max_total"`
+	// Пределы цены за единицу: в заказе есть такая позиция
+	MinPrice string `form:"min_price"`
+	MaxPrice string `form:"max_price"`
+	// Пределы числа единиц товара в заказе
+	MinQuantity int `form:"min_quantity" binding:"omitempty,gt=0"`
+	MaxQuantity int `form:"max_quantity" binding:"omitempty,gt=0"`
+	// Подстрока названия товара
+	Product string `form:"product" binding:"max=255"`
+	// Поле сортировки; "-" в начале — по убыванию. По умолчанию -created_at.
+	// price — самая высокая цена позиции заказа.
+	Sort string `form:"sort" binding:"omitempty,oneof=created_at -created_at price -price total -total"`
+}
+
+// AdminOrderListQuery параметры поиска заказов всех пользователей.
+type AdminOrderListQuery struct {
+	OrderListQuery
+	// Владелец заказов
+	UserID uint `form:"user_id" binding:"omitempty,gt=0"`
+	// Товар каталога в одной из позиций
+	ProductID uint   `form:"product_id" binding:"omitempty,gt=0"`
+	Status    string `form:"status" binding:"omitempty,oneof=pending paid shipped delivered cancelled"`
+	// Добавить к заказам краткие данные владельца
+	IncludeUser bool `form:"include_user"`
+}
+
+// OrderOwner краткие данные владельца заказа.
+type OrderOwner struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// AdminOrderResponse заказ в результатах поиска; владелец — только по запросу include_user.
+type AdminOrderResponse struct {
+	OrderResponse
+	User *OrderOwner `json:"user,omitempty"`
+}
+
+// Search ищет заказы всех пользователей; возвращает то же, что ListByUser.
+func (s *OrderService) Search(ctx context.Context, q *AdminOrderListQuery) ([]AdminOrderResponse, int64, string, error) {
+	f, err := q.filter(s.currency)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	f.UserID, f.ProductID, f.Status = q.UserID, q.ProductID, q.Status
+	list, total, next, err := s.list(ctx, f, q.Sort)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	out := make([]AdminOrderResponse, len(list))
+	for i := range list {
+		out[i].OrderResponse = list[i]
+	}
+	if q.IncludeUser && len(list) > 0 {
+		if err := s.attachOwners(ctx, out); err != nil {
+			return nil, 0, "", err
+		}
+	}
+	return out, total, next, nil
+}
+
+// attachOwners добавляет к заказам данные владельцев одним запросом.
+func (s *OrderService) attachOwners(ctx context.Context, orders []AdminOrderResponse) error {
+	ids := make([]uint, 0, len(orders))
+	seen := make(map[uint]bool)
+	for _, o := range orders {
+		if !seen[o.UserID] {
+			seen[o.UserID] = true
+			ids = append(ids, o.UserID)
+		}
+	}
+	users, err := s.users.ListByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	owners := make(map[uint]*OrderOwner, len(users))
+	for _, u := range users {
+		owners[u.ID] = &OrderOwner{ID: u.ID, Name: u.Name, Email: u.Email, Role: u.Role}
+	}
+	for i := range orders {
+		orders[i].User = owners[orders[i].UserID]
+	}
+	return nil
+}
+
+// ListByUser возвращает страницу заказов пользователя, общее число подходящих
+// заказов и курсор следующей страницы (пустой, если страница последняя).
+// Незаданные page и limit в q заполняются значениями по умолчанию.
+func (s *OrderService) ListByUser(ctx context.Context, userID uint, q *OrderListQuery) ([]OrderResponse, int64, string, error) {
+	f, err := q.filter(s.currency)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	f.UserID = userID
+	return s.list(ctx, f, q.Sort)
+}
+
+// list выбирает страницу заказов; лишний заказ запрашивается, чтобы узнать, есть ли следующая страница.
+func (s *OrderService) list(ctx context.Context, f *repositories.OrderFilter, sort string) ([]OrderResponse, int64, string, error) {
+	limit := f.Limit
+	f.Limit++
+	list, total, err := s.repo.List(ctx, f)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	next := ""
+	if len(list) > limit {
+		list = list[:limit]
+		next = encodeOrderCursor(sort, repositories.CursorOf(&list[limit-1], f.Sort))
+	}
+	out := make([]OrderResponse, len(list))
+	for i, o := range list {
+		out[i] = *toOrderResponse(&o)
+	}
+	return out, total, next, nil
+}
+
+// filter проверяет параметры и переводит их в фильтр репозитория.
+func (q *OrderListQuery) filter(defaultCurrency string) (*repositories.OrderFilter, error) {
+	if q.Sort == "" {
+		q.Sort = "-" + repositories.OrderSortCreatedAt
+	}
+	if q.Limit == 0 {
+		q.Limit = defaultOrderPageSize
+	}
+	if q.Limit > maxOrderPageSize {
+		return nil, fmt.Errorf("%w: размер страницы должен быть от 1 до %d", ErrInvalidFilter, maxOrderPageSize)
+	}
+	if q.Cursor != "" && q.Page > 1 {
+		return nil, fmt.Errorf("%w: page и cursor нельзя задавать вместе", ErrInvalidFilter)
+	}
+	if q.Page == 0 {
+		q.Page = 1
+	}
+	f := &repositories.OrderFilter{
+		Sort:    strings.TrimPrefix(q.Sort, "-"),
+		Desc:    strings.HasPrefix(q.Sort, "-"),
+		Offset:  (q.Page - 1) * q.Limit,
+		Limit:   q.Limit,
+		Product: strings.TrimSpace(q.Product),
+	}
+	if q.Cursor != "" {
+		after, err := decodeOrderCursor(q.Sort, q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		f.After, f.Offset = after, 0
+	}
+
+	var err error
+	if f.CreatedFrom, err = parseFilterTime(q.CreatedFrom, false); err != nil {
+		return nil, fmt.Errorf("%w: created_from: %v", ErrInvalidFilter, err)
+	}
+	if f.CreatedTo, err = parseFilterTime(q.CreatedTo, true); err != nil {
+		return nil, fmt.Errorf("%w: created_to: %v", ErrInvalidFilter, err)
+	}
+
+	if q.MinQuantity != 0 {
+		f.MinQuantity = &q.MinQuantity
+	}
+	if q.MaxQuantity != 0 {
+		f.MaxQuantity = &q.MaxQuantity
+	}
+
+	code := q.Currency
+	if code == "" {
+		code = defaultCurrency
+	}
+	cur, err := money.Lookup(code)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	amounts := []struct {
+		name  string
+		value string
+		dst   **int64
+	}{
+		{"min_total", q.MinTotal, &f.MinTotal},
+		{"max_total", q.MaxTotal, &f.MaxTotal},
+		{"min_price", q.MinPrice, &f.MinPrice},
+		{"max_price", q.MaxPrice, &f.MaxPrice},
+	}
+	for _, a := range amounts {
+		if a.value == "" {
+			continue
+		}
+		v, err := cur.Parse(a.value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidFilter, a.name, err)
+		}
+		*a.dst = &v
+		f.Currency = cur.Code
+	}
+	if q.Currency != "" {
+		f.Currency = cur.Code
+	}
+	return f, nil
+}
+
+// parseFilterTime разбирает дату или время RFC 3339. Для конца периода (end)
+// возвращается исключающая граница: начало следующего дня или следующая микросекунда.
+func parseFilterTime(s string, end bool) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return &t, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil, errors.New("ожидается дата ГГГГ-ММ-ДД или время RFC 3339")
+	}
+	if end {
+		t = t.Truncate(time.Microsecond).Add(time.Microsecond)
+	}
+	return &t, nil
+}
+
+// encodeOrderCursor кодирует курсор вместе с сортировкой, для которой он выдан.
+// Время хранится в микросекундах — с точностью базы.
+func encodeOrderCursor(sort string, c *repositories.OrderCursor) string {
+	v := c.Amount
+	if strings.TrimPrefix(sort, "-") == repositories.OrderSortCreatedAt {
+		v = c.CreatedAt.UnixMicro()
+	}
+	raw := fmt.Sprintf("%s:%d:%d", sort, v, c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeOrderCursor разбирает курсор; курсор другой сортировки не принимается.
+func decodeOrderCursor(sort, s string) (*repositories.OrderCursor, error) {
+	invalid := fmt.Errorf("%w: некорректный курсор", ErrInvalidFilter)
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalid
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return nil, invalid
+	}
+	if parts[0] != sort {
+		return nil, fmt.Errorf("%w: курсор выдан для другой сортировки", ErrInvalidFilter)
+	}
+	v, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+	id, err := strconv.ParseUint(parts[2], 10, 32)
+	if err != nil {
+		return nil, invalid
+	}
+	c := &repositories.OrderCursor{Amount: v, ID: uint(id)}
+	if strings.TrimPrefix(sort, "-") == repositories.OrderSortCreatedAt {
+		c.CreatedAt = time.UnixMicro(v)
+	}
+	return c, nil
+}
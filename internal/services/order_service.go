@@ -2,9 +2,17 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	"kvant_task/internal/config"
+	"kvant_task/internal/models"
+	"kvant_task/internal/money"
+	"kvant_task/internal/payment"
+	"kvant_task/internal/pricing"
 	"kvant_task/internal/repositories"
 
 	"gorm.io/gorm"
@@ -14,56 +22,228 @@ import (
 // Этот файл содержит бизнес-логику для работы с заказами.
 // Реализует методы для создания, обновления и получения заказов.
 
+// maxOrderItems — сколько позиций может быть в одном заказе.
+const maxOrderItems = 100
+
 // CreateOrderRequest данные для создания заказа.
-// Поля точно соответствуют ТЗ.
 type CreateOrderRequest struct {
-	Product  string  `json:"product" binding:"required"`
-	Quantity int     `json:"quantity" binding:"required,gt=0"`
-	Price    float64 `json:"price" binding:"required,gt=0"`
+	Items []OrderItemRequest `json:"items" binding:"required,min=1,max=100,dive"`
+	// Промокод; скидка считается от суммы позиций
+	CouponCode string `json:"coupon_code" binding:"max=64" example:"SPRING10"`
+}
+
+// OrderItemRequest позиция создаваемого заказа: товар каталога по ID или по артикулу.
+// Цена берётся из каталога.
+type OrderItemRequest struct {
+	ProductID uint   `json:"product_id"`
+	SKU       string `json:"sku" binding:"max=64"`
+	Quantity  int    `json:"quantity" binding:"required,gt=0"`
+}
+
+// UpdateOrderRequest данные для изменения заказа.
+// Состав меняется только у неоплаченного заказа; из статусов здесь можно задать только отмену.
+// Состав и статус меняются отдельными запросами.
+type UpdateOrderRequest struct {
+	Items   []OrderItemRequest `json:"items" binding:"omitempty,min=1,max=100,dive"`
+	Status  string             `json:"status" binding:"omitempty,oneof=cancelled"`
+	Comment string             `json:"comment" binding:"max=255"`
 }
 
 // OrderResponse DTO для отправки клиенту.
+// Суммы — десятичные строки с числом знаков валюты, например "1230.47".
 type OrderResponse struct {
-	ID        uint      `json:"id"`
-	UserID    uint      `json:"user_id"`
-	Product   string    `json:"product"`
-	Quantity  int       `json:"quantity"`
-	Price     float64   `json:"price"`
+	ID     uint                `json:"id"`
+	UserID uint                `json:"user_id"`
+	Items  []OrderItemResponse `json:"items"`
+	// Валюта заказа, код ISO 4217
+	Currency string `json:"currency" example:"RUB"`
+	// Сумма позиций
+	Subtotal string `json:"subtotal" example:"1230.47"`
+	// Применённый промокод
+	CouponCode string `json:"coupon_code,omitempty" example:"SPRING10"`
+	// Скидка по промокоду
+	Discount string `json:"discount" example:"0.00"`
+	// Налог; по ставкам — в tax_lines
+	Tax      string            `json:"tax" example:"0.00"`
+	TaxLines []TaxLineResponse `json:"tax_lines"`
+	// Стоимость доставки
+	Shipping string `json:"shipping" example:"0.00"`
+	// Итого к оплате: сумма позиций минус скидка, плюс налог и доставка
+	Total     string    `json:"total" example:"1230.47"`
+	Status    string    `json:"status"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// OrderItemResponse позиция заказа в ответе.
+type OrderItemResponse struct {
+	ID        uint   `json:"id"`
+	ProductID *uint  `json:"product_id"`
+	SKU       string `json:"sku"`
+	Product   string `json:"product"`
+	Quantity  int    `json:"quantity"`
+	Price     string `json:"price" example:"9.99"`
+	// Стоимость позиции: цена × количество
+	LineTotal string `json:"line_total" example:"29.97"`
+}
+
+// TaxLineResponse строка налога заказа.
+type TaxLineResponse struct {
+	// Название налога или налоговой категории
+	Name string `json:"name" example:"VAT"`
+	// Ставка в процентах
+	Rate string `json:"rate" example:"20"`
+	// Облагаемая сумма: стоимость позиций за вычетом скидки
+	Base   string `json:"base" example:"1230.47"`
+	Amount string `json:"amount" example:"246.09"`
+}
+
 // OrderService бизнес-логика заказов.
 type OrderService struct {
-	repo *repositories.OrderRepo
+	repo  *repositories.OrderRepo
+	users *repositories.UserRepo
+	// расчёт налога и доставки при создании и изменении заказа
+	tax      pricing.TaxCalculator
+	shipping pricing.ShippingCalculator
+	// возврат платежей при отмене оплаченного заказа
+	payments *PaymentService
+	// заказы только от пользователей с подтверждённым email
+	requireVerified bool
+	// валюта сумм в фильтрах списка, если она не указана
+	currency string
 }
 
-// NewOrderService создаёт OrderService.
-func NewOrderService(db *gorm.DB) *OrderService {
-	return &OrderService{repo: repositories.NewOrderRepo(db)}
+// NewOrderService создаёт OrderService с расчётом налога tax и доставки shipping;
+// платежи отменённых заказов возвращаются через провайдера payments.
+func NewOrderService(db *gorm.DB, cfg *config.Config, tax pricing.TaxCalculator, shipping pricing.ShippingCalculator, payments payment.PaymentProvider) *OrderService {
+	return &OrderService{
+		repo:            repositories.NewOrderRepo(db),
+		users:           repositories.NewUserRepo(db),
+		tax:             tax,
+		shipping:        shipping,
+		payments:        NewPaymentService(db, cfg, payments),
+		requireVerified: cfg.Auth.RequireVerifiedEmailToOrder,
+		currency:        cfg.Money.DefaultCurrency,
+	}
 }
 
 func toOrderResponse(o *repositories.Order) *OrderResponse {
+	cur := currencyOf(o.Currency)
+	items := make([]OrderItemResponse, len(o.Items))
+	for i, it := range o.Items {
+		// переполнение исключено: суммы проверены при создании заказа
+		line, _ := money.Mul(it.PriceMinor, int64(it.Quantity))
+		items[i] = OrderItemResponse{
+			ID:        it.ID,
+			ProductID: it.ProductID,
+			SKU:       it.SKU,
+			Product:   it.Product,
+			Quantity:  it.Quantity,
+			Price:     cur.Format(it.PriceMinor),
+			LineTotal: cur.Format(line),
+		}
+	}
+	taxLines := make([]TaxLineResponse, len(o.TaxLines))
+	for i, l := range o.TaxLines {
+		taxLines[i] = TaxLineResponse{
+			Name:   l.Name,
+			Rate:   formatRate(l.Rate),
+			Base:   cur.Format(l.BaseMinor),
+			Amount: cur.Format(l.AmountMinor),
+		}
+	}
 	return &OrderResponse{
-		ID:        o.ID,
-		UserID:    o.UserID,
-		Product:   o.Product,
-		Quantity:  o.Quantity,
-		Price:     o.Price,
-		CreatedAt: o.CreatedAt,
+		ID:         o.ID,
+		UserID:     o.UserID,
+		Items:      items,
+		Currency:   cur.Code,
+		Subtotal:   cur.Format(o.SubtotalMinor),
+		CouponCode: o.CouponCode,
+		Discount:   cur.Format(o.DiscountMinor),
+		Tax:        cur.Format(o.TaxMinor),
+		TaxLines:   taxLines,
+		Shipping:   cur.Format(o.ShippingMinor),
+		Total:      cur.Format(o.TotalMinor),
+		Status:     o.Status,
+		CreatedAt:  o.CreatedAt,
+	}
+}
+
+// currencyOf возвращает валюту по коду из базы. Коды проверяются при записи,
+// поэтому для неизвестного кода (например, изменённого вручную) берутся два знака.
+func currencyOf(code string) money.Currency {
+	cur, err := money.Lookup(code)
+	if err != nil {
+		return money.Currency{Code: code, Exponent: 2}
 	}
+	return cur
+}
+
+// formatRate возвращает ставку в сотых долях процента строкой в процентах: "20", "7.5".
+func formatRate(rate int) string {
+	s := money.Currency{Exponent: 2}.Format(int64(rate))
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
 }
 
-// Create создаёт новый заказ и возвращает его DTO.
+var (
+	// ErrInvalidOrder ошибка, если состав заказа некорректен.
+	ErrInvalidOrder = errors.New("некорректный заказ")
+	// ErrInsufficientStock ошибка, если товара на складе меньше, чем заказано.
+	ErrInsufficientStock = errors.New("недостаточно товара на складе")
+	// ErrMixedCurrencies ошибка, если товары заказа продаются в разных валютах.
+	ErrMixedCurrencies = errors.New("товары заказа в разных валютах")
+	// ErrOrderNotEditable ошибка, если состав заказа уже нельзя менять.
+	ErrOrderNotEditable = errors.New("изменить можно только неоплаченный заказ")
+	// ErrOrderNotDeletable ошибка, если заказ уже нельзя удалить.
+	ErrOrderNotDeletable = errors.New("удалить можно только неоплаченный или отменённый заказ")
+	// ErrShippingUnavailable ошибка, если для заказа нет тарифа доставки.
+	ErrShippingUnavailable = pricing.ErrShippingUnavailable
+)
+
+// Create создаёт заказ из товаров каталога и возвращает его DTO.
+// Товары блокируются до конца транзакции, остатки списываются вместе с созданием
+// заказа; если какого-то товара не хватает, заказ не создаётся. Промокод
+// проверяется и применяется в той же транзакции, затем рассчитываются налог и доставка.
 func (s *OrderService) Create(ctx context.Context, userID uint, req *CreateOrderRequest) (*OrderResponse, error) {
 	// Add logging for order creation
 	log.Printf("Attempting to create order for user ID: %d", userID)
+	if s.requireVerified {
+		u, err := s.users.GetByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if u.EmailVerifiedAt == nil {
+			return nil, ErrEmailNotVerified
+		}
+	}
+	if err := checkItems(req.Items); err != nil {
+		return nil, err
+	}
 	o := &repositories.Order{
-		UserID:   userID,
-		Product:  req.Product,
-		Quantity: req.Quantity,
-		Price:    req.Price,
+		UserID: userID,
+		Status: models.OrderStatusPending,
 	}
-	if err := s.repo.Create(ctx, o); err != nil {
+	err := s.repo.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		lines, err := reserveItems(ctx, tx, o, req.Items)
+		if err != nil {
+			return err
+		}
+		if req.CouponCode != "" {
+			if err := applyCoupon(ctx, tx, o, req.CouponCode, time.Now()); err != nil {
+				return err
+			}
+		}
+		if err := s.price(ctx, o, lines); err != nil {
+			return err
+		}
+		if err := repositories.NewOrderRepo(tx).Create(ctx, o); err != nil {
+			return err
+		}
+		if o.CouponID == nil {
+			return nil
+		}
+		return redeemCoupon(ctx, tx, o)
+	})
+	if err != nil {
 		log.Printf("Error creating order: %v", err)
 		return nil, err
 	}
@@ -71,17 +251,305 @@ func (s *OrderService) Create(ctx context.Context, userID uint, req *CreateOrder
 	return toOrderResponse(o), nil
 }
 
-// ListByUser возвращает список заказов пользователя.
-func (s *OrderService) ListByUser(ctx context.Context, userID uint) ([]OrderResponse, error) {
-	list, err := s.repo.ListByUser(ctx, userID)
+// checkItems проверяет состав заказа до обращения к базе.
+func checkItems(items []OrderItemRequest) error {
+	if len(items) == 0 || len(items) > maxOrderItems {
+		return fmt.Errorf("%w: в заказе должно быть от 1 до %d позиций", ErrInvalidOrder, maxOrderItems)
+	}
+	for i, it := range items {
+		if (it.ProductID == 0) == (it.SKU == "") {
+			return fmt.Errorf("%w: позиция %d: укажите product_id или sku", ErrInvalidOrder, i+1)
+		}
+		if it.Quantity <= 0 {
+			return fmt.Errorf("%w: позиция %d: количество должно быть больше нуля", ErrInvalidOrder, i+1)
+		}
+	}
+	return nil
+}
+
+// reserveItems заполняет позиции, валюту и суммы заказа по каталогу и списывает остатки.
+// Возвращает позиции для расчёта налога и доставки. Товары блокируются до конца
+// транзакции tx. Если какого-то товара не хватает, возвращает ErrInsufficientStock,
+// и транзакцию нужно откатить.
+func reserveItems(ctx context.Context, tx *gorm.DB, o *repositories.Order, items []OrderItemRequest) ([]pricing.Line, error) {
+	ids := make([]uint, 0, len(items))
+	skus := make([]string, 0, len(items))
+	for _, it := range items {
+		if it.ProductID != 0 {
+			ids = append(ids, it.ProductID)
+		} else {
+			skus = append(skus, it.SKU)
+		}
+	}
+	products := repositories.NewProductRepo(tx)
+	locked, err := products.LockForOrder(ctx, ids, skus)
 	if err != nil {
 		return nil, err
 	}
-	out := make([]OrderResponse, len(list))
-	for i, o := range list {
-		out[i] = *toOrderResponse(&o)
+	byID := make(map[uint]*models.Product, len(locked))
+	bySKU := make(map[string]*models.Product, len(locked))
+	for i := range locked {
+		byID[locked[i].ID] = &locked[i]
+		bySKU[locked[i].SKU] = &locked[i]
+	}
+	o.Items = make([]models.OrderItem, len(items))
+	lines := make([]pricing.Line, len(items))
+	o.Currency = ""
+	o.SubtotalMinor = 0
+	// одинаковый товар может встречаться в нескольких позициях
+	need := make(map[uint]int, len(locked))
+	for i, it := range items {
+		p := byID[it.ProductID]
+		if it.ProductID == 0 {
+			p = bySKU[it.SKU]
+		}
+		if p == nil {
+			return nil, fmt.Errorf("%w: позиция %d", ErrProductNotFound, i+1)
+		}
+		if o.Currency == "" {
+			o.Currency = p.Currency
+		}
+		if p.Currency != o.Currency {
+			return nil, fmt.Errorf("%w: позиция %d: %s, а не %s", ErrMixedCurrencies, i+1, p.Currency, o.Currency)
+		}
+		o.Items[i] = models.OrderItem{
+			ProductID:  &p.ID,
+			SKU:        p.SKU,
+			Product:    p.Name,
+			Quantity:   it.Quantity,
+			PriceMinor: p.PriceMinor,
+		}
+		line, err := money.Mul(p.PriceMinor, int64(it.Quantity))
+		if err == nil {
+			o.SubtotalMinor, err = money.Add(o.SubtotalMinor, line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: позиция %d: %v", ErrInvalidOrder, i+1, err)
+		}
+		lines[i] = pricing.Line{
+			ProductID:   p.ID,
+			TaxCategory: p.TaxCategory,
+			Quantity:    it.Quantity,
+			WeightGrams: p.WeightGrams,
+			AmountMinor: line,
+		}
+		need[p.ID] += it.Quantity
+	}
+	for _, p := range locked {
+		q, ok := need[p.ID]
+		if !ok {
+			continue
+		}
+		if p.Stock < q {
+			return nil, fmt.Errorf("%w: %s: доступно %d, заказано %d", ErrInsufficientStock, p.SKU, p.Stock, q)
+		}
+		ok, err := products.DecrementStock(ctx, p.ID, q)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInsufficientStock, p.SKU)
+		}
+	}
+	o.DiscountMinor = 0
+	o.TotalMinor = o.SubtotalMinor
+	return lines, nil
+}
+
+// price рассчитывает налог и доставку заказа o с позициями lines и итог к оплате.
+// Скидка делится между позициями пропорционально их стоимости, налог начисляется
+// на стоимость позиций за вычетом скидки; доставка налогом не облагается.
+func (s *OrderService) price(ctx context.Context, o *repositories.Order, lines []pricing.Line) error {
+	amounts := make([]int64, len(lines))
+	for i, l := range lines {
+		amounts[i] = l.AmountMinor
+	}
+	for i, share := range pricing.Distribute(o.DiscountMinor, amounts) {
+		lines[i].AmountMinor -= share
+	}
+	po := &pricing.Order{Currency: o.Currency, Lines: lines}
+	taxLines, err := s.tax.Tax(ctx, po)
+	if err != nil {
+		return err
+	}
+	if o.ShippingMinor, err = s.shipping.Shipping(ctx, po); err != nil {
+		return err
+	}
+	o.TaxMinor = 0
+	o.TaxLines = make([]models.OrderTaxLine, 0, len(taxLines))
+	for _, l := range taxLines {
+		if o.TaxMinor, err = money.Add(o.TaxMinor, l.AmountMinor); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidOrder, err)
+		}
+		o.TaxLines = append(o.TaxLines, models.OrderTaxLine{
+			Name:        l.Name,
+			Rate:        l.Rate,
+			BaseMinor:   l.BaseMinor,
+			AmountMinor: l.AmountMinor,
+		})
+	}
+	total, err := money.Add(o.SubtotalMinor-o.DiscountMinor, o.TaxMinor)
+	if err == nil {
+		total, err = money.Add(total, o.ShippingMinor)
 	}
-	return out, nil
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidOrder, err)
+	}
+	o.TotalMinor = total
+	return nil
+}
+
+// Get возвращает заказ пользователя.
+func (s *OrderService) Get(ctx context.Context, userID, orderID uint) (*OrderResponse, error) {
+	o, err := s.getOrder(ctx, userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return toOrderResponse(o), nil
+}
+
+// Update меняет состав неоплаченного заказа или отменяет заказ.
+// При смене состава прежние позиции возвращаются на склад, новые списываются
+// по текущим ценам каталога — в одной транзакции. Скидка по промокоду, налог
+// и доставка пересчитываются от нового состава.
+func (s *OrderService) Update(ctx context.Context, userID, orderID uint, actor Actor, req *UpdateOrderRequest) (*OrderResponse, error) {
+	log.Printf("Attempting to update order ID: %d for user ID: %d", orderID, userID)
+	switch {
+	case req.Items != nil && req.Status != "":
+		return nil, fmt.Errorf("%w: состав и статус меняются отдельными запросами", ErrInvalidOrder)
+	case req.Status != "":
+		return s.Transition(ctx, userID, orderID, actor, &OrderTransitionRequest{Status: req.Status, Comment: req.Comment})
+	case req.Items == nil:
+		return nil, fmt.Errorf("%w: нечего изменять", ErrInvalidOrder)
+	}
+	if err := checkItems(req.Items); err != nil {
+		return nil, err
+	}
+	if _, err := s.getOrder(ctx, userID, orderID); err != nil {
+		return nil, err
+	}
+	var o *repositories.Order
+	err := s.repo.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		orders := repositories.NewOrderRepo(tx)
+		var err error
+		if o, err = lockOrder(ctx, orders, orderID); err != nil {
+			return err
+		}
+		if o.Status != models.OrderStatusPending {
+			return ErrOrderNotEditable
+		}
+		if err := checkNoPayment(ctx, tx, o.ID); err != nil {
+			return err
+		}
+		if err := orders.Restock(ctx, o.ID); err != nil {
+			return err
+		}
+		lines, err := reserveItems(ctx, tx, o, req.Items)
+		if err != nil {
+			return err
+		}
+		if err := recalcCoupon(ctx, tx, o); err != nil {
+			return err
+		}
+		if err := s.price(ctx, o, lines); err != nil {
+			return err
+		}
+		ok, err := orders.Update(ctx, o, models.OrderStatusPending)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrOrderStatusChanged
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error updating order: %v", err)
+		return nil, err
+	}
+	log.Printf("Order ID: %d updated", o.ID)
+	return toOrderResponse(o), nil
+}
+
+// Delete удаляет неоплаченный или отменённый заказ пользователя. Товары
+// неоплаченного заказа возвращаются на склад.
+func (s *OrderService) Delete(ctx context.Context, userID, orderID uint) error {
+	log.Printf("Attempting to delete order ID: %d for user ID: %d", orderID, userID)
+	if _, err := s.getOrder(ctx, userID, orderID); err != nil {
+		return err
+	}
+	return s.repo.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		orders := repositories.NewOrderRepo(tx)
+		o, err := lockOrder(ctx, orders, orderID)
+		if err != nil {
+			return err
+		}
+		switch o.Status {
+		case models.OrderStatusPending:
+			if err := checkNoPayment(ctx, tx, o.ID); err != nil {
+				return err
+			}
+			if err := orders.Restock(ctx, o.ID); err != nil {
+				return err
+			}
+		case models.OrderStatusCancelled:
+			// списанный платёж ещё не возвращён — запись о нём нужна для возврата
+			if err := checkNoPayment(ctx, tx, o.ID); err != nil {
+				return err
+			}
+		default:
+			return ErrOrderNotDeletable
+		}
+		return orders.Delete(ctx, o.ID)
+	})
+}
+
+// checkNoPayment возвращает ErrPaymentInProgress, если заказ уже оплачивается:
+// сумма действующего платежа должна совпадать с суммой заказа.
+func checkNoPayment(ctx context.Context, tx *gorm.DB, orderID uint) error {
+	active, err := repositories.NewPaymentRepo(tx).HasActive(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if active {
+		return ErrPaymentInProgress
+	}
+	return nil
+}
+
+// lockOrder блокирует заказ до конца транзакции и перечитывает его.
+// Возвращает ErrOrderNotFound, если заказ удалён параллельным запросом.
+func lockOrder(ctx context.Context, orders *repositories.OrderRepo, id uint) (*repositories.Order, error) {
+	if err := orders.Lock(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+	return orders.GetByID(ctx, id)
+}
+
+// getOrder возвращает заказ пользователя или ErrOrderNotFound.
+func (s *OrderService) getOrder(ctx context.Context, userID, orderID uint) (*repositories.Order, error) {
+	o, err := s.repo.GetByID(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+	// чужой заказ не отличается от несуществующего
+	if o.UserID != userID {
+		return nil, ErrOrderNotFound
+	}
+	return o, nil
+}
+
+// CheckUser проверяет, что владелец заказов существует.
+// Возвращает ErrNotFound, если пользователя нет.
+func (s *OrderService) CheckUser(ctx context.Context, userID uint) error {
+	_, err := s.users.GetByID(ctx, userID)
+	return err
 }
 
 func (s *OrderService) GetDB() *gorm.DB {
@@ -0,0 +1,164 @@
+// order_status.go
+// Этот файл содержит жизненный цикл заказа: допустимые переходы между статусами,
+// кто может их выполнять, и историю переходов.
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"kvant_task/internal/models"
+	"kvant_task/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrOrderNotFound ошибка, если у пользователя нет такого заказа.
+	ErrOrderNotFound = errors.New("заказ не найден")
+	// ErrInvalidTransition ошибка, если переход между статусами не предусмотрен.
+	ErrInvalidTransition = errors.New("недопустимый переход статуса заказа")
+	// ErrTransitionForbidden ошибка, если переход разрешён только администратору.
+	ErrTransitionForbidden = errors.New("недостаточно прав для перехода статуса заказа")
+	// ErrOrderStatusChanged ошибка, если статус заказа изменился параллельным запросом.
+	ErrOrderStatusChanged = errors.New("статус заказа изменился, повторите запрос")
+)
+
+// orderTransitions — допустимые переходы: из статуса в статусы.
+// delivered и cancelled — конечные статусы.
+var orderTransitions = map[string][]string{
+	models.OrderStatusPending: {models.OrderStatusPaid, models.OrderStatusCancelled},
+	models.OrderStatusPaid:    {models.OrderStatusShipped, models.OrderStatusCancelled},
+	models.OrderStatusShipped: {models.OrderStatusDelivered},
+}
+
+// ownerTransitions — переходы, которые владелец заказа может выполнить сам;
+// остальные выполняет администратор.
+var ownerTransitions = map[string][]string{
+	models.OrderStatusPending: {models.OrderStatusCancelled},
+}
+
+// Actor — пользователь, выполняющий операцию, и его роль.
+type Actor struct {
+	UserID uint
+	Role   string
+}
+
+// OrderTransitionRequest данные для смены статуса заказа
+type OrderTransitionRequest struct {
+	Status  string `json:"status" binding:"required,oneof=pending paid shipped delivered cancelled"`
+	Comment string `json:"comment" binding:"max=255"`
+}
+
+// OrderStatusChangeResponse запись истории статусов заказа
+type OrderStatusChangeResponse struct {
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ActorID    uint      `json:"actor_id"`
+	ActorRole  string    `json:"actor_role"`
+	Comment    string    `json:"comment,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Transition переводит заказ пользователя в новый статус, если переход допустим
+// и разрешён роли actor, и записывает переход в историю. Пока заказ оплачивается,
+// его нельзя ни отменить, ни отметить оплаченным вручную. Ручная отметка об оплате
+// записывает платёж провайдера manual, а отмена оплаченного заказа возвращает платёж.
+func (s *OrderService) Transition(ctx context.Context, userID, orderID uint, actor Actor, req *OrderTransitionRequest) (*OrderResponse, error) {
+	log.Printf("Attempting to move order ID: %d to status %s by user ID: %d", orderID, req.Status, actor.UserID)
+	if _, err := s.getOrder(ctx, userID, orderID); err != nil {
+		return nil, err
+	}
+	var o *repositories.Order
+	from := ""
+	err := s.repo.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		orders := repositories.NewOrderRepo(tx)
+		var err error
+		if o, err = lockOrder(ctx, orders, orderID); err != nil {
+			return err
+		}
+		from = o.Status
+		if !allowed(orderTransitions, from, req.Status) {
+			return fmt.Errorf("%w: %s → %s", ErrInvalidTransition, from, req.Status)
+		}
+		if actor.Role != models.RoleAdmin && !allowed(ownerTransitions, from, req.Status) {
+			return fmt.Errorf("%w: %s → %s", ErrTransitionForbidden, from, req.Status)
+		}
+		if from == models.OrderStatusPending {
+			if err := checkNoPayment(ctx, tx, o.ID); err != nil {
+				return err
+			}
+		}
+		if req.Status == models.OrderStatusPaid {
+			// оплата вне сервиса: платёж нужен, чтобы отмена заказа его вернула
+			if err := recordManualPayment(ctx, tx, o); err != nil {
+				return err
+			}
+		}
+		ok, err := orders.ChangeStatus(ctx, &models.OrderStatusChange{
+			OrderID:    o.ID,
+			FromStatus: from,
+			ToStatus:   req.Status,
+			ActorID:    actor.UserID,
+			ActorRole:  actor.Role,
+			Comment:    req.Comment,
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrOrderStatusChanged
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Order ID: %d moved from %s to %s", o.ID, from, req.Status)
+	if from == models.OrderStatusPaid && req.Status == models.OrderStatusCancelled {
+		// заказ уже отменён; если провайдер недоступен, возврат повторит PaymentService.Reconcile
+		if err := s.payments.refundOrder(ctx, o.ID); err != nil {
+			log.Printf("Error refunding cancelled order ID: %d: %v", o.ID, err)
+		}
+	}
+	o.Status = req.Status
+	return toOrderResponse(o), nil
+}
+
+// StatusHistory возвращает историю статусов заказа пользователя.
+func (s *OrderService) StatusHistory(ctx context.Context, userID, orderID uint) ([]OrderStatusChangeResponse, error) {
+	o, err := s.getOrder(ctx, userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.repo.ListStatusHistory(ctx, o.ID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]OrderStatusChangeResponse, len(list))
+	for i, c := range list {
+		out[i] = OrderStatusChangeResponse{
+			FromStatus: c.FromStatus,
+			ToStatus:   c.ToStatus,
+			ActorID:    c.ActorID,
+			ActorRole:  c.ActorRole,
+			Comment:    c.Comment,
+			CreatedAt:  c.CreatedAt,
+		}
+	}
+	return out, nil
+}
+
+// allowed сообщает, есть ли переход from → to в таблице переходов.
+func allowed(transitions map[string][]string, from, to string) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,488 @@
+// payment_service.go
+// Этот файл содержит оплату заказов через платёжного провайдера: попытки оплаты,
+// списание с переводом заказа в paid, возвраты, обработку уведомлений провайдера
+// и сверку зависших платежей.
+// Провайдер вызывается вне транзакций БД, чтобы не держать блокировки на время запроса к нему.
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"kvant_task/internal/config"
+	"kvant_task/internal/models"
+	"kvant_task/internal/payment"
+	"kvant_task/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrOrderNotPayable ошибка, если заказ уже оплачен или отменён.
+	ErrOrderNotPayable = errors.New("оплатить можно только неоплаченный заказ")
+	// ErrPaymentInProgress ошибка, если у заказа уже есть действующий платёж.
+	ErrPaymentInProgress = errors.New("заказ уже оплачивается")
+	// ErrPaymentDeclined ошибка, если провайдер отклонил платёж.
+	ErrPaymentDeclined = payment.ErrDeclined
+	// ErrPaymentProvider ошибка, если платёжный провайдер не выполнил операцию.
+	ErrPaymentProvider = errors.New("ошибка платёжного провайдера")
+	// ErrPaymentNotFound ошибка, если уведомление относится к неизвестному платежу.
+	ErrPaymentNotFound = errors.New("платёж не найден")
+	// ErrInvalidWebhook ошибка, если уведомление не подписано провайдером.
+	ErrInvalidWebhook = payment.ErrInvalidWebhook
+)
+
+// paymentActorRole — роль в истории статусов для переходов по уведомлениям провайдера.
+const paymentActorRole = "payment"
+
+// manualProvider — провайдер платежей, которые администратор отметил вручную
+// (оплата вне сервиса); возврат по ним провайдеру не отправляется.
+const manualProvider = "manual"
+
+// expiredReason — причина отказа платежа, не подтверждённого за PAYMENT_PENDING_TTL.
+const expiredReason = "истёк срок ожидания подтверждения"
+
+// PayOrderRequest данные для оплаты заказа.
+type PayOrderRequest struct {
+	// Способ оплаты — токен, выданный клиенту платёжным провайдером
+	Method string `json:"payment_method" binding:"max=64" example:"fake_card_ok"`
+}
+
+// PaymentResponse DTO платежа
+type PaymentResponse struct {
+	ID       uint   `json:"id"`
+	OrderID  uint   `json:"order_id"`
+	Provider string `json:"provider" example:"fake"`
+	// Идентификатор платежа у провайдера
+	ProviderRef string `json:"provider_payment_id,omitempty"`
+	Amount      string `json:"amount" example:"1230.47"`
+	Currency    string `json:"currency" example:"RUB"`
+	// pending, authorized, captured, failed или refunded
+	Status        string    `json:"status"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// PaymentService оплата заказов.
+type PaymentService struct {
+	db       *gorm.DB
+	payments *repositories.PaymentRepo
+	orders   *repositories.OrderRepo
+	users    *repositories.UserRepo
+	provider payment.PaymentProvider
+	// сколько платёж может ждать подтверждения провайдера
+	pendingTTL time.Duration
+}
+
+// NewPaymentService создаёт PaymentService с провайдером provider.
+func NewPaymentService(db *gorm.DB, cfg *config.Config, provider payment.PaymentProvider) *PaymentService {
+	return &PaymentService{
+		db:         db,
+		payments:   repositories.NewPaymentRepo(db),
+		orders:     repositories.NewOrderRepo(db),
+		users:      repositories.NewUserRepo(db),
+		provider:   provider,
+		pendingTTL: cfg.Payments.PendingTTL,
+	}
+}
+
+func toPaymentResponse(p *models.Payment) PaymentResponse {
+	return PaymentResponse{
+		ID:            p.ID,
+		OrderID:       p.OrderID,
+		Provider:      p.Provider,
+		ProviderRef:   p.ProviderRef,
+		Amount:        currencyOf(p.Currency).Format(p.AmountMinor),
+		Currency:      p.Currency,
+		Status:        p.Status,
+		FailureReason: p.FailureReason,
+		CreatedAt:     p.CreatedAt,
+		UpdatedAt:     p.UpdatedAt,
+	}
+}
+
+// CheckUser проверяет, что владелец заказов существует.
+// Возвращает ErrNotFound, если пользователя нет.
+func (s *PaymentService) CheckUser(ctx context.Context, userID uint) error {
+	_, err := s.users.GetByID(ctx, userID)
+	return err
+}
+
+// Pay оплачивает заказ пользователя на полную сумму: авторизует платёж у провайдера,
+// списывает его и переводит заказ в paid. Если провайдер обрабатывает платёж
+// асинхронно, возвращается платёж в статусе pending — заказ станет оплаченным
+// по уведомлению провайдера. Платёж, который не удалось довести до конца,
+// отмечается failed; если не удалось и это, его закроет Reconcile.
+func (s *PaymentService) Pay(ctx context.Context, userID, orderID uint, actor Actor, req *PayOrderRequest) (*PaymentResponse, error) {
+	log.Printf("Attempting to pay order ID: %d for user ID: %d", orderID, userID)
+	o, err := s.orders.GetByID(ctx, orderID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && o.UserID != userID) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	p := &models.Payment{
+		OrderID:  o.ID,
+		UserID:   o.UserID,
+		Provider: s.provider.Name(),
+		Status:   models.PaymentStatusPending,
+	}
+	// заказ блокируется, чтобы сумма не изменилась, пока создаётся платёж
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if o, err = lockOrder(ctx, repositories.NewOrderRepo(tx), orderID); err != nil {
+			return err
+		}
+		if o.Status != models.OrderStatusPending {
+			return ErrOrderNotPayable
+		}
+		if err := checkNoPayment(ctx, tx, o.ID); err != nil {
+			return err
+		}
+		p.AmountMinor, p.Currency = o.TotalMinor, o.Currency
+		return repositories.NewPaymentRepo(tx).Create(ctx, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.provider.Authorize(ctx, payment.AuthorizeRequest{
+		Reference:   fmt.Sprintf("payment-%d", p.ID),
+		AmountMinor: p.AmountMinor,
+		Currency:    p.Currency,
+		Method:      req.Method,
+	})
+	if err != nil {
+		return nil, s.fail(ctx, p, models.PaymentStatusPending, err)
+	}
+	p.ProviderRef, p.Status = res.ProviderRef, res.Status
+	if _, err := s.payments.UpdateStatus(ctx, p, models.PaymentStatusPending); err != nil {
+		log.Printf("Error saving payment ID: %d: %v", p.ID, err)
+		s.markFailed(ctx, p, models.PaymentStatusPending, err.Error())
+		return nil, err
+	}
+	if res.Status == payment.StatusPending {
+		log.Printf("Payment ID: %d for order ID: %d is pending at provider", p.ID, o.ID)
+		resp := toPaymentResponse(p)
+		return &resp, nil
+	}
+
+	if _, err := s.provider.Capture(ctx, p.ProviderRef, p.AmountMinor); err != nil {
+		return nil, s.fail(ctx, p, models.PaymentStatusAuthorized, err)
+	}
+	if err := s.capture(ctx, p, actor); err != nil {
+		return nil, err
+	}
+	log.Printf("Order ID: %d paid with payment ID: %d", o.ID, p.ID)
+	resp := toPaymentResponse(p)
+	return &resp, nil
+}
+
+// ListByOrder возвращает попытки оплаты заказа пользователя.
+func (s *PaymentService) ListByOrder(ctx context.Context, userID, orderID uint) ([]PaymentResponse, error) {
+	o, err := s.orders.GetByID(ctx, orderID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && o.UserID != userID) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.payments.ListByOrder(ctx, o.ID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]PaymentResponse, len(list))
+	for i := range list {
+		out[i] = toPaymentResponse(&list[i])
+	}
+	return out, nil
+}
+
+// HandleWebhook проверяет подпись уведомления провайдера и применяет его к платежу:
+//   - EventCaptured — платёж списан, заказ переходит в paid. Сумма и валюта уведомления
+//     должны совпадать с платежом, иначе возвращается ErrInvalidWebhook. Если заказ
+//     уже не ждёт оплаты или платёж истёк (см. Reconcile), списанное возвращается;
+//   - EventFailed — платёж не прошёл, заказ можно оплатить снова;
+//   - EventRefunded — провайдер вернул платёж целиком. Статус заказа при этом не меняется:
+//     возврат на стороне провайдера (по претензии плательщика, после доставки) не означает
+//     отмену заказа — это решает администратор, а отмена оплаченного заказа сама делает возврат.
+//
+// Повторное уведомление с тем же ID ничего не меняет; уведомление и изменения по нему
+// записываются в одной транзакции, поэтому после сбоя провайдер может прислать его снова.
+// Возвращает ErrPaymentNotFound, если платёж неизвестен.
+func (s *PaymentService) HandleWebhook(ctx context.Context, header http.Header, payload []byte) error {
+	e, err := s.provider.VerifyWebhook(header, payload)
+	if err != nil {
+		return err
+	}
+	log.Printf("Attempting to process payment webhook %s (%s) for %s", e.ID, e.Type, e.ProviderRef)
+	p, err := s.payments.GetByProviderRef(ctx, s.provider.Name(), e.ProviderRef)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPaymentNotFound
+		}
+		return err
+	}
+	if e.Type == payment.EventCaptured &&
+		(e.AmountMinor != p.AmountMinor || strings.ToUpper(e.Currency) != p.Currency) {
+		log.Printf("Payment webhook %s: amount %d %s does not match payment ID: %d", e.ID, e.AmountMinor, e.Currency, p.ID)
+		return fmt.Errorf("%w: сумма %d %s не совпадает с платежом", ErrInvalidWebhook, e.AmountMinor, e.Currency)
+	}
+	fresh := false
+	refundFrom := ""
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		payments := repositories.NewPaymentRepo(tx)
+		var err error
+		fresh, err = payments.RecordEvent(ctx, &models.PaymentEvent{
+			Provider:  p.Provider,
+			EventID:   e.ID,
+			Type:      e.Type,
+			PaymentID: &p.ID,
+		})
+		if err != nil || !fresh {
+			return err
+		}
+		switch e.Type {
+		case payment.EventCaptured:
+			refundFrom, err = settleCaptured(ctx, tx, p, Actor{Role: paymentActorRole})
+			return err
+		case payment.EventFailed:
+			p.Status, p.FailureReason = models.PaymentStatusFailed, truncate(e.Reason, 255)
+			_, err := payments.UpdateStatus(ctx, p, models.PaymentStatusPending, models.PaymentStatusAuthorized)
+			return err
+		case payment.EventRefunded:
+			if e.AmountMinor < p.AmountMinor {
+				log.Printf("Payment webhook %s: partial refund of payment ID: %d ignored", e.ID, p.ID)
+				return nil
+			}
+			p.Status = models.PaymentStatusRefunded
+			_, err := payments.UpdateStatus(ctx, p, models.PaymentStatusCaptured)
+			return err
+		}
+		log.Printf("Payment webhook %s: unknown type %s ignored", e.ID, e.Type)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error processing payment webhook %s: %v", e.ID, err)
+		return err
+	}
+	if !fresh {
+		log.Printf("Payment webhook %s already processed", e.ID)
+		return nil
+	}
+	if refundFrom != "" {
+		return s.refund(ctx, p, refundFrom)
+	}
+	return nil
+}
+
+// Reconcile закрывает платежи, которые дольше PAYMENT_PENDING_TTL ждут провайдера
+// (уведомление не пришло, процесс упал между вызовами провайдера), и повторяет
+// невыполненные возвраты по отменённым заказам. Ошибки по отдельным платежам
+// записываются в лог и не прерывают сверку.
+func (s *PaymentService) Reconcile(ctx context.Context, now time.Time) error {
+	stale, err := s.payments.ListStale(ctx, now.Add(-s.pendingTTL))
+	if err != nil {
+		return err
+	}
+	for i := range stale {
+		if err := s.expire(ctx, &stale[i]); err != nil {
+			log.Printf("Error expiring payment ID: %d: %v", stale[i].ID, err)
+		}
+	}
+	refunds, err := s.payments.ListCapturedForCancelled(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range refunds {
+		if err := s.refund(ctx, &refunds[i], models.PaymentStatusCaptured); err != nil {
+			log.Printf("Error refunding payment ID: %d: %v", refunds[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// RunReconcile периодически вызывает Reconcile, пока не отменён ctx.
+func (s *PaymentService) RunReconcile(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.Reconcile(ctx, now); err != nil {
+				log.Printf("[payments] ошибка сверки платежей: %v", err)
+			}
+		}
+	}
+}
+
+// capture отмечает списание платежа p и переводит заказ в paid. Если заказ
+// тем временем отменили или платёж истёк, списанная сумма возвращается
+// и возвращается ErrOrderNotPayable.
+func (s *PaymentService) capture(ctx context.Context, p *models.Payment, actor Actor) error {
+	refundFrom := ""
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		refundFrom, err = settleCaptured(ctx, tx, p, actor)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if refundFrom == "" {
+		return nil
+	}
+	if err := s.refund(ctx, p, refundFrom); err != nil {
+		return err
+	}
+	return ErrOrderNotPayable
+}
+
+// expire закрывает платёж p, зависший в ожидании провайдера. Авторизованный платёж
+// мог быть уже списан (процесс упал после Capture), поэтому сначала он возвращается;
+// если провайдеру нечего возвращать, платёж просто отмечается failed.
+func (s *PaymentService) expire(ctx context.Context, p *models.Payment) error {
+	log.Printf("Expiring payment ID: %d for order ID: %d in status %s", p.ID, p.OrderID, p.Status)
+	if p.Status == models.PaymentStatusAuthorized && p.ProviderRef != "" {
+		_, err := s.provider.Refund(ctx, p.ProviderRef, p.AmountMinor)
+		switch {
+		case err == nil:
+			p.Status = models.PaymentStatusRefunded
+			_, err = s.payments.UpdateStatus(ctx, p, models.PaymentStatusAuthorized)
+			return err
+		case !errors.Is(err, payment.ErrInvalidState):
+			return fmt.Errorf("%w: %v", ErrPaymentProvider, err)
+		}
+	}
+	return s.markFailed(ctx, p, p.Status, expiredReason)
+}
+
+// refund возвращает плательщику платёж p, списанный по уже не ждущему оплаты заказу,
+// и отмечает его refunded, если платёж всё ещё в статусе from. Платежи, отмеченные
+// вручную, провайдеру не отправляются.
+func (s *PaymentService) refund(ctx context.Context, p *models.Payment, from string) error {
+	log.Printf("Refunding payment ID: %d for order ID: %d", p.ID, p.OrderID)
+	if p.Provider != manualProvider {
+		if _, err := s.provider.Refund(ctx, p.ProviderRef, p.AmountMinor); err != nil {
+			log.Printf("Error refunding payment ID: %d: %v", p.ID, err)
+			return fmt.Errorf("%w: %v", ErrPaymentProvider, err)
+		}
+	}
+	p.Status = models.PaymentStatusRefunded
+	_, err := s.payments.UpdateStatus(ctx, p, from)
+	return err
+}
+
+// refundOrder возвращает списанные платежи отменённого заказа. Если провайдер
+// недоступен, возврат повторит Reconcile.
+func (s *PaymentService) refundOrder(ctx context.Context, orderID uint) error {
+	list, err := s.payments.ListByOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		if list[i].Status != models.PaymentStatusCaptured {
+			continue
+		}
+		if err := s.refund(ctx, &list[i], models.PaymentStatusCaptured); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fail отмечает платёж p неудавшимся и возвращает ошибку для клиента:
+// ErrPaymentDeclined, если платёж отклонён, иначе ErrPaymentProvider.
+func (s *PaymentService) fail(ctx context.Context, p *models.Payment, from string, cause error) error {
+	log.Printf("Payment ID: %d failed: %v", p.ID, cause)
+	if err := s.markFailed(ctx, p, from, cause.Error()); err != nil {
+		return err
+	}
+	if errors.Is(cause, payment.ErrDeclined) {
+		return cause
+	}
+	return fmt.Errorf("%w: %v", ErrPaymentProvider, cause)
+}
+
+// markFailed отмечает платёж p, находящийся в статусе from, неудавшимся с причиной reason.
+func (s *PaymentService) markFailed(ctx context.Context, p *models.Payment, from, reason string) error {
+	p.Status, p.FailureReason = models.PaymentStatusFailed, truncate(reason, 255)
+	_, err := s.payments.UpdateStatus(ctx, p, from)
+	if err != nil {
+		log.Printf("Error marking payment ID: %d failed: %v", p.ID, err)
+	}
+	return err
+}
+
+// settleCaptured отмечает платёж p списанным и переводит его заказ в paid.
+// Возвращает статус, из которого платёж нужно вернуть плательщику: captured, если
+// заказ уже не ждёт оплаты, или failed, если платёж успел истечь; пустую строку,
+// если возвращать нечего. Вызывается внутри транзакции.
+func settleCaptured(ctx context.Context, tx *gorm.DB, p *models.Payment, actor Actor) (string, error) {
+	payments := repositories.NewPaymentRepo(tx)
+	p.Status = models.PaymentStatusCaptured
+	ok, err := payments.UpdateStatus(ctx, p, models.PaymentStatusPending, models.PaymentStatusAuthorized)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		cur, err := payments.GetByID(ctx, p.ID)
+		if err != nil {
+			return "", err
+		}
+		p.Status, p.FailureReason = cur.Status, cur.FailureReason
+		if cur.Status == models.PaymentStatusFailed {
+			return models.PaymentStatusFailed, nil
+		}
+		return "", nil
+	}
+	paid, err := markPaid(ctx, tx, p, actor)
+	if err != nil || paid {
+		return "", err
+	}
+	return models.PaymentStatusCaptured, nil
+}
+
+// recordManualPayment записывает оплату заказа o, которую администратор отметил
+// вручную: платёж провайдера manual на полную сумму. Вызывается внутри транзакции.
+func recordManualPayment(ctx context.Context, tx *gorm.DB, o *repositories.Order) error {
+	return repositories.NewPaymentRepo(tx).Create(ctx, &models.Payment{
+		OrderID:     o.ID,
+		UserID:      o.UserID,
+		Provider:    manualProvider,
+		AmountMinor: o.TotalMinor,
+		Currency:    o.Currency,
+		Status:      models.PaymentStatusCaptured,
+	})
+}
+
+// markPaid переводит заказ оплаченного платежа p из pending в paid и записывает
+// переход в историю. Возвращает false, если заказ уже не ждёт оплаты.
+func markPaid(ctx context.Context, tx *gorm.DB, p *models.Payment, actor Actor) (bool, error) {
+	return repositories.NewOrderRepo(tx).ChangeStatus(ctx, &models.OrderStatusChange{
+		OrderID:    p.OrderID,
+		FromStatus: models.OrderStatusPending,
+		ToStatus:   models.OrderStatusPaid,
+		ActorID:    actor.UserID,
+		ActorRole:  actor.Role,
+		Comment:    fmt.Sprintf("платёж #%d, %s %s", p.ID, currencyOf(p.Currency).Format(p.AmountMinor), p.Currency),
+	})
+}
+
+// truncate обрезает строку до n символов.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
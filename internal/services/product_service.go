@@ -0,0 +1,279 @@
+// product_service.go
+// Этот файл содержит бизнес-логику каталога товаров.
+// Каталогом управляет администратор; заказы ссылаются на товары по ID или артикулу.
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"kvant_task/internal/config"
+	"kvant_task/internal/models"
+	"kvant_task/internal/money"
+	"kvant_task/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrProductNotFound ошибка, если товара нет в каталоге.
+	ErrProductNotFound = errors.New("товар не найден")
+	// ErrSKUTaken ошибка, если артикул уже занят другим товаром.
+	ErrSKUTaken = errors.New("товар с таким артикулом уже существует")
+	// ErrInvalidProduct ошибка, если цена или валюта товара некорректны.
+	ErrInvalidProduct = errors.New("некорректные данные товара")
+)
+
+// CreateProductRequest данные для создания товара.
+// Цена — десятичная строка, знаков после точки не больше, чем у валюты.
+type CreateProductRequest struct {
+	SKU   string `json:"sku" binding:"required,max=64"`
+	Name  string `json:"name" binding:"required,max=255"`
+	Price string `json:"price" binding:"required" example:"19.99"`
+	// Код валюты ISO 4217; по умолчанию — DEFAULT_CURRENCY
+	Currency string `json:"currency" binding:"omitempty,len=3" example:"RUB"`
+	Stock    int    `json:"stock" binding:"gte=0"`
+	// Налоговая категория (TAX_CATEGORIES); пусто — ставка по умолчанию
+	TaxCategory string `json:"tax_category" binding:"max=32" example:"reduced"`
+	// Вес единицы в граммах, для расчёта доставки
+	WeightGrams int `json:"weight_grams" binding:"gte=0" example:"350"`
+}
+
+// UpdateProductRequest данные для изменения товара; незаданные поля не меняются.
+// При смене валюты нужно указать и цену.
+type UpdateProductRequest struct {
+	SKU      *string `json:"sku" binding:"omitempty,min=1,max=64"`
+	Name     *string `json:"name" binding:"omitempty,min=1,max=255"`
+	Price    *string `json:"price" binding:"omitempty,min=1" example:"19.99"`
+	Currency *string `json:"currency" binding:"omitempty,len=3" example:"RUB"`
+	Stock    *int    `json:"stock" binding:"omitempty,gte=0"`
+	// Пустая строка снимает налоговую категорию
+	TaxCategory *string `json:"tax_category" binding:"omitempty,max=32" example:"reduced"`
+	WeightGrams *int    `json:"weight_grams" binding:"omitempty,gte=0" example:"350"`
+}
+
+// ProductResponse DTO товара
+type ProductResponse struct {
+	ID       uint   `json:"id"`
+	SKU      string `json:"sku"`
+	Name     string `json:"name"`
+	Price    string `json:"price" example:"19.99"`
+	Currency string `json:"currency" example:"RUB"`
+	Stock    int    `json:"stock"`
+	// Налоговая категория; пусто — ставка по умолчанию
+	TaxCategory string    `json:"tax_category"`
+	WeightGrams int       `json:"weight_grams"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ProductService бизнес-логика каталога.
+type ProductService struct {
+	db   *gorm.DB
+	repo *repositories.ProductRepo
+	// валюта товаров, для которых она не указана
+	currency string
+}
+
+// NewProductService создаёт ProductService.
+func NewProductService(db *gorm.DB, cfg *config.Config) *ProductService {
+	return &ProductService{
+		db:       db,
+		repo:     repositories.NewProductRepo(db),
+		currency: cfg.Money.DefaultCurrency,
+	}
+}
+
+func toProductResponse(p *models.Product) ProductResponse {
+	return ProductResponse{
+		ID:          p.ID,
+		SKU:         p.SKU,
+		Name:        p.Name,
+		Price:       currencyOf(p.Currency).Format(p.PriceMinor),
+		Currency:    p.Currency,
+		Stock:       p.Stock,
+		TaxCategory: p.TaxCategory,
+		WeightGrams: p.WeightGrams,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
+
+// Create добавляет товар в каталог.
+func (s *ProductService) Create(ctx context.Context, req *CreateProductRequest) (*ProductResponse, error) {
+	log.Printf("Attempting to create product with SKU: %s", req.SKU)
+	code := req.Currency
+	if code == "" {
+		code = s.currency
+	}
+	cur, price, err := parsePrice(code, req.Price)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSKUFree(ctx, req.SKU, 0); err != nil {
+		return nil, err
+	}
+	p := &models.Product{
+		SKU:         req.SKU,
+		Name:        req.Name,
+		PriceMinor:  price,
+		Currency:    cur.Code,
+		Stock:       req.Stock,
+		TaxCategory: req.TaxCategory,
+		WeightGrams: req.WeightGrams,
+	}
+	if err := s.repo.Create(ctx, p); err != nil {
+		log.Printf("Error creating product: %v", err)
+		return nil, err
+	}
+	log.Printf("Product created successfully with ID: %d", p.ID)
+	resp := toProductResponse(p)
+	return &resp, nil
+}
+
+// Get возвращает товар по ID.
+func (s *ProductService) Get(ctx context.Context, id uint) (*ProductResponse, error) {
+	p, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	resp := toProductResponse(p)
+	return &resp, nil
+}
+
+// List возвращает страницу каталога и общее число товаров.
+func (s *ProductService) List(ctx context.Context, page, limit int) ([]ProductResponse, int64, error) {
+	list, total, err := s.repo.List(ctx, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	out := make([]ProductResponse, len(list))
+	for i := range list {
+		out[i] = toProductResponse(&list[i])
+	}
+	return out, total, nil
+}
+
+// Update меняет заданные поля товара. Новая цена действует для следующих заказов.
+// Товар блокируется на время изменения, чтобы не затереть остаток, списанный
+// параллельно оформленным заказом.
+func (s *ProductService) Update(ctx context.Context, id uint, req *UpdateProductRequest) (*ProductResponse, error) {
+	log.Printf("Attempting to update product with ID: %d", id)
+	var p *models.Product
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		repo := repositories.NewProductRepo(tx)
+		var err error
+		if p, err = repo.LockByID(ctx, id); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrProductNotFound
+			}
+			return err
+		}
+		if err := s.apply(ctx, p, req); err != nil {
+			return err
+		}
+		return repo.Update(ctx, p)
+	})
+	if err != nil {
+		log.Printf("Error updating product: %v", err)
+		return nil, err
+	}
+	resp := toProductResponse(p)
+	return &resp, nil
+}
+
+// apply переносит в товар p заданные поля запроса req.
+func (s *ProductService) apply(ctx context.Context, p *models.Product, req *UpdateProductRequest) error {
+	if req.SKU != nil && *req.SKU != p.SKU {
+		if err := s.checkSKUFree(ctx, *req.SKU, p.ID); err != nil {
+			return err
+		}
+		p.SKU = *req.SKU
+	}
+	if req.Name != nil {
+		p.Name = *req.Name
+	}
+	if req.Currency != nil || req.Price != nil {
+		code := p.Currency
+		if req.Currency != nil {
+			code = *req.Currency
+		}
+		if req.Price == nil {
+			if cur, err := money.Lookup(code); err != nil || cur.Code != p.Currency {
+				return fmt.Errorf("%w: при смене валюты укажите цену", ErrInvalidProduct)
+			}
+		} else {
+			cur, price, err := parsePrice(code, *req.Price)
+			if err != nil {
+				return err
+			}
+			p.Currency, p.PriceMinor = cur.Code, price
+		}
+	}
+	if req.Stock != nil {
+		p.Stock = *req.Stock
+	}
+	if req.TaxCategory != nil {
+		p.TaxCategory = *req.TaxCategory
+	}
+	if req.WeightGrams != nil {
+		p.WeightGrams = *req.WeightGrams
+	}
+	return nil
+}
+
+// Delete удаляет товар из каталога. Уже оформленные заказы его позиции сохраняют.
+func (s *ProductService) Delete(ctx context.Context, id uint) error {
+	log.Printf("Attempting to delete product with ID: %d", id)
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrProductNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// get возвращает товар или ErrProductNotFound.
+func (s *ProductService) get(ctx context.Context, id uint) (*models.Product, error) {
+	p, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// parsePrice разбирает цену в валюте code. Цена должна быть больше нуля.
+func parsePrice(code, price string) (money.Currency, int64, error) {
+	cur, err := money.Lookup(code)
+	if err != nil {
+		return money.Currency{}, 0, fmt.Errorf("%w: %v", ErrInvalidProduct, err)
+	}
+	v, err := cur.Parse(price)
+	if err != nil {
+		return money.Currency{}, 0, fmt.Errorf("%w: %v", ErrInvalidProduct, err)
+	}
+	if v <= 0 {
+		return money.Currency{}, 0, fmt.Errorf("%w: цена должна быть больше нуля", ErrInvalidProduct)
+	}
+	return cur, v, nil
+}
+
+// checkSKUFree проверяет, что артикул не занят другим товаром (кроме товара exceptID).
+func (s *ProductService) checkSKUFree(ctx context.Context, sku string, exceptID uint) error {
+	p, err := s.repo.GetBySKU(ctx, sku)
+	if err == nil && p.ID != exceptID {
+		return ErrSKUTaken
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+// report_service.go
+// Этот файл содержит отчёты по заказам: траты пользователя и выручку по периодам.
+// В отчёты входят оплаченные заказы — в статусах paid, shipped и delivered.
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"kvant_task/internal/models"
+	"kvant_task/internal/money"
+	"kvant_task/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidReport ошибка, если параметры отчёта некорректны.
+var ErrInvalidReport = errors.New("некорректные параметры отчёта")
+
+// maxReportRange — наибольший период отчёта о выручке.
+const maxReportRange = 5 * 366 * 24 * time.Hour
+
+// reportStatuses — статусы заказов, входящих в отчёты.
+var reportStatuses = []string{models.OrderStatusPaid, models.OrderStatusShipped, models.OrderStatusDelivered}
+
+// OrderSummaryResponse сводка заказов пользователя.
+// Суммы в разных валютах не складываются — они приведены по каждой валюте.
+type OrderSummaryResponse struct {
+	UserID     uint  `json:"user_id"`
+	OrderCount int64 `json:"order_count"`
+	// Даты первого и последнего заказа; пусто, если заказов нет
+	FirstOrderAt *time.Time         `json:"first_order_at"`
+	LastOrderAt  *time.Time         `json:"last_order_at"`
+	Currencies   []CurrencySpending `json:"currencies"`
+}
+
+// CurrencySpending траты пользователя в одной валюте.
+type CurrencySpending struct {
+	Currency   string `json:"currency" example:"RUB"`
+	OrderCount int64  `json:"order_count"`
+	TotalSpent string `json:"total_spent" example:"1230.47"`
+	// Средний заказ, округлённый до минимальной единицы валюты
+	AverageOrder string `json:"average_order" example:"410.16"`
+}
+
+// RevenueQuery параметры отчёта о выручке.
+type RevenueQuery struct {
+	// Начало и конец периода включительно: дата (2024-01-31) или RFC 3339
+	From string `form:"from" binding:"required"`
+	To   string `form:"to" binding:"required"`
+	// Разбивка: day, week (с понедельника) или month; по умолчанию day
+	Period   string `form:"period" binding:"omitempty,oneof=day week month"`
+	Currency string `form:"currency" binding:"omitempty,len=3"`
+	// product — разбить выручку по товарам
+	GroupBy string `form:"group_by" binding:"omitempty,oneof=product"`
+}
+
+// RevenueReport отчёт о выручке.
+type RevenueReport struct {
+	Period string `json:"period"`
+	// Границы отчёта: From включительно, To — не включая
+	From    time.Time       `json:"from"`
+	To      time.Time       `json:"to"`
+	GroupBy string          `json:"group_by,omitempty"`
+	Buckets []RevenueBucket `json:"buckets"`
+}
+
+// RevenueBucket выручка за период в одной валюте; при group_by=product — по одному товару.
+type RevenueBucket struct {
+	// Начало периода, UTC
+	Start      time.Time `json:"start"`
+	Currency   string    `json:"currency" example:"RUB"`
+	ProductID  *uint     `json:"product_id,omitempty"`
+	SKU        string    `json:"sku,omitempty"`
+	Product    string    `json:"product,omitempty"`
+	OrderCount int64     `json:"order_count"`
+	// Единиц товара; только при group_by=product
+	Quantity int64 `json:"quantity,omitempty"`
+	// Итого заказов, а при group_by=product — стоимость позиций товара
+	Revenue string `json:"revenue" example:"1230.47"`
+}
+
+// ReportService отчёты по заказам.
+type ReportService struct {
+	repo  *repositories.ReportRepo
+	users *repositories.UserRepo
+}
+
+// NewReportService создаёт ReportService.
+func NewReportService(db *gorm.DB) *ReportService {
+	return &ReportService{
+		repo:  repositories.NewReportRepo(db),
+		users: repositories.NewUserRepo(db),
+	}
+}
+
+// UserSummary возвращает сводку оплаченных заказов пользователя.
+// Возвращает gorm.ErrRecordNotFound, если пользователя нет.
+func (s *ReportService) UserSummary(ctx context.Context, userID uint) (*OrderSummaryResponse, error) {
+	if _, err := s.users.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.UserSpending(ctx, userID, reportStatuses)
+	if err != nil {
+		return nil, err
+	}
+	resp := &OrderSummaryResponse{UserID: userID, Currencies: make([]CurrencySpending, len(rows))}
+	for i, r := range rows {
+		cur := currencyOf(r.Currency)
+		resp.Currencies[i] = CurrencySpending{
+			Currency:     cur.Code,
+			OrderCount:   r.OrderCount,
+			TotalSpent:   cur.Format(r.TotalMinor),
+			AverageOrder: cur.Format(r.AverageMinor),
+		}
+		resp.OrderCount += r.OrderCount
+		if resp.FirstOrderAt == nil || r.FirstOrderAt.Before(*resp.FirstOrderAt) {
+			first := r.FirstOrderAt
+			resp.FirstOrderAt = &first
+		}
+		if resp.LastOrderAt == nil || r.LastOrderAt.After(*resp.LastOrderAt) {
+			last := r.LastOrderAt
+			resp.LastOrderAt = &last
+		}
+	}
+	return resp, nil
+}
+
+// Revenue возвращает выручку оплаченных заказов по периодам.
+func (s *ReportService) Revenue(ctx context.Context, q *RevenueQuery) (*RevenueReport, error) {
+	if q.Period == "" {
+		q.Period = repositories.ReportPeriodDay
+	}
+	from, err := parseFilterTime(q.From, false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: from: %v", ErrInvalidReport, err)
+	}
+	to, err := parseFilterTime(q.To, true)
+	if err != nil {
+		return nil, fmt.Errorf("%w: to: %v", ErrInvalidReport, err)
+	}
+	if !from.Before(*to) {
+		return nil, fmt.Errorf("%w: from должен быть раньше to", ErrInvalidReport)
+	}
+	if to.Sub(*from) > maxReportRange {
+		return nil, fmt.Errorf("%w: период отчёта не больше пяти лет", ErrInvalidReport)
+	}
+	f := &repositories.RevenueFilter{
+		Statuses:  reportStatuses,
+		From:      *from,
+		To:        *to,
+		Period:    q.Period,
+		ByProduct: q.GroupBy == "product",
+	}
+	if q.Currency != "" {
+		cur, err := money.Lookup(q.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidReport, err)
+		}
+		f.Currency = cur.Code
+	}
+	rows, err := s.repo.Revenue(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	report := &RevenueReport{
+		Period:  q.Period,
+		From:    f.From,
+		To:      f.To,
+		GroupBy: q.GroupBy,
+		Buckets: make([]RevenueBucket, len(rows)),
+	}
+	for i, r := range rows {
+		cur := currencyOf(r.Currency)
+		report.Buckets[i] = RevenueBucket{
+			Start:      r.Start,
+			Currency:   cur.Code,
+			ProductID:  r.ProductID,
+			SKU:        r.SKU,
+			Product:    r.Product,
+			OrderCount: r.OrderCount,
+			Quantity:   r.Quantity,
+			Revenue:    cur.Format(r.RevenueMinor),
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,200 @@
+// sessions.go
+// Этот файл содержит управление сессиями пользователя: создание сессии при входе,
+// просмотр активных сессий, их отзыв и отметку последней активности.
+
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"kvant_task/internal/models"
+	"kvant_task/internal/repositories"
+	"kvant_task/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// userAgentMaxLen — сколько символов User-Agent сохраняется в сессии.
+const userAgentMaxLen = 255
+
+// SessionResponse DTO для сессии
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current — сессия, которой принадлежит токен запроса
+	Current bool `json:"current"`
+}
+
+// ListSessions возвращает активные сессии пользователя. currentID — сессия текущего запроса.
+func (s *UserService) ListSessions(ctx context.Context, userID, currentID uint) ([]SessionResponse, error) {
+	list, err := s.sessions.ListActive(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]SessionResponse, len(list))
+	for i, sess := range list {
+		resp[i] = SessionResponse{
+			ID:         sess.ID,
+			IP:         sess.IP,
+			UserAgent:  sess.UserAgent,
+			CreatedAt:  sess.CreatedAt,
+			LastSeenAt: sess.LastSeenAt,
+			ExpiresAt:  sess.ExpiresAt,
+			Current:    sess.ID == currentID,
+		}
+	}
+	return resp, nil
+}
+
+// RevokeSession завершает сессию пользователя: отзывает её refresh-токены и ещё действующие access-токены.
+func (s *UserService) RevokeSession(ctx context.Context, userID, id uint) error {
+	sess, err := s.sessions.GetForUser(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if sess.RevokedAt != nil {
+		return nil
+	}
+	log.Printf("Revoking session ID: %d for user ID: %d", sess.ID, userID)
+	return s.revokeFamily(ctx, sess.FamilyID, time.Now())
+}
+
+// RevokeAllSessions завершает все сессии пользователя («выйти везде»).
+func (s *UserService) RevokeAllSessions(ctx context.Context, userID uint) error {
+	log.Printf("Revoking all sessions for user ID: %d", userID)
+	return s.revokeAllTokens(ctx, userID)
+}
+
+// startSession открывает новую сессию (и новое семейство refresh-токенов) и выпускает её первые токены.
+func (s *UserService) startSession(ctx context.Context, u *models.User, client ClientInfo) (*TokenResponse, error) {
+	family, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	ua := client.UserAgent
+	if r := []rune(ua); len(r) > userAgentMaxLen {
+		ua = string(r[:userAgentMaxLen])
+	}
+	now := time.Now()
+	sess := &models.Session{
+		UserID:     u.ID,
+		FamilyID:   family,
+		IP:         client.IP,
+		UserAgent:  ua,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.tokens.RefreshTTL()),
+	}
+	if err := s.sessions.Create(ctx, sess); err != nil {
+		return nil, err
+	}
+	log.Printf("Session started: id=%d user_id=%d ip=%s", sess.ID, u.ID, client.IP)
+	return s.issueTokens(ctx, u, sess)
+}
+
+// sessionFor возвращает сессию refresh-токена. Для токенов, выпущенных до появления
+// сессий, сессия создаётся по их семейству.
+func (s *UserService) sessionFor(ctx context.Context, rt *models.RefreshToken) (*models.Session, error) {
+	sess, err := s.sessions.GetByFamily(ctx, rt.FamilyID)
+	if err == nil {
+		if sess.RevokedAt != nil {
+			return nil, ErrInvalidRefreshToken
+		}
+		return sess, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	sess = &models.Session{
+		UserID:     rt.UserID,
+		FamilyID:   rt.FamilyID,
+		LastSeenAt: time.Now(),
+		ExpiresAt:  rt.ExpiresAt,
+	}
+	if err := s.sessions.Create(ctx, sess); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// revokeFamily отзывает семейство refresh-токенов, ещё действующие access-токены,
+// выпущенные в нём, и соответствующую сессию.
+func (s *UserService) revokeFamily(ctx context.Context, familyID string, now time.Time) error {
+	live, err := s.refresh.ListLiveAccessByFamily(ctx, familyID, now)
+	if err != nil {
+		return err
+	}
+	for _, rt := range live {
+		if err := s.tokens.Revoke(ctx, rt.AccessJTI, rt.AccessExpiresAt); err != nil {
+			return err
+		}
+	}
+	if err := s.refresh.RevokeFamily(ctx, familyID, now); err != nil {
+		return err
+	}
+	return s.sessions.RevokeFamily(ctx, familyID, now)
+}
+
+// sessionTrackerMaxEntries — размер кэша, после которого из него удаляются устаревшие записи.
+const sessionTrackerMaxEntries = 10000
+
+// SessionTracker обновляет время последней активности сессий.
+// Запись в базу делается не чаще раза в interval на сессию: недавние отметки
+// хранятся в памяти, а условный UPDATE не даёт нескольким экземплярам
+// сервиса писать одну и ту же сессию чаще.
+type SessionTracker struct {
+	repo     *repositories.SessionRepo
+	interval time.Duration
+	now      func() time.Time
+
+	mu   sync.Mutex
+	seen map[uint]time.Time
+}
+
+// NewSessionTracker конструктор
+func NewSessionTracker(db *gorm.DB, interval time.Duration) *SessionTracker {
+	return &SessionTracker{
+		repo:     repositories.NewSessionRepo(db),
+		interval: interval,
+		now:      time.Now,
+		seen:     make(map[uint]time.Time),
+	}
+}
+
+// Touch отмечает активность сессии id.
+func (t *SessionTracker) Touch(ctx context.Context, id uint) error {
+	if !t.due(id) {
+		return nil
+	}
+	now := t.now()
+	return t.repo.Touch(ctx, id, now, now.Add(-t.interval))
+}
+
+// due сообщает, пора ли записать активность сессии, и запоминает момент записи.
+func (t *SessionTracker) due(id uint) bool {
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if last, ok := t.seen[id]; ok && now.Sub(last) < t.interval {
+		return false
+	}
+	t.seen[id] = now
+	if len(t.seen) > sessionTrackerMaxEntries {
+		for sid, last := range t.seen {
+			if now.Sub(last) >= t.interval {
+				delete(t.seen, sid)
+			}
+		}
+	}
+	return true
+}
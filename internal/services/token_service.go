@@ -0,0 +1,337 @@
+// token_service.go
+// Этот файл содержит единый сервис выпуска и проверки JWT.
+// Логин, middleware и тесты используют его, поэтому набор claims и алгоритм везде одинаковы.
+
+package services
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"kvant_task/internal/config"
+	"kvant_task/internal/models"
+	"kvant_task/internal/revocation"
+	"kvant_task/internal/utils"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	// ErrInvalidToken ошибка, если токен не прошёл проверку подписи, срока или claims.
+	ErrInvalidToken = errors.New("некорректный токен")
+	// ErrTokenRevoked ошибка, если токен отозван.
+	ErrTokenRevoked = errors.New("токен отозван")
+)
+
+// Способы аутентификации (claim amr, RFC 8176).
+const (
+	// AMRPassword — вход по паролю
+	AMRPassword = "pwd"
+	// AMROTP — подтверждение одноразовым кодом второго фактора
+	AMROTP = "otp"
+)
+
+// challengeAudience — суффикс aud challenge-токена второго фактора.
+// Из-за другого aud challenge-токен не принимается как access-токен.
+const challengeAudience = "/2fa"
+
+// Claims — claims access-токена.
+// Помимо стандартных sub, iat, nbf, exp, iss, aud и jti содержит роль пользователя,
+// способы, которыми он подтвердил вход, и ID сессии.
+type Claims struct {
+	Role      string   `json:"role,omitempty"`
+	AMR       []string `json:"amr,omitempty"`
+	SessionID uint     `json:"sid,omitempty"`
+	jwt.StandardClaims
+}
+
+// HasAMR проверяет, что вход подтверждён способом method.
+func (c *Claims) HasAMR(method string) bool {
+	for _, m := range c.AMR {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// UserID возвращает ID пользователя из claim'а sub.
+func (c *Claims) UserID() (uint, error) {
+	id, err := strconv.ParseUint(c.Subject, 10, 64)
+	if err != nil || id == 0 {
+		return 0, ErrInvalidToken
+	}
+	return uint(id), nil
+}
+
+// ExpiresAtTime возвращает срок действия токена.
+func (c *Claims) ExpiresAtTime() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+// TokenService выпускает и проверяет access-токены.
+// В режиме HS256 используется общий секрет, в режимах RS256 и EdDSA — закрытый ключ
+// для подписи и набор публичных ключей (по kid) для проверки, что позволяет ротацию.
+type TokenService struct {
+	method       jwt.SigningMethod
+	signKey      interface{}
+	kid          string
+	keys         map[string]verificationKey
+	validMethods []string
+	jwks         JWKSet
+	issuer       string
+	audience     string
+	accessTTL    time.Duration
+	refreshTTL   time.Duration
+	challengeTTL time.Duration
+	revoked      revocation.Store
+	// роли, права которых действуют только при входе со вторым фактором
+	twoFactorRoles []string
+}
+
+// NewTokenService создаёт TokenService из конфигурации и загружает ключи.
+func NewTokenService(cfg *config.Config, revoked revocation.Store) (*TokenService, error) {
+	s := &TokenService{
+		keys:       make(map[string]verificationKey),
+		jwks:       JWKSet{Keys: []JWK{}},
+		issuer:     cfg.JWT.Issuer,
+		audience:   cfg.JWT.Audience,
+		accessTTL:  cfg.JWT.AccessTTL,
+		refreshTTL: cfg.JWT.RefreshTTL,
+		revoked:    revoked,
+
+		challengeTTL: cfg.Auth.TwoFactorChallengeTTL,
+	}
+	if cfg.Auth.RequireTwoFactorForAdmins {
+		s.twoFactorRoles = []string{models.RoleAdmin}
+	}
+
+	if cfg.JWT.Algorithm == "" || cfg.JWT.Algorithm == jwt.SigningMethodHS256.Alg() {
+		// режим обратной совместимости: симметричный секрет, публичных ключей нет
+		s.method = jwt.SigningMethodHS256
+		s.signKey = []byte(cfg.JWT.Secret)
+		s.kid = cfg.JWT.SigningKeyID
+		s.validMethods = []string{s.method.Alg()}
+		return s, nil
+	}
+
+	signer, err := loadPrivateKey(cfg.JWT.SigningKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	method, err := methodForKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	if method.Alg() != cfg.JWT.Algorithm {
+		return nil, fmt.Errorf("ключ подписи не подходит для алгоритма %s", cfg.JWT.Algorithm)
+	}
+	s.method = method
+	s.signKey = signer
+	s.kid = cfg.JWT.SigningKeyID
+	if s.kid == "" {
+		if s.kid, err = keyThumbprint(signer.Public()); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.addVerificationKey(s.kid, signer.Public()); err != nil {
+		return nil, err
+	}
+
+	// ключи, которые ещё принимаются во время ротации; сортируем для стабильного JWKS
+	kids := make([]string, 0, len(cfg.JWT.VerificationKeyFiles))
+	for kid := range cfg.JWT.VerificationKeyFiles {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	for _, kid := range kids {
+		pub, err := loadPublicKey(cfg.JWT.VerificationKeyFiles[kid])
+		if err != nil {
+			return nil, err
+		}
+		if err := s.addVerificationKey(kid, pub); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// addVerificationKey регистрирует публичный ключ для проверки и публикации в JWKS.
+func (s *TokenService) addVerificationKey(kid string, pub crypto.PublicKey) error {
+	if _, exists := s.keys[kid]; exists {
+		return fmt.Errorf("ключ с kid %q задан дважды", kid)
+	}
+	method, err := methodForKey(pub)
+	if err != nil {
+		return err
+	}
+	jwk, err := toJWK(kid, pub)
+	if err != nil {
+		return err
+	}
+	s.keys[kid] = verificationKey{method: method, key: pub}
+	s.jwks.Keys = append(s.jwks.Keys, jwk)
+	for _, alg := range s.validMethods {
+		if alg == method.Alg() {
+			return nil
+		}
+	}
+	s.validMethods = append(s.validMethods, method.Alg())
+	return nil
+}
+
+// JWKS возвращает публичные ключи проверки. В режиме HS256 набор пуст.
+func (s *TokenService) JWKS() JWKSet {
+	return s.jwks
+}
+
+// AccessTTL возвращает время жизни access-токена.
+func (s *TokenService) AccessTTL() time.Duration {
+	return s.accessTTL
+}
+
+// RefreshTTL возвращает время жизни refresh-токена.
+func (s *TokenService) RefreshTTL() time.Duration {
+	return s.refreshTTL
+}
+
+// ChallengeTTL возвращает время жизни challenge-токена второго фактора.
+func (s *TokenService) ChallengeTTL() time.Duration {
+	return s.challengeTTL
+}
+
+// Issue выпускает access-токен для пользователя.
+// amr перечисляет способы, которыми пользователь подтвердил вход.
+func (s *TokenService) Issue(userID uint, role string, amr ...string) (string, *Claims, error) {
+	return s.IssueForSession(userID, 0, role, amr...)
+}
+
+// IssueForSession выпускает access-токен, привязанный к сессии sessionID.
+func (s *TokenService) IssueForSession(userID, sessionID uint, role string, amr ...string) (string, *Claims, error) {
+	claims, err := s.newClaims(userID, s.audience, s.accessTTL)
+	if err != nil {
+		return "", nil, err
+	}
+	claims.Role = role
+	claims.AMR = amr
+	claims.SessionID = sessionID
+	return s.sign(claims)
+}
+
+// IssueChallenge выпускает короткоживущий challenge-токен: пароль проверен,
+// и его можно обменять на access-токен вместе с кодом второго фактора.
+func (s *TokenService) IssueChallenge(userID uint) (string, *Claims, error) {
+	claims, err := s.newClaims(userID, s.audience+challengeAudience, s.challengeTTL)
+	if err != nil {
+		return "", nil, err
+	}
+	claims.AMR = []string{AMRPassword}
+	return s.sign(claims)
+}
+
+// newClaims заполняет стандартные claims.
+func (s *TokenService) newClaims(userID uint, audience string, ttl time.Duration) (*Claims, error) {
+	jti, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &Claims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(ttl).Unix(),
+			Issuer:    s.issuer,
+			Audience:  audience,
+			Id:        jti,
+		},
+	}, nil
+}
+
+// sign подписывает claims текущим ключом.
+func (s *TokenService) sign(claims *Claims) (string, *Claims, error) {
+	token := jwt.NewWithClaims(s.method, claims)
+	if s.kid != "" {
+		token.Header["kid"] = s.kid
+	}
+	tok, err := token.SignedString(s.signKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return tok, claims, nil
+}
+
+// Validate проверяет алгоритм, подпись, сроки, iss, aud и отзыв токена.
+func (s *TokenService) Validate(ctx context.Context, raw string) (*Claims, error) {
+	return s.validate(ctx, raw, s.audience)
+}
+
+// ValidateChallenge проверяет challenge-токен второго фактора.
+func (s *TokenService) ValidateChallenge(ctx context.Context, raw string) (*Claims, error) {
+	return s.validate(ctx, raw, s.audience+challengeAudience)
+}
+
+// EffectiveRole возвращает роль, с которой выполняется запрос.
+// Токены без роли выпущены до появления ролей. Если для роли требуется второй фактор,
+// а вход выполнен только по паролю, пользователь действует с правами обычного.
+func (s *TokenService) EffectiveRole(c *Claims) string {
+	if c.Role == "" {
+		return models.RoleUser
+	}
+	for _, r := range s.twoFactorRoles {
+		if c.Role == r && !c.HasAMR(AMROTP) {
+			return models.RoleUser
+		}
+	}
+	return c.Role
+}
+
+func (s *TokenService) validate(ctx context.Context, raw, audience string) (*Claims, error) {
+	parser := &jwt.Parser{ValidMethods: s.validMethods}
+	claims := &Claims{}
+	token, err := parser.ParseWithClaims(raw, claims, s.keyFunc)
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	if !claims.VerifyIssuer(s.issuer, true) || !claims.VerifyAudience(audience, true) || claims.Id == "" {
+		return nil, ErrInvalidToken
+	}
+	if _, err := claims.UserID(); err != nil {
+		return nil, err
+	}
+	revoked, err := s.revoked.IsRevoked(ctx, claims.Id)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// keyFunc выбирает ключ проверки по заголовку kid и сверяет алгоритм токена с алгоритмом ключа.
+func (s *TokenService) keyFunc(t *jwt.Token) (interface{}, error) {
+	if s.method == jwt.SigningMethodHS256 {
+		if t.Method.Alg() != s.method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		return s.signKey, nil
+	}
+	kid, _ := t.Header["kid"].(string)
+	k, ok := s.keys[kid]
+	if !ok || t.Method.Alg() != k.method.Alg() {
+		return nil, ErrInvalidToken
+	}
+	return k.key, nil
+}
+
+// Revoke отзывает токен с указанным jti до истечения его срока.
+func (s *TokenService) Revoke(ctx context.Context, jti string, exp time.Time) error {
+	return s.revoked.Revoke(ctx, jti, exp)
+}
@@ -0,0 +1,308 @@
+// two_factor.go
+// Этот файл содержит двухфакторную аутентификацию по TOTP:
+// подключение, второй шаг входа, коды восстановления и отключение.
+
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"kvant_task/internal/models"
+	"kvant_task/internal/totp"
+	"kvant_task/internal/utils"
+)
+
+// recoveryCodeCount — сколько кодов восстановления выдаётся за раз.
+const recoveryCodeCount = 10
+
+var (
+	// ErrTwoFactorEnabled ошибка, если 2FA уже включена.
+	ErrTwoFactorEnabled = errors.New("двухфакторная аутентификация уже включена")
+	// ErrTwoFactorNotEnabled ошибка, если 2FA не включена или не подключена.
+	ErrTwoFactorNotEnabled = errors.New("двухфакторная аутентификация не включена")
+	// ErrInvalidTwoFactorCode ошибка, если код TOTP или код восстановления неверен.
+	ErrInvalidTwoFactorCode = errors.New("неверный код подтверждения")
+	// ErrInvalidChallenge ошибка, если challenge-токен недействителен или истёк.
+	ErrInvalidChallenge = errors.New("недействительный или истёкший challenge-токен, войдите заново")
+)
+
+// LoginResponse ответ на логин: пара токенов либо, при включённой 2FA, challenge-токен,
+// который обменивается на токены через POST /auth/login/2fa.
+type LoginResponse struct {
+	*TokenResponse
+	TwoFactorRequired  bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken     string `json:"challenge_token,omitempty"`
+	ChallengeExpiresIn int64  `json:"challenge_expires_in,omitempty"`
+}
+
+// TwoFactorLoginRequest данные для второго шага входа
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	// Code — код из приложения-аутентификатора или код восстановления
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorCodeRequest код второго фактора
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorDisableRequest данные для отключения 2FA
+type TwoFactorDisableRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// TwoFactorEnrollResponse секрет для приложения-аутентификатора
+type TwoFactorEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesResponse одноразовые коды восстановления; показываются один раз
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// EnrollTwoFactor создаёт новый секрет TOTP. 2FA включается только после
+// подтверждения кодом через ConfirmTwoFactor.
+func (s *UserService) EnrollTwoFactor(ctx context.Context, userID uint) (*TwoFactorEnrollResponse, error) {
+	u, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	u.TOTPSecret = &secret
+	if err := s.repo.Update(ctx, u); err != nil {
+		return nil, err
+	}
+	log.Printf("2FA enrollment started for user ID: %d", u.ID)
+	return &TwoFactorEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(s.totpIssuer, u.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor включает 2FA после проверки первого кода и выдаёт коды восстановления.
+func (s *UserService) ConfirmTwoFactor(ctx context.Context, userID uint, req *TwoFactorCodeRequest) (*RecoveryCodesResponse, error) {
+	u, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+	if u.TOTPSecret == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	step, ok := totp.Verify(*u.TOTPSecret, strings.TrimSpace(req.Code), time.Now(), 1)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	codes, hashed, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	u.TOTPEnabledAt = &now
+	u.TOTPLastStep = step
+	u.TOTPRecoveryCodes = hashed
+	if err := s.repo.Update(ctx, u); err != nil {
+		return nil, err
+	}
+	log.Printf("2FA enabled for user ID: %d", u.ID)
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor отключает 2FA. Требует пароль и действующий код второго фактора.
+func (s *UserService) DisableTwoFactor(ctx context.Context, userID uint, req *TwoFactorDisableRequest) error {
+	u, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u.TOTPEnabledAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+	if !s.checkPassword(ctx, u, req.Password, false) {
+		return ErrWrongPassword
+	}
+	if err := s.verifySecondFactor(ctx, u, req.Code); err != nil {
+		return err
+	}
+	u.TOTPSecret = nil
+	u.TOTPEnabledAt = nil
+	u.TOTPLastStep = 0
+	u.TOTPRecoveryCodes = ""
+	if err := s.repo.Update(ctx, u); err != nil {
+		return err
+	}
+	log.Printf("2FA disabled for user ID: %d", u.ID)
+	return nil
+}
+
+// RegenerateRecoveryCodes выдаёт новый набор кодов восстановления; старые перестают действовать.
+func (s *UserService) RegenerateRecoveryCodes(ctx context.Context, userID uint, req *TwoFactorCodeRequest) (*RecoveryCodesResponse, error) {
+	u, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.TOTPEnabledAt == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.verifySecondFactor(ctx, u, req.Code); err != nil {
+		return nil, err
+	}
+	codes, hashed, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.repo.ReplaceRecoveryCodes(ctx, u.ID, u.TOTPRecoveryCodes, hashed)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// LoginTwoFactor завершает вход: обменивает challenge-токен и код второго фактора на токены.
+// Неверные коды учитываются защитой от перебора так же, как неверные пароли.
+func (s *UserService) LoginTwoFactor(ctx context.Context, req *TwoFactorLoginRequest, client ClientInfo) (*TokenResponse, error) {
+	claims, err := s.tokens.ValidateChallenge(ctx, req.ChallengeToken)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrTokenRevoked) {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	u, err := s.repo.GetByID(ctx, userID)
+	if err != nil || u.TOTPEnabledAt == nil {
+		return nil, ErrInvalidChallenge
+	}
+	if err := s.attempts.Check(ctx, u.Email, client.IP); err != nil {
+		return nil, err
+	}
+	if err := s.verifySecondFactor(ctx, u, req.Code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			if ferr := s.attempts.Fail(ctx, u.Email, client.IP); ferr != nil {
+				return nil, ferr
+			}
+		}
+		return nil, err
+	}
+	// challenge одноразовый
+	if err := s.tokens.Revoke(ctx, claims.Id, claims.ExpiresAtTime()); err != nil {
+		return nil, err
+	}
+	if err := s.attempts.Succeed(ctx, u.Email); err != nil {
+		return nil, err
+	}
+	return s.startSession(ctx, u, client)
+}
+
+// challenge выпускает challenge-токен для пользователя с включённой 2FA.
+func (s *UserService) challenge(u *models.User) (*LoginResponse, error) {
+	tok, _, err := s.tokens.IssueChallenge(u.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResponse{
+		TwoFactorRequired:  true,
+		ChallengeToken:     tok,
+		ChallengeExpiresIn: int64(s.tokens.ChallengeTTL().Seconds()),
+	}, nil
+}
+
+// verifySecondFactor принимает код TOTP (6 цифр) или код восстановления.
+// Каждый код действует один раз: шаг TOTP и код восстановления списываются атомарно.
+func (s *UserService) verifySecondFactor(ctx context.Context, u *models.User, code string) error {
+	code = strings.TrimSpace(code)
+	if u.TOTPSecret == nil {
+		return ErrTwoFactorNotEnabled
+	}
+	if len(code) == totp.Digits {
+		step, ok := totp.Verify(*u.TOTPSecret, code, time.Now(), 1)
+		if !ok || step <= u.TOTPLastStep {
+			return ErrInvalidTwoFactorCode
+		}
+		ok, err := s.repo.AdvanceTOTPStep(ctx, u.ID, step)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		u.TOTPLastStep = step
+		return nil
+	}
+
+	var hashes []string
+	if u.TOTPRecoveryCodes != "" {
+		if err := json.Unmarshal([]byte(u.TOTPRecoveryCodes), &hashes); err != nil {
+			return err
+		}
+	}
+	h := utils.HashToken(normalizeRecoveryCode(code))
+	for i, stored := range hashes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(h)) != 1 {
+			continue
+		}
+		rest, err := json.Marshal(append(hashes[:i:i], hashes[i+1:]...))
+		if err != nil {
+			return err
+		}
+		ok, err := s.repo.ReplaceRecoveryCodes(ctx, u.ID, u.TOTPRecoveryCodes, string(rest))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		u.TOTPRecoveryCodes = string(rest)
+		log.Printf("Recovery code used by user ID: %d, left: %d", u.ID, len(hashes)-1)
+		return nil
+	}
+	return ErrInvalidTwoFactorCode
+}
+
+// newRecoveryCodes создаёт коды восстановления вида xxxxx-xxxxx и JSON-массив их хэшей.
+func newRecoveryCodes() ([]string, string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw, err := totp.GenerateSecret()
+		if err != nil {
+			return nil, "", err
+		}
+		c := strings.ToLower(raw[:5] + "-" + raw[5:10])
+		codes[i] = c
+		hashes[i] = utils.HashToken(normalizeRecoveryCode(c))
+	}
+	b, err := json.Marshal(hashes)
+	if err != nil {
+		return nil, "", err
+	}
+	return codes, string(b), nil
+}
+
+// normalizeRecoveryCode убирает дефис и регистр, чтобы код можно было ввести как угодно.
+func normalizeRecoveryCode(c string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(c), "-", ""))
+}
@@ -11,11 +11,14 @@ import (
 	"log"
 	"time"
 
+	"kvant_task/internal/config"
+	"kvant_task/internal/lockout"
 	"kvant_task/internal/models"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/password"
 	"kvant_task/internal/repositories"
+	"kvant_task/internal/utils"
 
-	"github.com/dgrijalva/jwt-go"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
@@ -26,6 +29,20 @@ var (
 	ErrInvalidCredentials = errors.New("неверный email или пароль")
 	// ErrNotFound ошибка, если пользователь не найден.
 	ErrNotFound = gorm.ErrRecordNotFound
+	// ErrInvalidRefreshToken ошибка, если refresh-токен не найден, истёк или отозван.
+	ErrInvalidRefreshToken = errors.New("недействительный refresh-токен")
+	// ErrRefreshTokenReused ошибка, если уже обменянный refresh-токен предъявлен повторно.
+	ErrRefreshTokenReused = errors.New("refresh-токен уже использован, сессия отозвана")
+	// ErrWrongPassword ошибка, если при смене пароля указан неверный текущий пароль.
+	ErrWrongPassword = errors.New("неверный текущий пароль")
+	// ErrInvalidResetToken ошибка, если токен сброса пароля не найден, истёк или уже использован.
+	ErrInvalidResetToken = errors.New("недействительный или истёкший токен сброса пароля")
+	// ErrInvalidVerificationToken ошибка, если токен подтверждения email недействителен.
+	ErrInvalidVerificationToken = errors.New("недействительный или истёкший токен подтверждения email")
+	// ErrEmailNotVerified ошибка, если действие требует подтверждённого email.
+	ErrEmailNotVerified = errors.New("email не подтверждён")
+	// ErrEmailAlreadyVerified ошибка, если подтверждать нечего.
+	ErrEmailAlreadyVerified = errors.New("email уже подтверждён")
 )
 
 // RegisterRequest данные для создания пользователя
@@ -34,16 +51,21 @@ var (
 type RegisterRequest struct {
 	Name     string `json:"name" binding:"required,min=2"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 	Age      int    `json:"age" binding:"required,gt=0"`
 }
 
 // UserResponse данные пользователя в ответе
 type UserResponse struct {
-	ID    uint   `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
-	Age   int    `json:"age"`
+	ID            uint    `json:"id"`
+	Name          string  `json:"name"`
+	Email         string  `json:"email"`
+	EmailVerified bool    `json:"email_verified"`
+	PendingEmail  *string `json:"pending_email,omitempty"`
+	Age           int     `json:"age"`
+	Role          string  `json:"role"`
+	// TwoFactorEnabled — включена ли двухфакторная аутентификация
+	TwoFactorEnabled bool `json:"two_factor_enabled"`
 }
 
 // LoginRequest данные для логина
@@ -52,9 +74,31 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// TokenResponse возвращает JWT
+// ClientInfo сведения о клиенте, выполняющем вход
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// TokenResponse возвращает пару токенов
 type TokenResponse struct {
+	// Access-токен (JWT)
 	Token string `json:"token"`
+	// Refresh-токен для POST /auth/refresh
+	RefreshToken string `json:"refresh_token"`
+	// Время жизни access-токена в секундах
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+// RefreshRequest данные для обновления токенов
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest данные для выхода.
+// Если передан refresh-токен, отзывается и вся его сессия.
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 // UpdateRequest данные для обновления пользователя
@@ -64,6 +108,38 @@ type UpdateRequest struct {
 	Age   *int    `json:"age" binding:"omitempty,gt=0"`
 }
 
+// ChangePasswordRequest данные для смены пароля
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// PasswordResetRequest данные для запроса сброса пароля
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// VerificationResendRequest данные для повторной отправки письма подтверждения без входа
+type VerificationResendRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// PasswordResetConfirmRequest данные для установки нового пароля по токену сброса
+type PasswordResetConfirmRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// VerifyEmailRequest данные для подтверждения email
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// RoleRequest данные для смены роли пользователя
+type RoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user admin"`
+}
+
 // UserFilter фильтры при списке пользователей
 type UserFilter struct {
 	MinAge string
@@ -74,24 +150,56 @@ type UserFilter struct {
 
 // UserService бизнес-логика по пользователям.
 type UserService struct {
-	repo      *repositories.UserRepo
-	jwtSecret string
+	repo     *repositories.UserRepo
+	refresh  *repositories.RefreshTokenRepo
+	sessions *repositories.SessionRepo
+	oneTime  *repositories.OneTimeTokenRepo
+	tokens   *TokenService
+	notifier notify.Notifier
+	attempts *lockout.Guard
+	// хэширование паролей; хэши прежнего алгоритма пересчитываются при входе
+	passwords *password.Manager
+	policy    *password.Policy
+	resetTTL  time.Duration
+	// подтверждение email
+	verifyTTL            time.Duration
+	requireVerifiedLogin bool
+	// название сервиса в приложении-аутентификаторе
+	totpIssuer string
 }
 
 // NewUserService конструктор
-func NewUserService(db *gorm.DB, jwtSecret string) *UserService {
+func NewUserService(db *gorm.DB, cfg *config.Config, tokens *TokenService, notifier notify.Notifier, attempts *lockout.Guard, policy *password.Policy) *UserService {
 	return &UserService{
-		repo:      repositories.NewUserRepo(db),
-		jwtSecret: jwtSecret,
+		repo:     repositories.NewUserRepo(db),
+		refresh:  repositories.NewRefreshTokenRepo(db),
+		sessions: repositories.NewSessionRepo(db),
+		oneTime:  repositories.NewOneTimeTokenRepo(db),
+		tokens:   tokens,
+		notifier: notifier,
+		attempts: attempts,
+
+		passwords: passwordManager(cfg),
+		policy:    policy,
+		resetTTL:  cfg.Auth.PasswordResetTTL,
+
+		verifyTTL:            cfg.Auth.EmailVerificationTTL,
+		requireVerifiedLogin: cfg.Auth.RequireVerifiedEmailToLogin,
+		totpIssuer:           cfg.Auth.TOTPIssuer,
 	}
 }
 
 func toUserResponse(u *models.User) *UserResponse {
 	return &UserResponse{
-		ID:    u.ID,
-		Name:  u.Name,
-		Email: u.Email,
-		Age:   u.Age,
+		ID:            u.ID,
+		Name:          u.Name,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerifiedAt != nil,
+		PendingEmail:  u.PendingEmail,
+		Age:           u.Age,
+		Role:          u.Role,
+
+		TwoFactorEnabled: u.TOTPEnabledAt != nil,
 	}
 }
 
@@ -99,6 +207,9 @@ func toUserResponse(u *models.User) *UserResponse {
 func (s *UserService) Create(ctx context.Context, req *RegisterRequest) (*UserResponse, error) {
 	// Add logging for user creation
 	log.Printf("Attempting to create user with email: %s", req.Email)
+	if err := s.policy.Validate(req.Password, req.Name, req.Email); err != nil {
+		return nil, err
+	}
 	if _, err := s.repo.GetByEmail(ctx, req.Email); err == nil {
 		log.Printf("User with email %s already exists", req.Email)
 		return nil, ErrUserExists
@@ -107,7 +218,7 @@ func (s *UserService) Create(ctx context.Context, req *RegisterRequest) (*UserRe
 		return nil, err
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hash, err := s.passwords.Hash(req.Password)
 	if err != nil {
 		log.Printf("Error generating password hash: %v", err)
 		return nil, err
@@ -117,7 +228,8 @@ func (s *UserService) Create(ctx context.Context, req *RegisterRequest) (*UserRe
 		Name:         req.Name,
 		Email:        req.Email,
 		Age:          req.Age,
-		PasswordHash: string(hash),
+		PasswordHash: hash,
+		Role:         models.RoleUser,
 	}
 	if err := s.repo.Create(ctx, u); err != nil {
 		log.Printf("Error creating user: %v", err)
@@ -125,29 +237,327 @@ func (s *UserService) Create(ctx context.Context, req *RegisterRequest) (*UserRe
 	}
 	log.Printf("User created successfully with ID: %d", u.ID)
 
+	if err := s.sendVerification(ctx, u, u.Email); err != nil {
+		// пользователь уже создан: письмо можно запросить повторно
+		log.Printf("Error sending verification email: %v", err)
+	}
+
 	return toUserResponse(u), nil
 }
 
-// Login проверяет учётные данные и возвращает JWT
-func (s *UserService) Login(ctx context.Context, req *LoginRequest) (*TokenResponse, error) {
+// Login проверяет учётные данные и возвращает access- и refresh-токены.
+// Если у пользователя включена 2FA, вместо токенов возвращается challenge-токен.
+// При превышении числа неудачных попыток возвращает *lockout.LockedError.
+func (s *UserService) Login(ctx context.Context, req *LoginRequest, client ClientInfo) (*LoginResponse, error) {
+	if err := s.attempts.Check(ctx, req.Email, client.IP); err != nil {
+		log.Printf("Login blocked: email=%s ip=%s", req.Email, client.IP)
+		return nil, err
+	}
 	u, err := s.repo.GetByEmail(ctx, req.Email)
-	if (err != nil) {
-		return nil, ErrInvalidCredentials
+	if err == nil && !s.checkPassword(ctx, u, req.Password, true) {
+		err = ErrInvalidCredentials
 	}
-	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(req.Password)) != nil {
+	if err != nil {
+		// неизвестный email считается так же, как неверный пароль
+		if ferr := s.attempts.Fail(ctx, req.Email, client.IP); ferr != nil {
+			return nil, ferr
+		}
 		return nil, ErrInvalidCredentials
 	}
+	if err := s.attempts.Succeed(ctx, req.Email); err != nil {
+		return nil, err
+	}
+	if s.requireVerifiedLogin && u.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
+	}
+	if u.TOTPEnabledAt != nil {
+		return s.challenge(u)
+	}
 
-	// генерируем токен
-	claims := jwt.MapClaims{
-		"user_id": u.ID,
-		"exp":     time.Now().Add(72 * time.Hour).Unix(),
+	tok, err := s.startSession(ctx, u, client)
+	if err != nil {
+		return nil, err
 	}
-	tok, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.jwtSecret))
+	return &LoginResponse{TokenResponse: tok}, nil
+}
+
+// Refresh обменивает refresh-токен на новую пару токенов (ротация).
+// Повторное предъявление уже обменянного токена отзывает всё семейство.
+func (s *UserService) Refresh(ctx context.Context, req *RefreshRequest) (*TokenResponse, error) {
+	rt, err := s.refresh.GetByHash(ctx, utils.HashToken(req.RefreshToken))
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
 		return nil, err
 	}
-	return &TokenResponse{Token: tok}, nil
+	now := time.Now()
+	if rt.RotatedAt != nil {
+		log.Printf("Refresh token reuse detected: user_id=%d family=%s", rt.UserID, rt.FamilyID)
+		if err := s.revokeFamily(ctx, rt.FamilyID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if rt.RevokedAt != nil || now.After(rt.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	ok, err := s.refresh.MarkRotated(ctx, rt.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// параллельный запрос успел обменять этот же токен
+		log.Printf("Concurrent refresh token reuse detected: user_id=%d family=%s", rt.UserID, rt.FamilyID)
+		if err := s.revokeFamily(ctx, rt.FamilyID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	// пользователь мог быть удалён, пока жил refresh-токен;
+	// роль берётся актуальная, поэтому её смена вступает в силу при обновлении
+	u, err := s.repo.GetByID(ctx, rt.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	sess, err := s.sessionFor(ctx, rt)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, u, sess)
+}
+
+// Logout отзывает текущий access-токен и его сессию. Если передан refresh-токen,
+// отзывается и его семейство (для токенов, выпущенных без сессии).
+func (s *UserService) Logout(ctx context.Context, userID, sessionID uint, jti string, exp time.Time, req *LogoutRequest) error {
+	if err := s.RevokeAccessToken(ctx, jti, exp); err != nil {
+		return err
+	}
+	if sessionID != 0 {
+		if err := s.RevokeSession(ctx, userID, sessionID); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	if req == nil || req.RefreshToken == "" {
+		return nil
+	}
+	rt, err := s.refresh.GetByHash(ctx, utils.HashToken(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		return err
+	}
+	if rt.UserID != userID {
+		return ErrInvalidRefreshToken
+	}
+	return s.revokeFamily(ctx, rt.FamilyID, time.Now())
+}
+
+// RevokeAccessToken отзывает access-токен до истечения его срока.
+func (s *UserService) RevokeAccessToken(ctx context.Context, jti string, exp time.Time) error {
+	log.Printf("Revoking access token: jti=%s", jti)
+	return s.tokens.Revoke(ctx, jti, exp)
+}
+
+// revokeAllTokens завершает все сессии пользователя: отзывает его refresh-токены
+// и все ещё действующие access-токены.
+func (s *UserService) revokeAllTokens(ctx context.Context, userID uint) error {
+	now := time.Now()
+	live, err := s.refresh.ListLiveAccess(ctx, userID, now)
+	if err != nil {
+		return err
+	}
+	for _, rt := range live {
+		if err := s.tokens.Revoke(ctx, rt.AccessJTI, rt.AccessExpiresAt); err != nil {
+			return err
+		}
+	}
+	if err := s.refresh.RevokeUser(ctx, userID, now); err != nil {
+		return err
+	}
+	return s.sessions.RevokeUser(ctx, userID, now)
+}
+
+// ChangePassword меняет пароль после проверки текущего и завершает все сессии пользователя.
+func (s *UserService) ChangePassword(ctx context.Context, userID uint, req *ChangePasswordRequest) error {
+	log.Printf("Attempting to change password for user ID: %d", userID)
+	u, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !s.checkPassword(ctx, u, req.CurrentPassword, false) {
+		return ErrWrongPassword
+	}
+	return s.setPassword(ctx, u, req.NewPassword)
+}
+
+// RequestPasswordReset выпускает токен сброса пароля и отправляет его пользователю.
+// Для неизвестного email ничего не делает и не сообщает об этом, чтобы не раскрывать,
+// какие адреса зарегистрированы.
+func (s *UserService) RequestPasswordReset(ctx context.Context, req *PasswordResetRequest) error {
+	u, err := s.repo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Password reset requested for unknown email")
+			return nil
+		}
+		return err
+	}
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(s.resetTTL)
+	if err := s.oneTime.Create(ctx, &models.OneTimeToken{
+		UserID:    u.ID,
+		Purpose:   models.TokenPurposePasswordReset,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return err
+	}
+	log.Printf("Password reset token issued for user ID: %d", u.ID)
+	return s.notifier.Send(ctx, notify.Message{
+		To:      u.Email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf("Токен для сброса пароля: %s\nДействителен до %s. Если вы не запрашивали сброс, проигнорируйте это письмо.",
+			token, expiresAt.Format(time.RFC3339)),
+	})
+}
+
+// ConfirmPasswordReset устанавливает новый пароль по одноразовому токену сброса.
+func (s *UserService) ConfirmPasswordReset(ctx context.Context, req *PasswordResetConfirmRequest) error {
+	t, err := s.oneTime.Consume(ctx, utils.HashToken(req.Token), models.TokenPurposePasswordReset, time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	u, err := s.repo.GetByID(ctx, t.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	return s.setPassword(ctx, u, req.NewPassword)
+}
+
+// setPassword проверяет новый пароль политикой, сохраняет его и отзывает все токены пользователя.
+func (s *UserService) setPassword(ctx context.Context, u *models.User, password string) error {
+	personal := []string{u.Name, u.Email}
+	if u.PendingEmail != nil {
+		personal = append(personal, *u.PendingEmail)
+	}
+	if err := s.policy.Validate(password, personal...); err != nil {
+		return err
+	}
+	hash, err := s.passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = hash
+	if err := s.repo.Update(ctx, u); err != nil {
+		log.Printf("Error updating password: %v", err)
+		return err
+	}
+	if err := s.revokeAllTokens(ctx, u.ID); err != nil {
+		log.Printf("Error revoking tokens after password change: %v", err)
+		return err
+	}
+	log.Printf("Password changed for user ID: %d, all sessions revoked", u.ID)
+	return nil
+}
+
+// checkPassword сравнивает пароль с хэшем пользователя. При rehash хэш прежнего
+// алгоритма или с устаревшими параметрами пересчитывается и сохраняется;
+// ошибка пересчёта не мешает входу.
+func (s *UserService) checkPassword(ctx context.Context, u *models.User, pw string, rehash bool) bool {
+	ok, outdated, err := s.passwords.Verify(pw, u.PasswordHash)
+	if err != nil {
+		log.Printf("Error verifying password hash for user ID: %d: %v", u.ID, err)
+		return false
+	}
+	if !ok || !rehash || !outdated {
+		return ok
+	}
+	hash, err := s.passwords.Hash(pw)
+	if err == nil {
+		err = s.repo.ReplacePasswordHash(ctx, u.ID, u.PasswordHash, hash)
+	}
+	if err != nil {
+		log.Printf("Error rehashing password for user ID: %d: %v", u.ID, err)
+		return true
+	}
+	log.Printf("Password rehashed for user ID: %d", u.ID)
+	u.PasswordHash = hash
+	return true
+}
+
+// passwordManager хэширует пароли выбранным в настройках алгоритмом и проверяет хэши обоих.
+func passwordManager(cfg *config.Config) *password.Manager {
+	argon := password.Argon2id{
+		Memory:      uint32(cfg.Password.Argon2Memory),
+		Iterations:  uint32(cfg.Password.Argon2Iterations),
+		Parallelism: uint8(cfg.Password.Argon2Parallelism),
+	}
+	bc := password.Bcrypt{Cost: cfg.Password.BcryptCost}
+	if cfg.Password.Algorithm == "bcrypt" {
+		return password.NewManager(bc, argon)
+	}
+	return password.NewManager(argon, bc)
+}
+
+// issueTokens выпускает access-токен и новый refresh-токен в семействе сессии
+// и продлевает сессию до срока нового refresh-токена.
+func (s *UserService) issueTokens(ctx context.Context, u *models.User, sess *models.Session) (*TokenResponse, error) {
+	// при включённой 2FA вход (и каждое обновление в его сессии) прошёл проверку второго фактора
+	amr := []string{AMRPassword}
+	if u.TOTPEnabledAt != nil {
+		amr = append(amr, AMROTP)
+	}
+	tok, claims, err := s.tokens.IssueForSession(u.ID, sess.ID, u.Role, amr...)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	expiresAt := now.Add(s.tokens.RefreshTTL())
+	if err := s.refresh.Create(ctx, &models.RefreshToken{
+		UserID:    u.ID,
+		FamilyID:  sess.FamilyID,
+		TokenHash: utils.HashToken(refresh),
+		ExpiresAt: expiresAt,
+
+		AccessJTI:       claims.Id,
+		AccessExpiresAt: claims.ExpiresAtTime(),
+	}); err != nil {
+		return nil, err
+	}
+
+	sess.AccessJTI = claims.Id
+	sess.LastSeenAt = now
+	sess.ExpiresAt = expiresAt
+	if err := s.sessions.Extend(ctx, sess.ID, claims.Id, now, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		Token:        tok,
+		RefreshToken: refresh,
+		ExpiresIn:    int64(s.tokens.AccessTTL().Seconds()),
+	}, nil
 }
 
 // List возвращает срез DTO пользователей по фильтрам и пагинации.
@@ -192,8 +602,14 @@ func (s *UserService) Update(ctx context.Context, id uint, req *UpdateRequest) (
 	if req.Name != nil {
 		u.Name = *req.Name
 	}
-	if req.Email != nil {
-		u.Email = *req.Email
+	// новый email вступает в силу только после подтверждения
+	var newEmail string
+	if req.Email != nil && *req.Email != u.Email {
+		if err := s.checkEmailFree(ctx, *req.Email, u.ID); err != nil {
+			return nil, err
+		}
+		newEmail = *req.Email
+		u.PendingEmail = &newEmail
 	}
 	if req.Age != nil {
 		u.Age = *req.Age
@@ -203,9 +619,153 @@ func (s *UserService) Update(ctx context.Context, id uint, req *UpdateRequest) (
 		return nil, err
 	}
 	log.Printf("User updated successfully with ID: %d", u.ID)
+	if newEmail != "" {
+		if err := s.sendVerification(ctx, u, newEmail); err != nil {
+			return nil, err
+		}
+	}
+	return toUserResponse(u), nil
+}
+
+// ResendVerification повторно отправляет письмо подтверждения:
+// на ожидающий подтверждения новый email или на неподтверждённый текущий.
+func (s *UserService) ResendVerification(ctx context.Context, userID uint) error {
+	u, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case u.PendingEmail != nil:
+		return s.sendVerification(ctx, u, *u.PendingEmail)
+	case u.EmailVerifiedAt == nil:
+		return s.sendVerification(ctx, u, u.Email)
+	default:
+		return ErrEmailAlreadyVerified
+	}
+}
+
+// RequestVerification повторно отправляет письмо подтверждения на неподтверждённый
+// email пользователя, который не может войти без подтверждения. Для неизвестного
+// или уже подтверждённого адреса ничего не делает и не сообщает об этом,
+// чтобы по ответу нельзя было узнать, зарегистрирован ли адрес.
+func (s *UserService) RequestVerification(ctx context.Context, req *VerificationResendRequest) error {
+	u, err := s.repo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Email verification requested for unknown email")
+			return nil
+		}
+		return err
+	}
+	if u.EmailVerifiedAt != nil {
+		log.Printf("Email verification requested for verified user ID: %d", u.ID)
+		return nil
+	}
+	return s.sendVerification(ctx, u, u.Email)
+}
+
+// ConfirmEmail подтверждает email по одноразовому токену.
+// Если токен выдан на ожидающий новый адрес, этот адрес становится основным.
+func (s *UserService) ConfirmEmail(ctx context.Context, req *VerifyEmailRequest) (*UserResponse, error) {
+	t, err := s.oneTime.Consume(ctx, utils.HashToken(req.Token), models.TokenPurposeEmailVerification, time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, err
+	}
+	u, err := s.repo.GetByID(ctx, t.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	switch {
+	case u.PendingEmail != nil && *u.PendingEmail == t.Payload:
+		// адрес мог занять кто-то другой, пока письмо шло
+		if err := s.checkEmailFree(ctx, t.Payload, u.ID); err != nil {
+			return nil, err
+		}
+		u.Email = t.Payload
+		u.PendingEmail = nil
+	case u.Email == t.Payload:
+	default:
+		// токен выдан на адрес, который пользователь уже сменил
+		return nil, ErrInvalidVerificationToken
+	}
+	u.EmailVerifiedAt = &now
+	if err := s.repo.Update(ctx, u); err != nil {
+		return nil, err
+	}
+	log.Printf("Email verified for user ID: %d", u.ID)
 	return toUserResponse(u), nil
 }
 
+// sendVerification выпускает токен подтверждения для адреса email и отправляет его на этот адрес.
+func (s *UserService) sendVerification(ctx context.Context, u *models.User, email string) error {
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(s.verifyTTL)
+	if err := s.oneTime.Create(ctx, &models.OneTimeToken{
+		UserID:    u.ID,
+		Purpose:   models.TokenPurposeEmailVerification,
+		Payload:   email,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return err
+	}
+	return s.notifier.Send(ctx, notify.Message{
+		To:      email,
+		Subject: "Подтверждение email",
+		Body: fmt.Sprintf("Токен для подтверждения email: %s\nДействителен до %s.",
+			token, expiresAt.Format(time.RFC3339)),
+	})
+}
+
+// checkEmailFree проверяет, что email не занят другим пользователем.
+func (s *UserService) checkEmailFree(ctx context.Context, email string, selfID uint) error {
+	other, err := s.repo.GetByEmail(ctx, email)
+	if err == nil && other.ID != selfID {
+		return ErrUserExists
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+// SetRole меняет роль пользователя.
+// Новая роль попадает в токены при следующем логине или обновлении токенов.
+func (s *UserService) SetRole(ctx context.Context, id uint, req *RoleRequest) (*UserResponse, error) {
+	log.Printf("Attempting to set role %q for user ID: %d", req.Role, id)
+	u, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	u.Role = req.Role
+	if err := s.repo.Update(ctx, u); err != nil {
+		log.Printf("Error updating user role: %v", err)
+		return nil, err
+	}
+	return toUserResponse(u), nil
+}
+
+// Unlock снимает блокировку входа, наложенную после неудачных попыток.
+func (s *UserService) Unlock(ctx context.Context, id uint) error {
+	u, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	log.Printf("Unlocking login for user ID: %d", id)
+	return s.attempts.Unlock(ctx, u.Email)
+}
+
 // Delete удаляет пользователя.
 func (s *UserService) Delete(ctx context.Context, id uint) error {
 	// Add logging for user deletion
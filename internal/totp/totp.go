@@ -0,0 +1,105 @@
+// totp.go
+// Этот файл реализует одноразовые пароли TOTP (RFC 6238) на HMAC-SHA1:
+// 6 цифр, шаг 30 секунд — параметры, которые понимают все приложения-аутентификаторы.
+
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits — число цифр в коде
+	Digits = 6
+	// Period — длительность шага в секундах
+	Period = 30
+	// secretSize — длина секрета в байтах (160 бит, как рекомендует RFC 4226)
+	secretSize = 20
+)
+
+// ErrInvalidSecret ошибка, если секрет не является корректной base32-строкой.
+var ErrInvalidSecret = errors.New("некорректный секрет TOTP")
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret создаёт случайный секрет в base32 без выравнивания.
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// URI возвращает otpauth-ссылку для добавления секрета в приложение-аутентификатор.
+func URI(issuer, account, secret string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(Digits))
+	q.Set("period", fmt.Sprint(Period))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// Step возвращает номер шага для момента t.
+func Step(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// Code возвращает код для момента t.
+func Code(secret string, t time.Time) (string, error) {
+	key, err := decode(secret)
+	if err != nil {
+		return "", err
+	}
+	return code(key, Step(t)), nil
+}
+
+// Verify проверяет код с допуском skew шагов в обе стороны на рассинхронизацию часов.
+// Возвращает номер совпавшего шага: вызывающий код должен отвергать шаги,
+// которые уже использовались, чтобы код нельзя было предъявить повторно.
+func Verify(secret, c string, t time.Time, skew int) (int64, bool) {
+	key, err := decode(secret)
+	if err != nil || len(c) != Digits {
+		return 0, false
+	}
+	now := Step(t)
+	for i := -int64(skew); i <= int64(skew); i++ {
+		if subtle.ConstantTimeCompare([]byte(code(key, now+i)), []byte(c)) == 1 {
+			return now + i, true
+		}
+	}
+	return 0, false
+}
+
+func decode(secret string) ([]byte, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	return key, nil
+}
+
+// code — HOTP (RFC 4226) для счётчика step.
+func code(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", Digits, v%1000000)
+}
@@ -0,0 +1,27 @@
+// random.go
+// Этот файл содержит утилиты для генерации случайных токенов.
+// Используется для refresh-токенов и других непрозрачных секретов.
+
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// RandomToken возвращает криптостойкую случайную строку из n байт в base64url.
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken возвращает SHA-256 хэш токена в hex — в таком виде токены хранятся в БД.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,14 @@
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS access_jti VARCHAR(64);
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS access_expires_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_access_expires_at ON refresh_tokens(access_expires_at);
+
+CREATE TABLE IF NOT EXISTS one_time_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER       NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(32)   NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP  NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_one_time_tokens_user_id ON one_time_tokens(user_id);
@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pending_email VARCHAR(255);
+-- учётные записи, созданные до появления подтверждения, считаются подтверждёнными
+UPDATE users SET email_verified_at = CURRENT_TIMESTAMP WHERE email_verified_at IS NULL;
+
+ALTER TABLE one_time_tokens ADD COLUMN IF NOT EXISTS payload VARCHAR(255);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS order_items (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    product VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL,
+    price NUMERIC(10,2) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items(order_id);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS subtotal NUMERIC(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS total NUMERIC(12,2) NOT NULL DEFAULT 0;
+
+-- существующие заказы становятся заказами из одной позиции
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'orders' AND column_name = 'product') THEN
+        INSERT INTO order_items (order_id, product, quantity, price)
+        SELECT id, product, quantity, price FROM orders;
+        UPDATE orders SET subtotal = ROUND(quantity * price, 2), total = ROUND(quantity * price, 2);
+        ALTER TABLE orders DROP COLUMN product, DROP COLUMN quantity, DROP COLUMN price;
+    END IF;
+END $$;
@@ -0,0 +1,10 @@
+-- суммы хранятся целыми минимальными единицами валюты, у товаров и заказов есть валюта ISO 4217;
+-- старые колонки price, subtotal и total переносит в новые при запуске сервиса
+-- bootstrap.ConvertMoneyToMinorUnits — в валюте DEFAULT_CURRENCY и с её числом знаков,
+-- поэтому здесь они не пересчитываются
+ALTER TABLE products ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS price_minor BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS price_minor BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS subtotal_minor BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS total_minor BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- составные индексы для списков и поиска заказов; id в конце — для постраничной выдачи по курсору
+CREATE INDEX IF NOT EXISTS idx_orders_user_created ON orders(user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_orders_created ON orders(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_orders_status_created ON orders(status, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_orders_currency_total ON orders(currency, total_minor, id);
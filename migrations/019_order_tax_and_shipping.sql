@@ -0,0 +1,17 @@
+-- налоговые категории и вес товаров, налог и доставка заказов
+ALTER TABLE products ADD COLUMN IF NOT EXISTS tax_category VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS weight_grams INTEGER NOT NULL DEFAULT 0
+    CONSTRAINT chk_products_weight CHECK (weight_grams >= 0);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_minor BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_minor BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS order_tax_lines (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    rate INTEGER NOT NULL,
+    base_minor BIGINT NOT NULL,
+    amount_minor BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_order_tax_lines_order_id ON order_tax_lines(order_id);
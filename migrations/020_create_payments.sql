@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS payments (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    provider_ref VARCHAR(128),
+    amount_minor BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    failure_reason VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments(order_id);
+CREATE INDEX IF NOT EXISTS idx_payments_user_id ON payments(user_id);
+CREATE INDEX IF NOT EXISTS idx_payments_provider_ref ON payments(provider, provider_ref);
+-- не больше одного действующего платежа на заказ
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_order_active ON payments(order_id)
+    WHERE status <> 'failed' AND status <> 'refunded';
+
+-- обработанные уведомления провайдера: повтор с тем же ID не обрабатывается
+CREATE TABLE IF NOT EXISTS payment_events (
+    provider VARCHAR(32) NOT NULL,
+    event_id VARCHAR(128) NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    payment_id INTEGER,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (provider, event_id)
+);
+CREATE INDEX IF NOT EXISTS idx_payment_events_payment_id ON payment_events(payment_id);
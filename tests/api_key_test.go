@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"kvant_task/internal/idempotency"
+	"kvant_task/internal/middleware"
+	"kvant_task/internal/models"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/pricing"
+	"kvant_task/internal/router"
+	"kvant_task/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAPIKeys проверяет доступ по X-API-Key: ограничение scopes, запрет управления
+// учётной записью по ключу, отзыв, ротацию и отметку последнего использования.
+func TestAPIKeys(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	ctx := context.Background()
+
+	tokens := newTestTokenService()
+	users := services.NewUserService(db, testConfig(), tokens, notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
+	owner, err := users.Create(ctx, &services.RegisterRequest{
+		Name:     "Batch",
+		Email:    "batch@example.com",
+		Password: "Tr0ub4dor&3x",
+		Age:      30,
+	})
+	require.NoError(t, err)
+
+	keys := services.NewAPIKeyService(db)
+	r := gin.New()
+	auth := r.Group("/")
+	auth.Use(middleware.Auth(tokens, keys, nil))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	selfOrAdmin := middleware.RequireSelfOrRole("id", models.RoleAdmin)
+	auth.GET("/users/:id/orders", selfOrAdmin, middleware.RequireScope(models.ScopeOrdersRead), ok)
+	auth.POST("/users/:id/orders", selfOrAdmin, middleware.RequireScope(models.ScopeOrdersWrite), ok)
+	auth.POST("/users/me/password", middleware.RequireToken(), ok)
+
+	call := func(method, path, key string) int {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set(middleware.APIKeyHeader, key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	created, err := keys.Create(ctx, owner.ID, &services.CreateAPIKeyRequest{
+		Name:   "export",
+		Scopes: []string{models.ScopeOrdersRead, models.ScopeOrdersRead},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{models.ScopeOrdersRead}, created.Scopes)
+	require.Contains(t, created.Key, "kv_"+created.Prefix+"_")
+
+	t.Run("Scopes", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, call(http.MethodGet, "/users/1/orders", created.Key))
+		require.Equal(t, http.StatusForbidden, call(http.MethodPost, "/users/1/orders", created.Key))
+		require.Equal(t, http.StatusForbidden, call(http.MethodGet, "/users/2/orders", created.Key))
+		require.Equal(t, http.StatusForbidden, call(http.MethodPost, "/users/me/password", created.Key))
+		require.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/users/1/orders", "kv_nope_nope"))
+		require.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/users/1/orders", created.Key+"x"))
+	})
+
+	t.Run("ListMarksLastUsed", func(t *testing.T) {
+		list, err := keys.List(ctx, owner.ID)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		require.Equal(t, created.Prefix, list[0].Prefix)
+		require.NotNil(t, list[0].LastUsedAt)
+		require.WithinDuration(t, time.Now(), *list[0].LastUsedAt, time.Minute)
+	})
+
+	t.Run("Rotate", func(t *testing.T) {
+		rotated, err := keys.Rotate(ctx, owner.ID, created.ID)
+		require.NoError(t, err)
+		require.NotEqual(t, created.Key, rotated.Key)
+		require.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/users/1/orders", created.Key))
+		require.Equal(t, http.StatusOK, call(http.MethodGet, "/users/1/orders", rotated.Key))
+		created = rotated
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		// чужой ключ не найден
+		require.ErrorIs(t, keys.Revoke(ctx, owner.ID+1, created.ID), services.ErrNotFound)
+
+		require.NoError(t, keys.Revoke(ctx, owner.ID, created.ID))
+		require.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/users/1/orders", created.Key))
+		_, err := keys.Rotate(ctx, owner.ID, created.ID)
+		require.ErrorIs(t, err, services.ErrAPIKeyRevoked)
+	})
+}
+
+// TestAPIKeyAdminScopes проверяет, что ключ администратора открывает админские маршруты
+// только в пределах своих прав: ключ с orders:read не видит промокоды.
+func TestAPIKeyAdminScopes(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	ctx := context.Background()
+
+	users := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
+	admin, err := users.Create(ctx, &services.RegisterRequest{
+		Name:     "Admin",
+		Email:    "admin@example.com",
+		Password: "Tr0ub4dor&3x",
+		Age:      30,
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", admin.ID).Update("role", models.RoleAdmin).Error)
+
+	r := router.New(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy(),
+		idempotency.NewMemoryStore(), pricing.NoTax{}, pricing.NoShipping{}, newTestPaymentProvider())
+	keys := services.NewAPIKeyService(db)
+	newKey := func(scope string) string {
+		k, err := keys.Create(ctx, admin.ID, &services.CreateAPIKeyRequest{Name: scope, Scopes: []string{scope}})
+		require.NoError(t, err)
+		return k.Key
+	}
+	call := func(path, key string) int {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(middleware.APIKeyHeader, key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	ordersKey := newKey(models.ScopeOrdersRead)
+	couponsKey := newKey(models.ScopeCouponsRead)
+	require.Equal(t, http.StatusForbidden, call("/coupons", ordersKey))
+	require.Equal(t, http.StatusForbidden, call("/coupons/1", ordersKey))
+	require.Equal(t, http.StatusOK, call("/coupons", couponsKey))
+	require.Equal(t, http.StatusOK, call("/orders", ordersKey))
+}
@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"kvant_task/internal/models"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/pricing"
+	"kvant_task/internal/services"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCoupons проверяет промокоды: расчёт скидки, условия применения, лимиты
+// (в том числе при параллельных заказах) и отмену применения при отмене заказа.
+func TestCoupons(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	ctx := context.Background()
+
+	userSvc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
+	register := func(name, email string) uint {
+		u, err := userSvc.Create(ctx, &services.RegisterRequest{Name: name, Email: email, Password: "Tr0ub4dor&3x", Age: 30})
+		require.NoError(t, err)
+		return u.ID
+	}
+	buyer := register("Buyer", "buyer@example.com")
+	other := register("Other", "other@example.com")
+
+	createTestProduct(t, db, "BOOK-1", "Book", 1999, 100)
+	require.NoError(t, db.Create(&models.Product{SKU: "MUG-1", Name: "Mug", PriceMinor: 500, Currency: "USD", Stock: 100}).Error)
+
+	coupons := services.NewCouponService(db, testConfig())
+	orders := services.NewOrderService(db, testConfig(), pricing.NoTax{}, pricing.NoShipping{}, newTestPaymentProvider())
+	newCoupon := func(req services.CreateCouponRequest) *services.CouponResponse {
+		c, err := coupons.Create(ctx, &req)
+		require.NoError(t, err)
+		return c
+	}
+	order := func(userID uint, sku string, qty int, code string) (*services.OrderResponse, error) {
+		return orders.Create(ctx, userID, &services.CreateOrderRequest{
+			Items:      []services.OrderItemRequest{{SKU: sku, Quantity: qty}},
+			CouponCode: code,
+		})
+	}
+	redemptions := func(id uint) int {
+		c, err := coupons.Get(ctx, id)
+		require.NoError(t, err)
+		return c.Redemptions
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		c := newCoupon(services.CreateCouponRequest{Code: " welcome5 ", Type: models.CouponTypeFixed, Amount: "5"})
+		require.Equal(t, "WELCOME5", c.Code)
+		require.Equal(t, "5.00", c.Amount)
+		require.Equal(t, "RUB", c.Currency)
+
+		_, err := coupons.Create(ctx, &services.CreateCouponRequest{Code: "WELCOME5", Type: models.CouponTypePercent, Percent: 5})
+		require.ErrorIs(t, err, services.ErrCouponCodeTaken)
+		_, err = coupons.Create(ctx, &services.CreateCouponRequest{Code: "BAD CODE", Type: models.CouponTypePercent, Percent: 5})
+		require.ErrorIs(t, err, services.ErrInvalidCoupon)
+		_, err = coupons.Create(ctx, &services.CreateCouponRequest{Code: "BOTH", Type: models.CouponTypePercent, Percent: 5, Amount: "1"})
+		require.ErrorIs(t, err, services.ErrInvalidCoupon)
+		_, err = coupons.Create(ctx, &services.CreateCouponRequest{Code: "CENTS", Type: models.CouponTypeFixed, Amount: "0.001"})
+		require.ErrorIs(t, err, services.ErrInvalidCoupon)
+	})
+
+	t.Run("Discounts", func(t *testing.T) {
+		newCoupon(services.CreateCouponRequest{Code: "TEN", Type: models.CouponTypePercent, Percent: 10})
+		o, err := order(buyer, "BOOK-1", 3, "ten")
+		require.NoError(t, err)
+		// 59.97 − 10%, скидка округляется вниз
+		require.Equal(t, "59.97", o.Subtotal)
+		require.Equal(t, "TEN", o.CouponCode)
+		require.Equal(t, "5.99", o.Discount)
+		require.Equal(t, "53.98", o.Total)
+
+		// процентный промокод без валюты действует и для заказов в USD
+		o, err = order(buyer, "MUG-1", 1, "TEN")
+		require.NoError(t, err)
+		require.Equal(t, "0.50", o.Discount)
+		require.Equal(t, "4.50", o.Total)
+
+		// фиксированная скидка не больше суммы заказа
+		newCoupon(services.CreateCouponRequest{Code: "BIG", Type: models.CouponTypeFixed, Amount: "100"})
+		o, err = order(buyer, "BOOK-1", 1, "BIG")
+		require.NoError(t, err)
+		require.Equal(t, "19.99", o.Discount)
+		require.Equal(t, "0.00", o.Total)
+
+		// без промокода скидки нет
+		o, err = order(buyer, "BOOK-1", 1, "")
+		require.NoError(t, err)
+		require.Empty(t, o.CouponCode)
+		require.Equal(t, "0.00", o.Discount)
+		require.Equal(t, o.Subtotal, o.Total)
+	})
+
+	t.Run("Rules", func(t *testing.T) {
+		_, err := order(buyer, "BOOK-1", 1, "NOPE")
+		require.ErrorIs(t, err, services.ErrCouponNotFound)
+
+		future := time.Now().Add(time.Hour)
+		past := time.Now().Add(-time.Hour)
+		newCoupon(services.CreateCouponRequest{Code: "SOON", Type: models.CouponTypePercent, Percent: 5, ValidFrom: &future})
+		newCoupon(services.CreateCouponRequest{Code: "GONE", Type: models.CouponTypePercent, Percent: 5, ValidUntil: &past})
+		newCoupon(services.CreateCouponRequest{Code: "MIN50", Type: models.CouponTypePercent, Percent: 5, MinOrderAmount: "50"})
+		newCoupon(services.CreateCouponRequest{Code: "RUB5", Type: models.CouponTypeFixed, Amount: "5"})
+		for _, code := range []string{"SOON", "GONE", "MIN50"} {
+			_, err = order(buyer, "BOOK-1", 1, code)
+			require.ErrorIs(t, err, services.ErrCouponNotApplicable, code)
+		}
+		_, err = order(buyer, "MUG-1", 1, "RUB5")
+		require.ErrorIs(t, err, services.ErrCouponNotApplicable)
+
+		// смена состава ниже минимальной суммы промокода отклоняется, заказ не меняется
+		o, err := order(buyer, "BOOK-1", 3, "MIN50")
+		require.NoError(t, err)
+		_, err = orders.Update(ctx, buyer, o.ID, services.Actor{UserID: buyer, Role: models.RoleUser},
+			&services.UpdateOrderRequest{Items: []services.OrderItemRequest{{SKU: "BOOK-1", Quantity: 2}}})
+		require.ErrorIs(t, err, services.ErrCouponNotApplicable)
+		kept, err := orders.Get(ctx, buyer, o.ID)
+		require.NoError(t, err)
+		require.Equal(t, o.Total, kept.Total)
+		require.Equal(t, "MIN50", kept.CouponCode)
+
+		// отключённый промокод не применяется
+		off := newCoupon(services.CreateCouponRequest{Code: "OFF", Type: models.CouponTypePercent, Percent: 5})
+		require.NoError(t, coupons.Deactivate(ctx, off.ID))
+		_, err = order(buyer, "BOOK-1", 1, "OFF")
+		require.ErrorIs(t, err, services.ErrCouponNotFound)
+
+		// неудачная попытка не списывает товар
+		var book models.Product
+		require.NoError(t, db.Where("sku = ?", "BOOK-1").First(&book).Error)
+		stock := book.Stock
+		_, err = order(buyer, "BOOK-1", 2, "MIN50")
+		require.Error(t, err)
+		require.NoError(t, db.First(&book, book.ID).Error)
+		require.Equal(t, stock, book.Stock)
+	})
+
+	t.Run("PerUserLimit", func(t *testing.T) {
+		one := 1
+		c := newCoupon(services.CreateCouponRequest{Code: "ONCE", Type: models.CouponTypePercent, Percent: 5, MaxPerUser: &one})
+		_, err := order(buyer, "BOOK-1", 1, "ONCE")
+		require.NoError(t, err)
+		_, err = order(buyer, "BOOK-1", 1, "ONCE")
+		require.ErrorIs(t, err, services.ErrCouponExhausted)
+		_, err = order(other, "BOOK-1", 1, "ONCE")
+		require.NoError(t, err)
+		require.Equal(t, 2, redemptions(c.ID))
+	})
+
+	t.Run("GlobalLimitConcurrent", func(t *testing.T) {
+		three := 3
+		c := newCoupon(services.CreateCouponRequest{Code: "FIRST3", Type: models.CouponTypeFixed, Amount: "1", MaxRedemptions: &three})
+		var wg sync.WaitGroup
+		errs := make([]error, 8)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = order(buyer, "BOOK-1", 1, "FIRST3")
+			}(i)
+		}
+		wg.Wait()
+		created := 0
+		for _, err := range errs {
+			if err == nil {
+				created++
+				continue
+			}
+			require.ErrorIs(t, err, services.ErrCouponExhausted)
+		}
+		require.Equal(t, 3, created)
+		require.Equal(t, 3, redemptions(c.ID))
+	})
+
+	t.Run("ReleaseOnCancel", func(t *testing.T) {
+		one := 1
+		c := newCoupon(services.CreateCouponRequest{Code: "SINGLE", Type: models.CouponTypePercent, Percent: 20, MaxRedemptions: &one})
+		o, err := order(buyer, "BOOK-1", 1, "SINGLE")
+		require.NoError(t, err)
+		_, err = order(other, "BOOK-1", 1, "SINGLE")
+		require.ErrorIs(t, err, services.ErrCouponExhausted)
+
+		// при смене состава скидка пересчитывается
+		o, err = orders.Update(ctx, buyer, o.ID, services.Actor{UserID: buyer, Role: models.RoleUser},
+			&services.UpdateOrderRequest{Items: []services.OrderItemRequest{{SKU: "BOOK-1", Quantity: 2}}})
+		require.NoError(t, err)
+		require.Equal(t, "SINGLE", o.CouponCode)
+		require.Equal(t, "7.99", o.Discount)
+		require.Equal(t, "31.99", o.Total)
+
+		_, err = orders.Transition(ctx, buyer, o.ID, services.Actor{UserID: buyer, Role: models.RoleUser},
+			&services.OrderTransitionRequest{Status: models.OrderStatusCancelled})
+		require.NoError(t, err)
+		require.Equal(t, 0, redemptions(c.ID))
+		_, err = order(other, "BOOK-1", 1, "SINGLE")
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"kvant_task/internal/notify"
+	"kvant_task/internal/services"
+
+	"github.com/stretchr/testify/require"
+)
+
+// verifyTokenRe извлекает токен подтверждения email из текста уведомления.
+var verifyTokenRe = regexp.MustCompile(`Токен для подтверждения email: (\S+)`)
+
+func verificationToken(t *testing.T, msg notify.Message) string {
+	m := verifyTokenRe.FindStringSubmatch(msg.Body)
+	require.Len(t, m, 2)
+	return m[1]
+}
+
+// TestEmailVerification проверяет подтверждение email при регистрации,
+// отложенную смену email и запрет входа без подтверждения.
+func TestEmailVerification(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	ctx := context.Background()
+
+	cfg := testConfig()
+	cfg.Auth.RequireVerifiedEmailToLogin = true
+	notifier := &recordingNotifier{}
+	svc := services.NewUserService(db, cfg, newTestTokenService(), notifier, newTestGuard(), newTestPolicy())
+
+	user, err := svc.Create(ctx, &services.RegisterRequest{
+		Name:     "Verify",
+		Email:    "verify@example.com",
+		Password: "Tr0ub4dor&3x",
+		Age:      30,
+	})
+	require.NoError(t, err)
+	require.False(t, user.EmailVerified)
+
+	login := &services.LoginRequest{Email: "verify@example.com", Password: "Tr0ub4dor&3x"}
+
+	t.Run("ResendWithoutLogin", func(t *testing.T) {
+		first := notifier.last()
+		sent := len(notifier.sent)
+		// неизвестный адрес не отличается от известного, но письмо не уходит
+		require.NoError(t, svc.RequestVerification(ctx, &services.VerificationResendRequest{Email: "nobody@example.com"}))
+		require.Len(t, notifier.sent, sent)
+
+		require.NoError(t, svc.RequestVerification(ctx, &services.VerificationResendRequest{Email: "verify@example.com"}))
+		require.Len(t, notifier.sent, sent+1)
+		require.Equal(t, "verify@example.com", notifier.last().To)
+		_, err := svc.ConfirmEmail(ctx, &services.VerifyEmailRequest{Token: verificationToken(t, first)})
+		require.ErrorIs(t, err, services.ErrInvalidVerificationToken)
+	})
+
+	t.Run("Registration", func(t *testing.T) {
+		_, err := svc.Login(ctx, login, services.ClientInfo{})
+		require.ErrorIs(t, err, services.ErrEmailNotVerified)
+
+		msg := notifier.last()
+		require.Equal(t, "verify@example.com", msg.To)
+		token := verificationToken(t, msg)
+
+		u, err := svc.ConfirmEmail(ctx, &services.VerifyEmailRequest{Token: token})
+		require.NoError(t, err)
+		require.True(t, u.EmailVerified)
+
+		// токен одноразовый
+		_, err = svc.ConfirmEmail(ctx, &services.VerifyEmailRequest{Token: token})
+		require.ErrorIs(t, err, services.ErrInvalidVerificationToken)
+
+		_, err = svc.Login(ctx, login, services.ClientInfo{})
+		require.NoError(t, err)
+
+		require.ErrorIs(t, svc.ResendVerification(ctx, user.ID), services.ErrEmailAlreadyVerified)
+		sent := len(notifier.sent)
+		require.NoError(t, svc.RequestVerification(ctx, &services.VerificationResendRequest{Email: "verify@example.com"}))
+		require.Len(t, notifier.sent, sent)
+	})
+
+	t.Run("EmailChange_Pending", func(t *testing.T) {
+		newEmail := "verify-new@example.com"
+		u, err := svc.Update(ctx, user.ID, &services.UpdateRequest{Email: &newEmail})
+		require.NoError(t, err)
+		require.Equal(t, "verify@example.com", u.Email)
+		require.NotNil(t, u.PendingEmail)
+		require.Equal(t, newEmail, *u.PendingEmail)
+
+		msg := notifier.last()
+		require.Equal(t, newEmail, msg.To)
+
+		// повторная отправка делает предыдущий токен недействительным
+		require.NoError(t, svc.ResendVerification(ctx, user.ID))
+		_, err = svc.ConfirmEmail(ctx, &services.VerifyEmailRequest{Token: verificationToken(t, msg)})
+		require.ErrorIs(t, err, services.ErrInvalidVerificationToken)
+
+		u, err = svc.ConfirmEmail(ctx, &services.VerifyEmailRequest{Token: verificationToken(t, notifier.last())})
+		require.NoError(t, err)
+		require.Equal(t, newEmail, u.Email)
+		require.Nil(t, u.PendingEmail)
+		require.True(t, u.EmailVerified)
+	})
+
+	t.Run("EmailChange_Taken", func(t *testing.T) {
+		_, err := svc.Create(ctx, &services.RegisterRequest{
+			Name:     "Other",
+			Email:    "other@example.com",
+			Password: "Tr0ub4dor&3x",
+			Age:      25,
+		})
+		require.NoError(t, err)
+
+		taken := "other@example.com"
+		_, err = svc.Update(ctx, user.ID, &services.UpdateRequest{Email: &taken})
+		require.ErrorIs(t, err, services.ErrUserExists)
+	})
+}
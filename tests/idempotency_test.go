@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kvant_task/internal/handlers"
+	"kvant_task/internal/idempotency"
+	"kvant_task/internal/middleware"
+	"kvant_task/internal/models"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/pricing"
+	"kvant_task/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIdempotencyMiddleware проверяет повтор сохранённого ответа, 409 на чужой запрос
+// с тем же ключом и на ещё выполняющийся запрос, разделение ключей по пользователям.
+func TestIdempotencyMiddleware(t *testing.T) {
+	var calls int32
+	entered, release := make(chan struct{}), make(chan struct{})
+	r := gin.New()
+	// пользователь из заголовка вместо Auth
+	r.Use(func(c *gin.Context) {
+		if id, err := strconv.Atoi(c.GetHeader("X-User")); err == nil {
+			c.Set("user_id", uint(id))
+		}
+	})
+	r.Use(middleware.Idempotency(idempotency.NewMemoryStore(), time.Hour))
+	r.POST("/items", func(c *gin.Context) {
+		n := atomic.AddInt32(&calls, 1)
+		var body map[string]interface{}
+		_ = c.ShouldBindJSON(&body)
+		c.JSON(http.StatusCreated, gin.H{"call": n, "name": body["name"]})
+	})
+	r.POST("/fail", func(c *gin.Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "сбой"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	r.POST("/slow", func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		c.Status(http.StatusNoContent)
+	})
+
+	send := func(path, user, key, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if user != "" {
+			req.Header.Set("X-User", user)
+		}
+		if key != "" {
+			req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("WithoutKey", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		send("/items", "1", "", `{"name":"a"}`)
+		send("/items", "1", "", `{"name":"a"}`)
+		require.EqualValues(t, 2, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		first := send("/items", "1", "key-1", `{"name":"a"}`)
+		require.Equal(t, http.StatusCreated, first.Code)
+		require.Empty(t, first.Header().Get(middleware.IdempotentReplayedHeader))
+
+		again := send("/items", "1", "key-1", `{"name":"a"}`)
+		require.Equal(t, http.StatusCreated, again.Code)
+		require.Equal(t, "true", again.Header().Get(middleware.IdempotentReplayedHeader))
+		require.Equal(t, first.Body.String(), again.Body.String())
+		require.Contains(t, again.Header().Get("Content-Type"), "application/json")
+		require.EqualValues(t, 1, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("DifferentBody", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		require.Equal(t, http.StatusCreated, send("/items", "1", "key-2", `{"name":"a"}`).Code)
+		require.Equal(t, http.StatusConflict, send("/items", "1", "key-2", `{"name":"b"}`).Code)
+		require.EqualValues(t, 1, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("ScopedPerUser", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		require.Equal(t, http.StatusCreated, send("/items", "1", "key-3", `{"name":"a"}`).Code)
+		w := send("/items", "2", "key-3", `{"name":"a"}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.Empty(t, w.Header().Get(middleware.IdempotentReplayedHeader))
+		// без пользователя ключ принадлежит IP
+		require.Equal(t, http.StatusCreated, send("/items", "", "key-3", `{"name":"a"}`).Code)
+		require.EqualValues(t, 3, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("ServerErrorNotStored", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		require.Equal(t, http.StatusInternalServerError, send("/fail", "1", "key-4", `{}`).Code)
+		w := send("/fail", "1", "key-4", `{}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Empty(t, w.Header().Get(middleware.IdempotentReplayedHeader))
+		require.EqualValues(t, 2, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("InProgress", func(t *testing.T) {
+		done := make(chan int)
+		go func() { done <- send("/slow", "1", "key-5", `{}`).Code }()
+		// первый запрос занял ключ и выполняется
+		<-entered
+		require.Equal(t, http.StatusConflict, send("/slow", "1", "key-5", `{}`).Code)
+		close(release)
+		require.Equal(t, http.StatusNoContent, <-done)
+		require.Equal(t, http.StatusNoContent, send("/slow", "1", "key-5", `{}`).Code)
+	})
+
+	t.Run("KeyTooLong", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, send("/items", "1", strings.Repeat("k", 256), `{}`).Code)
+	})
+}
+
+// TestIdempotentOrderCreate проверяет, что повтор POST /users/:id/orders с тем же ключом
+// не создаёт второй заказ и не списывает товар повторно.
+func TestIdempotentOrderCreate(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	ctx := context.Background()
+
+	userSvc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
+	u, err := userSvc.Create(ctx, &services.RegisterRequest{Name: "Buyer", Email: "buyer@example.com", Password: "Tr0ub4dor&3x", Age: 30})
+	require.NoError(t, err)
+	pen := createTestProduct(t, db, "PEN-1", "Pen", 150, 10)
+
+	orderH := handlers.NewOrderHandler(db, testConfig(), pricing.NoTax{}, pricing.NoShipping{}, newTestPaymentProvider())
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", u.ID) })
+	r.POST("/users/:id/orders", middleware.Idempotency(idempotency.NewPostgresStore(db), time.Hour), orderH.CreateForUser)
+
+	create := func(key string, qty int) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{
+			"items": []map[string]interface{}{{"product_id": pen.ID, "quantity": qty}},
+		})
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/%d/orders", u.ID), bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	countOrders := func() int64 {
+		var n int64
+		require.NoError(t, db.Model(&models.Order{}).Where("user_id = ?", u.ID).Count(&n).Error)
+		return n
+	}
+
+	first := create("retry-1", 2)
+	require.Equal(t, http.StatusCreated, first.Code)
+	retry := create("retry-1", 2)
+	require.Equal(t, http.StatusCreated, retry.Code)
+	require.Equal(t, "true", retry.Header().Get(middleware.IdempotentReplayedHeader))
+	require.JSONEq(t, first.Body.String(), retry.Body.String())
+	require.EqualValues(t, 1, countOrders())
+
+	var p models.Product
+	require.NoError(t, db.First(&p, pen.ID).Error)
+	require.Equal(t, 8, p.Stock)
+
+	// тот же ключ с другим составом
+	require.Equal(t, http.StatusConflict, create("retry-1", 3).Code)
+	// новый ключ — новый заказ
+	require.Equal(t, http.StatusCreated, create("retry-2", 1).Code)
+	require.EqualValues(t, 2, countOrders())
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kvant_task/internal/lockout"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoginGuard проверяет блокировку после порога неудач, удвоение длительности
+// блокировки, сброс счётчика после успешного входа и по истечении окна.
+func TestLoginGuard(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := lockout.NewMemoryStore()
+	guard := lockout.NewGuard(store, lockout.Policy{
+		Threshold:   3,
+		IPThreshold: 10,
+		BaseDelay:   time.Minute,
+		MaxDelay:    5 * time.Minute,
+		Window:      15 * time.Minute,
+	}).WithClock(func() time.Time { return now })
+
+	const email, ip = "victim@example.com", "10.0.0.1"
+
+	t.Run("LockAfterThreshold", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			require.NoError(t, guard.Fail(ctx, email, ip))
+			require.NoError(t, guard.Check(ctx, email, ip))
+		}
+		require.NoError(t, guard.Fail(ctx, email, ip))
+
+		// регистр email не влияет на ключ аккаунта
+		err := guard.Check(ctx, "Victim@Example.com", "")
+		var locked *lockout.LockedError
+		require.ErrorAs(t, err, &locked)
+		require.Equal(t, time.Minute, locked.RetryAfter(now))
+
+		// другой IP для того же аккаунта тоже заблокирован
+		require.Error(t, guard.Check(ctx, email, "10.0.0.2"))
+	})
+
+	t.Run("ExponentialBackoff", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		require.NoError(t, guard.Check(ctx, email, ip))
+
+		expected := []time.Duration{2 * time.Minute, 4 * time.Minute, 5 * time.Minute}
+		for _, d := range expected {
+			require.NoError(t, guard.Fail(ctx, email, ip))
+			var locked *lockout.LockedError
+			require.ErrorAs(t, guard.Check(ctx, email, ip), &locked)
+			require.Equal(t, d, locked.RetryAfter(now))
+		}
+	})
+
+	t.Run("UnlockAndSucceed", func(t *testing.T) {
+		require.NoError(t, guard.Unlock(ctx, email))
+		require.NoError(t, guard.Check(ctx, email, ""))
+
+		require.NoError(t, guard.Fail(ctx, email, ""))
+		require.NoError(t, guard.Fail(ctx, email, ""))
+		require.NoError(t, guard.Succeed(ctx, email))
+		// после успешного входа счётчик начинается заново
+		require.NoError(t, guard.Fail(ctx, email, ""))
+		require.NoError(t, guard.Check(ctx, email, ""))
+	})
+
+	t.Run("IPThreshold", func(t *testing.T) {
+		// перебор по разным аккаунтам с одного IP
+		for i := 0; i < 10; i++ {
+			require.NoError(t, guard.Fail(ctx, "spray@example.com", "10.0.0.9"))
+			require.NoError(t, guard.Succeed(ctx, "spray@example.com"))
+		}
+		require.Error(t, guard.Check(ctx, "other@example.com", "10.0.0.9"))
+		require.NoError(t, guard.Check(ctx, "other@example.com", "10.0.0.10"))
+	})
+
+	t.Run("WindowExpiry", func(t *testing.T) {
+		const e = "window@example.com"
+		require.NoError(t, guard.Fail(ctx, e, ""))
+		require.NoError(t, guard.Fail(ctx, e, ""))
+		now = now.Add(16 * time.Minute)
+		require.NoError(t, guard.Fail(ctx, e, ""))
+		require.NoError(t, guard.Check(ctx, e, ""))
+
+		n, err := store.Purge(ctx, now.Add(time.Hour))
+		require.NoError(t, err)
+		require.Positive(t, n)
+	})
+}
@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"math"
+	"testing"
+
+	"kvant_task/internal/money"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMoney проверяет разбор и форматирование сумм с учётом числа знаков валюты.
+func TestMoney(t *testing.T) {
+	rub, err := money.Lookup("rub")
+	require.NoError(t, err)
+	require.Equal(t, money.Currency{Code: "RUB", Exponent: 2}, rub)
+	jpy, err := money.Lookup("JPY")
+	require.NoError(t, err)
+	kwd, err := money.Lookup("KWD")
+	require.NoError(t, err)
+	_, err = money.Lookup("XAU")
+	require.ErrorIs(t, err, money.ErrUnknownCurrency)
+
+	t.Run("Parse", func(t *testing.T) {
+		cases := []struct {
+			cur  money.Currency
+			in   string
+			want int64
+		}{
+			{rub, "19.95", 1995},
+			{rub, "19.9", 1990},
+			{rub, "19", 1900},
+			{rub, "0.01", 1},
+			{rub, "-3.50", -350},
+			{jpy, "1200", 1200},
+			{kwd, "1.005", 1005},
+		}
+		for _, c := range cases {
+			got, err := c.cur.Parse(c.in)
+			require.NoError(t, err, c.in)
+			require.Equal(t, c.want, got, c.in)
+		}
+		for _, bad := range []string{"", "1.", ".5", "1.999", "1e2", "+1", "1,50", " 1", "99999999999999999999"} {
+			_, err := rub.Parse(bad)
+			require.Error(t, err, bad)
+		}
+		_, err := jpy.Parse("1.5")
+		require.ErrorIs(t, err, money.ErrInvalidAmount)
+	})
+
+	t.Run("Format", func(t *testing.T) {
+		require.Equal(t, "19.95", rub.Format(1995))
+		require.Equal(t, "0.05", rub.Format(5))
+		require.Equal(t, "0.00", rub.Format(0))
+		require.Equal(t, "-0.05", rub.Format(-5))
+		require.Equal(t, "1200", jpy.Format(1200))
+		require.Equal(t, "1.005", kwd.Format(1005))
+		require.Equal(t, "-92233720368547758.08", rub.Format(math.MinInt64))
+	})
+
+	t.Run("Overflow", func(t *testing.T) {
+		_, err := money.Mul(math.MaxInt64/2+1, 2)
+		require.ErrorIs(t, err, money.ErrOverflow)
+		_, err = money.Add(math.MaxInt64, 1)
+		require.ErrorIs(t, err, money.ErrOverflow)
+		v, err := money.Mul(1995, 3)
+		require.NoError(t, err)
+		require.EqualValues(t, 5985, v)
+	})
+}
@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"kvant_task/internal/handlers"
+	"kvant_task/internal/models"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/pricing"
+	"kvant_task/internal/repositories"
+	"kvant_task/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOrderCRUD проверяет получение, изменение, отмену и удаление отдельного заказа:
+// принадлежность заказа пользователю, различие 404 для пользователя и заказа,
+// пересчёт остатков при смене состава.
+func TestOrderCRUD(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	ctx := context.Background()
+
+	userSvc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
+	register := func(name, email string) uint {
+		u, err := userSvc.Create(ctx, &services.RegisterRequest{Name: name, Email: email, Password: "Tr0ub4dor&3x", Age: 30})
+		require.NoError(t, err)
+		return u.ID
+	}
+	owner := register("Owner", "owner@example.com")
+	other := register("Other", "other@example.com")
+
+	pen := createTestProduct(t, db, "PEN-1", "Pen", 150, 10)
+	pad := createTestProduct(t, db, "PAD-1", "Pad", 400, 10)
+	stockOf := func(id uint) int {
+		var p models.Product
+		require.NoError(t, db.First(&p, id).Error)
+		return p.Stock
+	}
+
+	orderH := handlers.NewOrderHandler(db, testConfig(), pricing.NoTax{}, pricing.NoShipping{}, newTestPaymentProvider())
+	r := gin.New()
+	r.POST("/users/:id/orders", orderH.CreateForUser)
+	r.GET("/users/:id/orders/:orderId", orderH.Get)
+	r.PATCH("/users/:id/orders/:orderId", orderH.Update)
+	r.DELETE("/users/:id/orders/:orderId", orderH.Delete)
+
+	call := func(method, path string, body interface{}) (int, map[string]interface{}) {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+	orderPath := func(userID uint, orderID interface{}) string {
+		return fmt.Sprintf("/users/%d/orders/%v", userID, orderID)
+	}
+
+	code, created := call(http.MethodPost, fmt.Sprintf("/users/%d/orders", owner), map[string]interface{}{
+		"items": []map[string]interface{}{{"product_id": pen.ID, "quantity": 2}},
+	})
+	require.Equal(t, http.StatusCreated, code)
+	orderID := uint(created["id"].(float64))
+	require.Equal(t, 8, stockOf(pen.ID))
+
+	t.Run("Get", func(t *testing.T) {
+		code, resp := call(http.MethodGet, orderPath(owner, orderID), nil)
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "3.00", resp["total"])
+
+		// чужой заказ не виден
+		code, resp = call(http.MethodGet, orderPath(other, orderID), nil)
+		require.Equal(t, http.StatusNotFound, code)
+		require.Equal(t, "заказ не найден", resp["error"])
+
+		code, resp = call(http.MethodGet, orderPath(9999, orderID), nil)
+		require.Equal(t, http.StatusNotFound, code)
+		require.Equal(t, "пользователь не найден", resp["error"])
+
+		code, _ = call(http.MethodGet, orderPath(owner, "abc"), nil)
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("UpdateItems", func(t *testing.T) {
+		code, resp := call(http.MethodPatch, orderPath(owner, orderID), map[string]interface{}{
+			"items": []map[string]interface{}{{"sku": "PAD-1", "quantity": 3}, {"sku": "PEN-1", "quantity": 1}},
+		})
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, resp["items"], 2)
+		require.Equal(t, "13.50", resp["total"])
+		require.Equal(t, 9, stockOf(pen.ID))
+		require.Equal(t, 7, stockOf(pad.ID))
+
+		// при нехватке товара заказ не меняется
+		code, _ = call(http.MethodPatch, orderPath(owner, orderID), map[string]interface{}{
+			"items": []map[string]interface{}{{"sku": "PAD-1", "quantity": 50}},
+		})
+		require.Equal(t, http.StatusConflict, code)
+		require.Equal(t, 7, stockOf(pad.ID))
+
+		code, _ = call(http.MethodPatch, orderPath(owner, orderID), map[string]interface{}{
+			"items":  []map[string]interface{}{{"sku": "PAD-1", "quantity": 1}},
+			"status": models.OrderStatusCancelled,
+		})
+		require.Equal(t, http.StatusBadRequest, code)
+		code, _ = call(http.MethodPatch, orderPath(owner, orderID), map[string]interface{}{})
+		require.Equal(t, http.StatusBadRequest, code)
+
+		code, _ = call(http.MethodPatch, orderPath(other, orderID), map[string]interface{}{
+			"items": []map[string]interface{}{{"sku": "PAD-1", "quantity": 1}},
+		})
+		require.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("CancelAndDelete", func(t *testing.T) {
+		code, resp := call(http.MethodPatch, orderPath(owner, orderID), map[string]interface{}{"status": models.OrderStatusCancelled})
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, models.OrderStatusCancelled, resp["status"])
+		require.Equal(t, 10, stockOf(pen.ID))
+		require.Equal(t, 10, stockOf(pad.ID))
+
+		// отменённый заказ не меняется
+		code, _ = call(http.MethodPatch, orderPath(owner, orderID), map[string]interface{}{
+			"items": []map[string]interface{}{{"sku": "PAD-1", "quantity": 1}},
+		})
+		require.Equal(t, http.StatusConflict, code)
+
+		code, _ = call(http.MethodDelete, orderPath(owner, orderID), nil)
+		require.Equal(t, http.StatusNoContent, code)
+		code, _ = call(http.MethodDelete, orderPath(owner, orderID), nil)
+		require.Equal(t, http.StatusNotFound, code)
+		require.Equal(t, 10, stockOf(pen.ID))
+	})
+
+	t.Run("DeletePaidOrder", func(t *testing.T) {
+		code, created := call(http.MethodPost, fmt.Sprintf("/users/%d/orders", owner), map[string]interface{}{
+			"items": []map[string]interface{}{{"sku": "PEN-1", "quantity": 1}},
+		})
+		require.Equal(t, http.StatusCreated, code)
+		id := uint(created["id"].(float64))
+		require.NoError(t, db.Model(&repositories.Order{}).Where("id = ?", id).Update("status", models.OrderStatusPaid).Error)
+
+		code, _ = call(http.MethodDelete, orderPath(owner, id), nil)
+		require.Equal(t, http.StatusConflict, code)
+	})
+}
@@ -11,6 +11,8 @@ import (
 
 	"kvant_task/internal/handlers"
 	"kvant_task/internal/middleware"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/pricing"
 	"kvant_task/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -24,17 +26,22 @@ func setupOrderRouter(t *testing.T) (*gin.Engine, uint) {
 	cleanUsers(t, db)
 
 	// создаём пользователя
-	userSvc := services.NewUserService(db, "test-secret")
+	userSvc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
 	user, err := userSvc.Create(context.Background(), &services.RegisterRequest{
 		Name:     "Order User",
 		Email:    "order@example.com",
-		Password: "pass1234",
+		Password: "Tr0ub4dor&3x",
 		Age:      33,
 	})
 	require.NoError(t, err)
 
+	createTestProduct(t, db, "LAPTOP-1", "Laptop", 120050, 10)
+	createTestProduct(t, db, "CABLE-1", "Cable", 999, 100)
+	createTestProduct(t, db, "MONITOR-1", "Monitor", 35000, 10)
+	createTestProduct(t, db, "MOUSE-1", "Mouse", 2550, 10)
+
 	// роутер для заказов (без JWT-мидлвэра)
-	orderH := handlers.NewOrderHandler(db)
+	orderH := handlers.NewOrderHandler(db, testConfig(), pricing.NoTax{}, pricing.NoShipping{}, newTestPaymentProvider())
 	r := gin.New()
 	r.POST("/users/:id/orders", orderH.CreateForUser)
 	r.GET("/users/:id/orders", orderH.ListByUser)
@@ -44,15 +51,16 @@ func setupOrderRouter(t *testing.T) (*gin.Engine, uint) {
 
 // Test_CreateOrder_Success проверяет успешное создание заказа для пользователя.
 // Тест отправляет POST-запрос с данными заказа и проверяет, что ответ содержит
-// корректные данные, включая продукт, количество, цену и идентификатор пользователя.
+// корректные данные, включая позиции, суммы и идентификатор пользователя.
 func Test_CreateOrder_Success(t *testing.T) {
 	r, userID := setupOrderRouter(t)
 
 	// подготовка тела запроса
 	order := map[string]interface{}{
-		"product":  "Laptop",
-		"quantity": 1,
-		"price":    1200.50,
+		"items": []map[string]interface{}{
+			{"sku": "LAPTOP-1", "quantity": 1},
+			{"product_id": 2, "quantity": 3},
+		},
 	}
 	body, _ := json.Marshal(order)
 
@@ -66,9 +74,17 @@ func Test_CreateOrder_Success(t *testing.T) {
 
 	var resp map[string]interface{}
 	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
-	require.Equal(t, "Laptop", resp["product"])
-	require.Equal(t, float64(1), resp["quantity"])
-	require.Equal(t, 1200.50, resp["price"])
+	items := resp["items"].([]interface{})
+	require.Len(t, items, 2)
+	first := items[0].(map[string]interface{})
+	require.Equal(t, "Laptop", first["product"])
+	require.Equal(t, "LAPTOP-1", first["sku"])
+	require.Equal(t, float64(1), first["quantity"])
+	require.Equal(t, "1200.50", first["price"])
+	require.Equal(t, "29.97", items[1].(map[string]interface{})["line_total"])
+	require.Equal(t, "1230.47", resp["subtotal"])
+	require.Equal(t, "1230.47", resp["total"])
+	require.Equal(t, "RUB", resp["currency"])
 	require.Equal(t, float64(userID), resp["user_id"])
 	require.NotEmpty(t, resp["created_at"])
 }
@@ -81,11 +97,11 @@ func Test_ListOrders_Success(t *testing.T) {
 
 	// создаём два заказа
 	toCreate := []map[string]interface{}{
-		{"product": "Monitor", "quantity": 2, "price": 350.00},
-		{"product": "Mouse", "quantity": 3, "price": 25.50},
+		{"sku": "MONITOR-1", "quantity": 2},
+		{"sku": "MOUSE-1", "quantity": 3},
 	}
 	for _, o := range toCreate {
-		body, _ := json.Marshal(o)
+		body, _ := json.Marshal(map[string]interface{}{"items": []map[string]interface{}{o}})
 		req, _ := http.NewRequest("POST", "/users/"+strconv.Itoa(int(userID))+"/orders", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
 		w := httptest.NewRecorder()
@@ -100,22 +116,31 @@ func Test_ListOrders_Success(t *testing.T) {
 
 	require.Equal(t, http.StatusOK, wList.Code)
 
-	var list []map[string]interface{}
-	require.NoError(t, json.Unmarshal(wList.Body.Bytes(), &list))
+	var page struct {
+		Total  int64                    `json:"total"`
+		Orders []map[string]interface{} `json:"orders"`
+	}
+	require.NoError(t, json.Unmarshal(wList.Body.Bytes(), &page))
+	require.EqualValues(t, len(toCreate), page.Total)
+	list := page.Orders
 	require.Len(t, list, len(toCreate))
 
 	// проверяем, что в ответе есть оба заказа с нужными полями
 	found := make(map[string]map[string]interface{})
 	for _, o := range list {
-		found[o["product"].(string)] = o
+		items := o["items"].([]interface{})
+		require.Len(t, items, 1)
+		item := items[0].(map[string]interface{})
+		require.Equal(t, float64(userID), o["user_id"])
+		found[item["sku"].(string)] = item
 	}
 	for _, o := range toCreate {
-		f, ok := found[o["product"].(string)]
-		require.True(t, ok, "заказ %q не найден", o["product"])
+		f, ok := found[o["sku"].(string)]
+		require.True(t, ok, "заказ %q не найден", o["sku"])
 		require.Equal(t, float64(o["quantity"].(int)), f["quantity"])
-		require.Equal(t, o["price"], f["price"])
-		require.Equal(t, float64(userID), f["user_id"])
 	}
+	require.Equal(t, "350.00", found["MONITOR-1"]["price"])
+	require.Equal(t, "25.50", found["MOUSE-1"]["price"])
 }
 
 // setupOrderRouterWithAuth инициализирует тестовую БД, создаёт пользователя и возвращает Gin-роутер с JWT middleware и его ID.
@@ -123,23 +148,25 @@ func setupOrderRouterWithAuth(t *testing.T) (*gin.Engine, uint, string) {
 	db := getTestDB(t)
 	cleanUsers(t, db)
 
-	userSvc := services.NewUserService(db, "test-secret")
+	tokens := newTestTokenService()
+	userSvc := services.NewUserService(db, testConfig(), tokens, notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
 	user, err := userSvc.Create(context.Background(), &services.RegisterRequest{
 		Name:     "Order User",
 		Email:    "order@example.com",
-		Password: "pass1234",
+		Password: "Tr0ub4dor&3x",
 		Age:      33,
 	})
 	require.NoError(t, err)
 
-	token := generateTestToken(user.ID, "test-secret")
+	token := generateTestToken(user.ID)
+	createTestProduct(t, db, "ITEM-1", "Item", 1000, 5)
 
-	orderH := handlers.NewOrderHandler(db)
+	orderH := handlers.NewOrderHandler(db, testConfig(), pricing.NoTax{}, pricing.NoShipping{}, newTestPaymentProvider())
 	r := gin.New()
 
 	// Настраиваем руты с JWT middleware
 	auth := r.Group("/")
-	auth.Use(middleware.Auth("test-secret"))
+	auth.Use(middleware.Auth(tokens, nil, nil))
 	auth.POST("/users/:id/orders", orderH.CreateForUser)
 	auth.GET("/users/:id/orders", orderH.ListByUser)
 
@@ -149,17 +176,23 @@ func setupOrderRouterWithAuth(t *testing.T) (*gin.Engine, uint, string) {
 // Изменение ожидаемых статусов ошибок с 422 на 400 в тестах
 func Test_CreateOrder_BadRequest(t *testing.T) {
 	r, userID, token := setupOrderRouterWithAuth(t)
+	item := func(it map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"items": []map[string]interface{}{it}}
+	}
 
 	testCases := []struct {
 		name       string
 		body       map[string]interface{}
 		wantStatus int
 	}{
-		{"Missing product", map[string]interface{}{"quantity": 1, "price": 10.0}, http.StatusBadRequest},
-		{"Missing quantity", map[string]interface{}{"product": "Item", "price": 10.0}, http.StatusBadRequest},
-		{"Missing price", map[string]interface{}{"product": "Item", "quantity": 1}, http.StatusBadRequest},
-		{"Negative quantity", map[string]interface{}{"product": "Item", "quantity": -1, "price": 10.0}, http.StatusBadRequest},
-		{"Negative price", map[string]interface{}{"product": "Item", "quantity": 1, "price": -10.0}, http.StatusBadRequest},
+		{"Missing items", map[string]interface{}{}, http.StatusBadRequest},
+		{"Empty items", map[string]interface{}{"items": []interface{}{}}, http.StatusBadRequest},
+		{"Missing product", item(map[string]interface{}{"quantity": 1}), http.StatusBadRequest},
+		{"Both product_id and sku", item(map[string]interface{}{"product_id": 1, "sku": "ITEM-1", "quantity": 1}), http.StatusBadRequest},
+		{"Unknown product", item(map[string]interface{}{"sku": "NOPE", "quantity": 1}), http.StatusBadRequest},
+		{"Missing quantity", item(map[string]interface{}{"sku": "ITEM-1"}), http.StatusBadRequest},
+		{"Negative quantity", item(map[string]interface{}{"sku": "ITEM-1", "quantity": -1}), http.StatusBadRequest},
+		{"Out of stock", item(map[string]interface{}{"sku": "ITEM-1", "quantity": 6}), http.StatusConflict},
 	}
 
 	for _, tc := range testCases {
@@ -180,9 +213,7 @@ func Test_CreateOrder_Unauthorized(t *testing.T) {
 	r, userID, _ := setupOrderRouterWithAuth(t)
 
 	order := map[string]interface{}{
-		"product":  "Laptop",
-		"quantity": 1,
-		"price":    1200.50,
+		"items": []map[string]interface{}{{"sku": "LAPTOP-1", "quantity": 1}},
 	}
 	body, _ := json.Marshal(order)
 
@@ -229,7 +260,7 @@ func Test_OrderEndpoints_InvalidJWT(t *testing.T) {
 		route  string
 		body   map[string]interface{}
 	}{
-		{"POST", "/users/" + strconv.Itoa(int(userID)) + "/orders", map[string]interface{}{"product": "Laptop", "quantity": 1, "price": 1200.50}},
+		{"POST", "/users/" + strconv.Itoa(int(userID)) + "/orders", map[string]interface{}{"items": []map[string]interface{}{{"sku": "LAPTOP-1", "quantity": 1}}}},
 		{"GET", "/users/" + strconv.Itoa(int(userID)) + "/orders", nil},
 	}
 
@@ -0,0 +1,273 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"kvant_task/internal/handlers"
+	"kvant_task/internal/models"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/pricing"
+	"kvant_task/internal/repositories"
+	"kvant_task/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOrderList проверяет фильтры, сортировку и постраничную выдачу GET /users/:id/orders.
+func TestOrderList(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	ctx := context.Background()
+
+	userSvc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
+	u, err := userSvc.Create(ctx, &services.RegisterRequest{Name: "Buyer", Email: "buyer@example.com", Password: "Tr0ub4dor&3x", Age: 30})
+	require.NoError(t, err)
+	o, err := userSvc.Create(ctx, &services.RegisterRequest{Name: "Other", Email: "other@example.com", Password: "Tr0ub4dor&3x", Age: 30})
+	require.NoError(t, err)
+
+	// заказы создаются напрямую, чтобы задать даты
+	repo := repositories.NewOrderRepo(db)
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	add := func(userID uint, daysAgo int, currency string, items ...models.OrderItem) {
+		var total int64
+		for _, it := range items {
+			total += it.PriceMinor * int64(it.Quantity)
+		}
+		require.NoError(t, repo.Create(ctx, &repositories.Order{
+			UserID:        userID,
+			Items:         items,
+			Currency:      currency,
+			SubtotalMinor: total,
+			TotalMinor:    total,
+			CreatedAt:     day.AddDate(0, 0, -daysAgo),
+		}))
+	}
+	item := func(name string, qty int, price int64) models.OrderItem {
+		return models.OrderItem{SKU: name, Product: name, Quantity: qty, PriceMinor: price}
+	}
+	add(u.ID, 0, "RUB", item("Red Pen", 2, 150))                          // 3.00
+	add(u.ID, 1, "RUB", item("Notebook", 1, 2000), item("Pencil", 5, 50)) // 22.50
+	add(u.ID, 2, "RUB", item("Stapler", 1, 900))                          // 9.00
+	add(u.ID, 3, "USD", item("Red Marker", 3, 400))                       // 12.00
+	add(u.ID, 10, "RUB", item("Desk_Lamp", 1, 5000))                      // 50.00
+	add(o.ID, 0, "RUB", item("Red Pen", 1, 150))
+
+	orderH := handlers.NewOrderHandler(db, testConfig(), pricing.NoTax{}, pricing.NoShipping{}, newTestPaymentProvider())
+	r := gin.New()
+	r.GET("/users/:id/orders", orderH.ListByUser)
+
+	list := func(query url.Values) (int, handlers.OrderListResponse) {
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/%d/orders?%s", u.ID, query.Encode()), nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var resp handlers.OrderListResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+	totals := func(resp handlers.OrderListResponse) []string {
+		out := make([]string, len(resp.Orders))
+		for i, o := range resp.Orders {
+			out[i] = o.Total
+		}
+		return out
+	}
+
+	t.Run("DefaultNewestFirst", func(t *testing.T) {
+		code, resp := list(url.Values{})
+		require.Equal(t, http.StatusOK, code)
+		require.EqualValues(t, 5, resp.Total)
+		require.Equal(t, 1, resp.Page)
+		require.Equal(t, 20, resp.Limit)
+		require.Empty(t, resp.NextCursor)
+		require.Equal(t, []string{"3.00", "22.50", "9.00", "12.00", "50.00"}, totals(resp))
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		cases := []struct {
+			name  string
+			query url.Values
+			want  []string
+		}{
+			{"Date range", url.Values{"created_from": {"2024-02-28"}, "created_to": {"2024-02-29"}}, []string{"22.50", "9.00"}},
+			{"Date time", url.Values{"created_to": {"2024-02-27T12:00:00Z"}}, []string{"12.00", "50.00"}},
+			{"Total range", url.Values{"min_total": {"9"}, "max_total": {"22.50"}}, []string{"22.50", "9.00"}},
+			{"Total in USD", url.Values{"min_total": {"1"}, "currency": {"USD"}}, []string{"12.00"}},
+			{"Item price", url.Values{"min_price": {"10"}, "max_price": {"30"}}, []string{"22.50"}},
+			{"Quantity", url.Values{"min_quantity": {"3"}}, []string{"22.50", "12.00"}},
+			{"Product", url.Values{"product": {"red"}}, []string{"3.00", "12.00"}},
+			{"Product wildcard is literal", url.Values{"product": {"k_l"}}, []string{"50.00"}},
+			{"Product percent is literal", url.Values{"product": {"%"}}, []string{}},
+			{"Sort by total", url.Values{"sort": {"total"}}, []string{"3.00", "9.00", "12.00", "22.50", "50.00"}},
+			{"Sort by item price", url.Values{"sort": {"-price"}}, []string{"50.00", "22.50", "9.00", "12.00", "3.00"}},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				code, resp := list(tc.query)
+				require.Equal(t, http.StatusOK, code)
+				require.Equal(t, tc.want, totals(resp))
+				require.EqualValues(t, len(tc.want), resp.Total)
+			})
+		}
+	})
+
+	t.Run("PageNumbers", func(t *testing.T) {
+		code, resp := list(url.Values{"page": {"2"}, "limit": {"2"}, "sort": {"total"}})
+		require.Equal(t, http.StatusOK, code)
+		require.EqualValues(t, 5, resp.Total)
+		require.Equal(t, []string{"12.00", "22.50"}, totals(resp))
+	})
+
+	t.Run("Cursor", func(t *testing.T) {
+		for _, sort := range []string{"-created_at", "total", "-price"} {
+			_, all := list(url.Values{"sort": {sort}})
+			var got []string
+			query := url.Values{"sort": {sort}, "limit": {"2"}}
+			for i := 0; i < 5; i++ {
+				code, resp := list(query)
+				require.Equal(t, http.StatusOK, code)
+				require.EqualValues(t, 5, resp.Total)
+				got = append(got, totals(resp)...)
+				if resp.NextCursor == "" {
+					break
+				}
+				query.Set("cursor", resp.NextCursor)
+			}
+			require.Equal(t, totals(all), got, sort)
+		}
+
+		// курсор выдан для другой сортировки
+		_, first := list(url.Values{"limit": {"1"}})
+		code, _ := list(url.Values{"cursor": {first.NextCursor}, "sort": {"total"}})
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("InvalidParams", func(t *testing.T) {
+		for _, q := range []url.Values{
+			{"page": {"-1"}},
+			{"limit": {"101"}},
+			{"sort": {"name"}},
+			{"created_from": {"01.03.2024"}},
+			{"min_total": {"1.001"}},
+			{"min_total": {"abc"}},
+			{"currency": {"XYZ"}},
+			{"cursor": {"garbage"}},
+			{"cursor": {"x"}, "page": {"2"}},
+		} {
+			code, _ := list(q)
+			require.Equal(t, http.StatusBadRequest, code, q.Encode())
+		}
+	})
+}
+
+// TestAdminOrderSearch проверяет поиск заказов всех пользователей GET /orders.
+func TestAdminOrderSearch(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	ctx := context.Background()
+
+	userSvc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
+	register := func(name, email string) uint {
+		u, err := userSvc.Create(ctx, &services.RegisterRequest{Name: name, Email: email, Password: "Tr0ub4dor&3x", Age: 30})
+		require.NoError(t, err)
+		return u.ID
+	}
+	alice := register("Alice", "alice@example.com")
+	bob := register("Bob", "bob@example.com")
+
+	pen := createTestProduct(t, db, "PEN-1", "Pen", 150, 100)
+	lamp := createTestProduct(t, db, "LAMP-1", "Lamp", 5000, 100)
+	orderSvc := services.NewOrderService(db, testConfig(), pricing.NoTax{}, pricing.NoShipping{}, newTestPaymentProvider())
+	create := func(userID uint, items ...services.OrderItemRequest) uint {
+		o, err := orderSvc.Create(ctx, userID, &services.CreateOrderRequest{Items: items})
+		require.NoError(t, err)
+		return o.ID
+	}
+	a1 := create(alice, services.OrderItemRequest{ProductID: pen.ID, Quantity: 1})
+	a2 := create(alice, services.OrderItemRequest{ProductID: lamp.ID, Quantity: 2})
+	b1 := create(bob, services.OrderItemRequest{ProductID: pen.ID, Quantity: 3}, services.OrderItemRequest{ProductID: lamp.ID, Quantity: 1})
+	require.NoError(t, db.Model(&models.Order{}).Where("id = ?", a2).Update("status", models.OrderStatusPaid).Error)
+
+	orderH := handlers.NewOrderHandler(db, testConfig(), pricing.NoTax{}, pricing.NoShipping{}, newTestPaymentProvider())
+	r := gin.New()
+	r.GET("/orders", orderH.Search)
+
+	search := func(query url.Values) (int, handlers.AdminOrderListResponse) {
+		req, _ := http.NewRequest(http.MethodGet, "/orders?"+query.Encode(), nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var resp handlers.AdminOrderListResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+	ids := func(resp handlers.AdminOrderListResponse) []uint {
+		out := make([]uint, len(resp.Orders))
+		for i, o := range resp.Orders {
+			out[i] = o.ID
+		}
+		return out
+	}
+
+	cases := []struct {
+		name  string
+		query url.Values
+		want  []uint
+	}{
+		{"All users", url.Values{"sort": {"created_at"}}, []uint{a1, a2, b1}},
+		{"By user", url.Values{"user_id": {fmt.Sprint(bob)}}, []uint{b1}},
+		{"By product", url.Values{"product_id": {fmt.Sprint(lamp.ID)}, "sort": {"created_at"}}, []uint{a2, b1}},
+		{"By status", url.Values{"status": {"paid"}}, []uint{a2}},
+		{"Over amount", url.Values{"min_total": {"50"}, "sort": {"-total"}}, []uint{a2, b1}},
+		{"Last week", url.Values{"created_from": {time.Now().AddDate(0, 0, -7).Format(time.DateOnly)}, "sort": {"created_at"}}, []uint{a1, a2, b1}},
+		{"Nothing", url.Values{"created_to": {"2000-01-01"}}, []uint{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, resp := search(tc.query)
+			require.Equal(t, http.StatusOK, code)
+			require.Equal(t, tc.want, ids(resp))
+			require.EqualValues(t, len(tc.want), resp.Total)
+			for _, o := range resp.Orders {
+				require.Nil(t, o.User)
+			}
+		})
+	}
+
+	t.Run("IncludeUser", func(t *testing.T) {
+		code, resp := search(url.Values{"include_user": {"true"}, "sort": {"created_at"}})
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, resp.Orders, 3)
+		require.Equal(t, "alice@example.com", resp.Orders[0].User.Email)
+		require.Equal(t, alice, resp.Orders[1].User.ID)
+		require.Equal(t, "Bob", resp.Orders[2].User.Name)
+	})
+
+	t.Run("Cursor", func(t *testing.T) {
+		code, first := search(url.Values{"limit": {"2"}, "sort": {"created_at"}})
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, []uint{a1, a2}, ids(first))
+		require.NotEmpty(t, first.NextCursor)
+		code, second := search(url.Values{"limit": {"2"}, "sort": {"created_at"}, "cursor": {first.NextCursor}})
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, []uint{b1}, ids(second))
+		require.Empty(t, second.NextCursor)
+	})
+
+	t.Run("InvalidParams", func(t *testing.T) {
+		for _, q := range []url.Values{
+			{"status": {"lost"}},
+			{"user_id": {"abc"}},
+			{"include_user": {"maybe"}},
+		} {
+			code, _ := search(q)
+			require.Equal(t, http.StatusBadRequest, code, q.Encode())
+		}
+	})
+}
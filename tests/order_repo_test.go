@@ -34,19 +34,24 @@ func TestOrderRepository(t *testing.T) {
 	// Проверяем успешное добавление заказов в базу данных.
 	// Убедимся, что идентификаторы заказов не равны нулю.
 	o1 := &repositories.Order{
-		UserID:   user.ID,
-		Product:  "Prod1",
-		Quantity: 2,
-		Price:    10.5,
+		UserID: user.ID,
+		Items: []models.OrderItem{
+			{Product: "Prod1", Quantity: 2, PriceMinor: 1050},
+			{Product: "Prod3", Quantity: 1, PriceMinor: 300},
+		},
+		Currency:      "RUB",
+		SubtotalMinor: 2400,
+		TotalMinor:    2400,
 	}
 	require.NoError(t, orderRepo.Create(context.Background(), o1))
 	require.NotZero(t, o1.ID)
 
 	o2 := &repositories.Order{
-		UserID:   user.ID,
-		Product:  "Prod2",
-		Quantity: 5,
-		Price:    7.25,
+		UserID:        user.ID,
+		Items:         []models.OrderItem{{Product: "Prod2", Quantity: 5, PriceMinor: 725}},
+		Currency:      "RUB",
+		SubtotalMinor: 3625,
+		TotalMinor:    3625,
 	}
 	require.NoError(t, orderRepo.Create(context.Background(), o2))
 	require.NotZero(t, o2.ID)
@@ -54,15 +59,20 @@ func TestOrderRepository(t *testing.T) {
 	// 2. ListByUser
 	// Проверяем, что метод ListByUser возвращает корректный список заказов
 	// для указанного пользователя.
-	list, err := orderRepo.ListByUser(context.Background(), user.ID)
+	list, total, err := orderRepo.ListByUser(context.Background(), user.ID, &repositories.OrderFilter{Limit: 10})
 	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
 	require.Len(t, list, 2)
 
 	// Ensure both products are present
 	found := map[string]bool{}
 	for _, o := range list {
-		found[o.Product] = true
+		for _, it := range o.Items {
+			require.Equal(t, o.ID, it.OrderID)
+			found[it.Product] = true
+		}
 	}
 	require.True(t, found["Prod1"])
 	require.True(t, found["Prod2"])
+	require.True(t, found["Prod3"])
 }
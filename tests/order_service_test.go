@@ -4,6 +4,8 @@ import (
 	"context"
 	"testing"
 
+	"kvant_task/internal/notify"
+	"kvant_task/internal/pricing"
 	"kvant_task/internal/repositories"
 	"kvant_task/internal/services"
 
@@ -17,64 +19,79 @@ func TestOrderService(t *testing.T) {
 	cleanUsers(t, db)
 
 	// First, create a user to attach orders to
-	userSvc := services.NewUserService(db, "test-secret")
+	userSvc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
 	user, err := userSvc.Create(context.Background(), &services.RegisterRequest{
 		Name:     "Order Tester",
 		Email:    "ordertester@example.com",
-		Password: "pass123",
+		Password: "Tr0ub4dor&3x",
 		Age:      30,
 	})
 	require.NoError(t, err)
 	require.NotZero(t, user.ID)
 
-	orderSvc := services.NewOrderService(db)
+	gadget := createTestProduct(t, db, "GADGET-1", "Gadget", 1995, 10)
+	createTestProduct(t, db, "CHARGER-1", "Charger", 410, 10)
+	createTestProduct(t, db, "WIDGET-1", "Widget", 500, 10)
+	createTestProduct(t, db, "THING-1", "Thing", 1250, 10)
+
+	orderSvc := services.NewOrderService(db, testConfig(), pricing.NoTax{}, pricing.NoShipping{}, newTestPaymentProvider())
 
 	t.Run("CreateOrder_Success", func(t *testing.T) {
 		// Проверяем успешное создание заказа через сервисный слой.
 		// Убедимся, что данные заказа корректно сохраняются в базе данных.
 		req := &services.CreateOrderRequest{
-			Product:  "Gadget",
-			Quantity: 3,
-			Price:    19.95,
+			Items: []services.OrderItemRequest{
+				{ProductID: gadget.ID, Quantity: 3},
+				{SKU: "CHARGER-1", Quantity: 1},
+			},
 		}
 		o, err := orderSvc.Create(context.Background(), user.ID, req)
 		require.NoError(t, err)
 		require.NotZero(t, o.ID)
 		require.Equal(t, user.ID, o.UserID)
-		require.Equal(t, "Gadget", o.Product)
-		require.Equal(t, 3, o.Quantity)
-		require.Equal(t, 19.95, o.Price)
+		require.Len(t, o.Items, 2)
+		require.Equal(t, "Gadget", o.Items[0].Product)
+		require.Equal(t, 3, o.Items[0].Quantity)
+		require.Equal(t, "19.95", o.Items[0].Price)
+		require.Equal(t, "59.85", o.Items[0].LineTotal)
+		require.Equal(t, "63.95", o.Subtotal)
+		require.Equal(t, "63.95", o.Total)
 
 		// verify in DB
 		var dbOrder repositories.Order
-		err = db.First(&dbOrder, o.ID).Error
+		err = db.Preload("Items").First(&dbOrder, o.ID).Error
 		require.NoError(t, err)
 		require.Equal(t, user.ID, dbOrder.UserID)
-		require.Equal(t, "Gadget", dbOrder.Product)
-		require.Equal(t, 3, dbOrder.Quantity)
-		require.Equal(t, 19.95, dbOrder.Price)
+		require.Len(t, dbOrder.Items, 2)
+		require.EqualValues(t, 6395, dbOrder.SubtotalMinor)
+		require.EqualValues(t, 6395, dbOrder.TotalMinor)
+		require.Equal(t, "RUB", dbOrder.Currency)
 	})
 
 	t.Run("ListByUser_ReturnsAll", func(t *testing.T) {
 		// create additional orders
 		orders := []services.CreateOrderRequest{
-			{Product: "Widget", Quantity: 1, Price: 5.00},
-			{Product: "Thing", Quantity: 2, Price: 12.50},
+			{Items: []services.OrderItemRequest{{SKU: "WIDGET-1", Quantity: 1}}},
+			{Items: []services.OrderItemRequest{{SKU: "THING-1", Quantity: 2}}},
 		}
 		for _, req := range orders {
 			_, err := orderSvc.Create(context.Background(), user.ID, &req)
 			require.NoError(t, err)
 		}
 
-		list, err := orderSvc.ListByUser(context.Background(), user.ID)
+		list, total, next, err := orderSvc.ListByUser(context.Background(), user.ID, &services.OrderListQuery{})
 		require.NoError(t, err)
+		require.EqualValues(t, 3, total)
+		require.Empty(t, next)
 		// should have 3 orders now
 		require.Len(t, list, 3)
 
 		// check that each product from our requests appears exactly once
 		found := make(map[string]bool)
 		for _, o := range list {
-			found[o.Product] = true
+			for _, it := range o.Items {
+				found[it.Product] = true
+			}
 		}
 		require.True(t, found["Gadget"])
 		require.True(t, found["Widget"])
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"kvant_task/internal/models"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/pricing"
+	"kvant_task/internal/services"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestOrderStatus проверяет допустимые переходы статусов заказа, права владельца
+// и администратора и запись истории переходов.
+func TestOrderStatus(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	ctx := context.Background()
+
+	userSvc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
+	user, err := userSvc.Create(ctx, &services.RegisterRequest{
+		Name:     "Buyer",
+		Email:    "buyer@example.com",
+		Password: "Tr0ub4dor&3x",
+		Age:      30,
+	})
+	require.NoError(t, err)
+	createTestProduct(t, db, "KETTLE-1", "Kettle", 3000, 100)
+
+	svc := services.NewOrderService(db, testConfig(), pricing.NoTax{}, pricing.NoShipping{}, newTestPaymentProvider())
+	newOrder := func() *services.OrderResponse {
+		o, err := svc.Create(ctx, user.ID, &services.CreateOrderRequest{
+			Items: []services.OrderItemRequest{{SKU: "KETTLE-1", Quantity: 1}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, models.OrderStatusPending, o.Status)
+		return o
+	}
+	owner := services.Actor{UserID: user.ID, Role: models.RoleUser}
+	admin := services.Actor{UserID: 999, Role: models.RoleAdmin}
+	move := func(o *services.OrderResponse, actor services.Actor, status string) error {
+		_, err := svc.Transition(ctx, user.ID, o.ID, actor, &services.OrderTransitionRequest{Status: status})
+		return err
+	}
+
+	t.Run("Lifecycle", func(t *testing.T) {
+		o := newOrder()
+		// владелец не может отметить заказ оплаченным
+		require.ErrorIs(t, move(o, owner, models.OrderStatusPaid), services.ErrTransitionForbidden)
+		// через статус перескочить нельзя
+		require.ErrorIs(t, move(o, admin, models.OrderStatusShipped), services.ErrInvalidTransition)
+
+		for _, status := range []string{models.OrderStatusPaid, models.OrderStatusShipped, models.OrderStatusDelivered} {
+			require.NoError(t, move(o, admin, status))
+		}
+		// delivered — конечный статус
+		require.ErrorIs(t, move(o, admin, models.OrderStatusCancelled), services.ErrInvalidTransition)
+
+		history, err := svc.StatusHistory(ctx, user.ID, o.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 3)
+		require.Equal(t, models.OrderStatusPending, history[0].FromStatus)
+		require.Equal(t, models.OrderStatusPaid, history[0].ToStatus)
+		require.Equal(t, uint(999), history[0].ActorID)
+		require.Equal(t, models.RoleAdmin, history[0].ActorRole)
+		require.Equal(t, models.OrderStatusDelivered, history[2].ToStatus)
+	})
+
+	t.Run("OwnerCancels", func(t *testing.T) {
+		o := newOrder()
+		resp, err := svc.Transition(ctx, user.ID, o.ID, owner, &services.OrderTransitionRequest{
+			Status:  models.OrderStatusCancelled,
+			Comment: "передумал",
+		})
+		require.NoError(t, err)
+		require.Equal(t, models.OrderStatusCancelled, resp.Status)
+		require.ErrorIs(t, move(o, owner, models.OrderStatusCancelled), services.ErrInvalidTransition)
+
+		history, err := svc.StatusHistory(ctx, user.ID, o.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		require.Equal(t, "передумал", history[0].Comment)
+	})
+
+	t.Run("ForeignOrder", func(t *testing.T) {
+		o := newOrder()
+		_, err := svc.Transition(ctx, user.ID+1, o.ID, admin, &services.OrderTransitionRequest{Status: models.OrderStatusPaid})
+		require.ErrorIs(t, err, services.ErrOrderNotFound)
+	})
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"kvant_task/internal/models"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/password"
+	"kvant_task/internal/services"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestPasswordHasher проверяет формат PHC для Argon2id, проверку хэшей bcrypt
+// и признак перехэширования при смене алгоритма или параметров.
+func TestPasswordHasher(t *testing.T) {
+	argon := password.Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1}
+	bc := password.Bcrypt{Cost: 4}
+	m := password.NewManager(argon, bc)
+
+	t.Run("Argon2id_PHC", func(t *testing.T) {
+		hash, err := m.Hash("secret-pass")
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+		require.Len(t, strings.Split(hash, "$"), 6)
+
+		ok, rehash, err := m.Verify("secret-pass", hash)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.False(t, rehash)
+
+		ok, _, err = m.Verify("other-pass", hash)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		// соль случайная
+		again, err := m.Hash("secret-pass")
+		require.NoError(t, err)
+		require.NotEqual(t, hash, again)
+	})
+
+	t.Run("Rehash_OutdatedParams", func(t *testing.T) {
+		hash, err := m.Hash("secret-pass")
+		require.NoError(t, err)
+		stronger := password.NewManager(password.Argon2id{Memory: 2048, Iterations: 1, Parallelism: 1}, bc)
+		ok, rehash, err := stronger.Verify("secret-pass", hash)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.True(t, rehash)
+	})
+
+	t.Run("Rehash_Bcrypt", func(t *testing.T) {
+		legacy, err := bcrypt.GenerateFromPassword([]byte("secret-pass"), bcrypt.MinCost)
+		require.NoError(t, err)
+		ok, rehash, err := m.Verify("secret-pass", string(legacy))
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.True(t, rehash)
+
+		ok, rehash, err = m.Verify("other-pass", string(legacy))
+		require.NoError(t, err)
+		require.False(t, ok)
+		require.False(t, rehash)
+	})
+
+	t.Run("UnknownFormat", func(t *testing.T) {
+		_, _, err := m.Verify("secret-pass", "plain")
+		require.ErrorIs(t, err, password.ErrUnknownFormat)
+		_, _, err = m.Verify("secret-pass", "$argon2id$v=19$m=0,t=1,p=1$AAAA$AAAA")
+		require.ErrorIs(t, err, password.ErrUnknownFormat)
+	})
+}
+
+// TestLogin_RehashesLegacyPassword проверяет, что вход с bcrypt-хэшем сохраняет хэш Argon2id.
+func TestLogin_RehashesLegacyPassword(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	ctx := context.Background()
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("Tr0ub4dor&3x"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := models.User{Name: "Legacy", Email: "legacy@example.com", PasswordHash: string(legacy), Age: 50}
+	require.NoError(t, db.Create(&user).Error)
+
+	svc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
+	login := &services.LoginRequest{Email: "legacy@example.com", Password: "Tr0ub4dor&3x"}
+	_, err = svc.Login(ctx, login, services.ClientInfo{})
+	require.NoError(t, err)
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	require.True(t, strings.HasPrefix(stored.PasswordHash, "$argon2id$"))
+
+	// новый хэш принимается
+	_, err = svc.Login(ctx, login, services.ClientInfo{})
+	require.NoError(t, err)
+}
@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"strings"
+	"testing"
+
+	"kvant_task/internal/password"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestPasswordPolicy проверяет требования к длине, типам символов, личным данным
+// и проверку по списку утёкших паролей.
+func TestPasswordPolicy(t *testing.T) {
+	policy := newTestPolicy()
+
+	violations := func(t *testing.T, pw string, personal ...string) []string {
+		err := policy.Validate(pw, personal...)
+		if err == nil {
+			return nil
+		}
+		var pe *password.PolicyError
+		require.ErrorAs(t, err, &pe)
+		return pe.Violations
+	}
+
+	t.Run("Accepts", func(t *testing.T) {
+		require.Empty(t, violations(t, "Tr0ub4dor&3x", "Alice Smith", "alice@example.com"))
+		require.Empty(t, violations(t, "correct horse 42"))
+	})
+
+	t.Run("EachViolationReported", func(t *testing.T) {
+		require.Len(t, violations(t, "abc"), 2)
+		require.Len(t, violations(t, "aliceeee", "Alice", "a@example.com"), 2)
+	})
+
+	t.Run("Personal", func(t *testing.T) {
+		require.Len(t, violations(t, "Smith-2024!", "Alice Smith", "x@example.com"), 1)
+		require.Len(t, violations(t, "my.JOHN.d0e", "Name", "john.doe@example.com"), 1)
+		// короткие части имени не проверяются
+		require.Empty(t, violations(t, "Tr0ub4dor&3x", "Al", "ab@example.com"))
+	})
+
+	t.Run("Breached", func(t *testing.T) {
+		v := violations(t, "Password123")
+		require.Len(t, v, 1)
+		require.Contains(t, v[0], "утечках")
+		require.Len(t, violations(t, "qwerty123"), 1)
+	})
+
+	t.Run("BreachedList_Range", func(t *testing.T) {
+		var buf bytes.Buffer
+		hashes := [][sha1.Size]byte{sha1.Sum([]byte("hunter2")), sha1.Sum([]byte("hunter2")), sha1.Sum([]byte("letmein"))}
+		require.NoError(t, password.WriteBreachedList(&buf, hashes))
+		list, err := password.ReadBreachedList(&buf)
+		require.NoError(t, err)
+		require.Equal(t, 2, list.Len())
+
+		ok, err := password.IsBreached(list, "hunter2")
+		require.NoError(t, err)
+		require.True(t, ok)
+		ok, err = password.IsBreached(list, "hunter3")
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		_, err = list.Range("ZZZZZ")
+		require.ErrorIs(t, err, password.ErrInvalidPrefix)
+		suffixes, err := list.Range("00000")
+		require.NoError(t, err)
+		require.Empty(t, suffixes)
+
+		_, err = password.ReadBreachedList(strings.NewReader("nope"))
+		require.Error(t, err)
+	})
+}
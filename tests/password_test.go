@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"kvant_task/internal/services"
+
+	"github.com/stretchr/testify/require"
+)
+
+// resetTokenRe извлекает токен сброса из текста уведомления.
+var resetTokenRe = regexp.MustCompile(`Токен для сброса пароля: (\S+)`)
+
+// TestPasswordChangeAndReset проверяет смену пароля с проверкой текущего,
+// сброс пароля по одноразовому токену и отзыв всех токенов после смены.
+func TestPasswordChangeAndReset(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	ctx := context.Background()
+
+	tokens := newTestTokenService()
+	notifier := &recordingNotifier{}
+	svc := services.NewUserService(db, testConfig(), tokens, notifier, newTestGuard(), newTestPolicy())
+
+	user, err := svc.Create(ctx, &services.RegisterRequest{
+		Name:     "Pass",
+		Email:    "pass@example.com",
+		Password: "Tr0ub4dor&3x",
+		Age:      30,
+	})
+	require.NoError(t, err)
+
+	t.Run("Change_WrongCurrent", func(t *testing.T) {
+		err := svc.ChangePassword(ctx, user.ID, &services.ChangePasswordRequest{
+			CurrentPassword: "wrong",
+			NewPassword:     "Bl4ck-Sw4n-Lake",
+		})
+		require.ErrorIs(t, err, services.ErrWrongPassword)
+	})
+
+	t.Run("Change_RevokesTokens", func(t *testing.T) {
+		before, err := svc.Login(ctx, &services.LoginRequest{Email: "pass@example.com", Password: "Tr0ub4dor&3x"}, services.ClientInfo{})
+		require.NoError(t, err)
+
+		require.NoError(t, svc.ChangePassword(ctx, user.ID, &services.ChangePasswordRequest{
+			CurrentPassword: "Tr0ub4dor&3x",
+			NewPassword:     "Bl4ck-Sw4n-Lake",
+		}))
+
+		// старые access- и refresh-токены больше не действуют
+		_, err = tokens.Validate(ctx, before.Token)
+		require.ErrorIs(t, err, services.ErrTokenRevoked)
+		_, err = svc.Refresh(ctx, &services.RefreshRequest{RefreshToken: before.RefreshToken})
+		require.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+
+		_, err = svc.Login(ctx, &services.LoginRequest{Email: "pass@example.com", Password: "Tr0ub4dor&3x"}, services.ClientInfo{})
+		require.ErrorIs(t, err, services.ErrInvalidCredentials)
+		_, err = svc.Login(ctx, &services.LoginRequest{Email: "pass@example.com", Password: "Bl4ck-Sw4n-Lake"}, services.ClientInfo{})
+		require.NoError(t, err)
+	})
+
+	t.Run("Reset_UnknownEmail", func(t *testing.T) {
+		sent := len(notifier.sent)
+		require.NoError(t, svc.RequestPasswordReset(ctx, &services.PasswordResetRequest{Email: "nobody@example.com"}))
+		require.Len(t, notifier.sent, sent)
+	})
+
+	t.Run("Reset_Flow", func(t *testing.T) {
+		require.NoError(t, svc.RequestPasswordReset(ctx, &services.PasswordResetRequest{Email: "pass@example.com"}))
+		msg := notifier.last()
+		require.Equal(t, "pass@example.com", msg.To)
+		m := resetTokenRe.FindStringSubmatch(msg.Body)
+		require.Len(t, m, 2)
+
+		require.NoError(t, svc.ConfirmPasswordReset(ctx, &services.PasswordResetConfirmRequest{
+			Token:       m[1],
+			NewPassword: "R3set-Galaxy-77",
+		}))
+		_, err := svc.Login(ctx, &services.LoginRequest{Email: "pass@example.com", Password: "R3set-Galaxy-77"}, services.ClientInfo{})
+		require.NoError(t, err)
+
+		// токен одноразовый
+		err = svc.ConfirmPasswordReset(ctx, &services.PasswordResetConfirmRequest{
+			Token:       m[1],
+			NewPassword: "An0ther-Galaxy-88",
+		})
+		require.ErrorIs(t, err, services.ErrInvalidResetToken)
+	})
+}
@@ -0,0 +1,317 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"kvant_task/internal/handlers"
+	"kvant_task/internal/models"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/payment"
+	"kvant_task/internal/pricing"
+	"kvant_task/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFakeProvider проверяет встроенный платёжный провайдер: исход по способу оплаты,
+// ограничения списания и возврата и подпись уведомлений.
+func TestFakeProvider(t *testing.T) {
+	ctx := context.Background()
+	p := payment.NewFakeProvider("secret")
+
+	res, err := p.Authorize(ctx, payment.AuthorizeRequest{Reference: "1", AmountMinor: 1000, Currency: "RUB"})
+	require.NoError(t, err)
+	require.Equal(t, payment.Result{ProviderRef: "fake_1", Status: payment.StatusAuthorized}, res)
+	// повтор авторизации с той же ссылкой не создаёт второй платёж
+	res, err = p.Authorize(ctx, payment.AuthorizeRequest{Reference: "1", AmountMinor: 1000, Currency: "RUB"})
+	require.NoError(t, err)
+	require.Equal(t, payment.StatusAuthorized, res.Status)
+
+	_, err = p.Refund(ctx, "fake_1", 1000)
+	require.ErrorIs(t, err, payment.ErrInvalidState)
+	_, err = p.Capture(ctx, "fake_1", 1001)
+	require.ErrorIs(t, err, payment.ErrInvalidState)
+	res, err = p.Capture(ctx, "fake_1", 1000)
+	require.NoError(t, err)
+	require.Equal(t, payment.StatusCaptured, res.Status)
+	res, err = p.Refund(ctx, "fake_1", 400)
+	require.NoError(t, err)
+	require.Equal(t, payment.StatusCaptured, res.Status)
+	res, err = p.Refund(ctx, "fake_1", 600)
+	require.NoError(t, err)
+	require.Equal(t, payment.StatusRefunded, res.Status)
+
+	_, err = p.Authorize(ctx, payment.AuthorizeRequest{Reference: "2", AmountMinor: 1000, Method: payment.FakeMethodDeclined})
+	require.ErrorIs(t, err, payment.ErrDeclined)
+	_, err = p.Authorize(ctx, payment.AuthorizeRequest{Reference: "3", AmountMinor: 1000, Method: "visa"})
+	require.ErrorIs(t, err, payment.ErrDeclined)
+
+	res, err = p.Authorize(ctx, payment.AuthorizeRequest{Reference: "4", AmountMinor: 1000, Currency: "RUB", Method: payment.FakeMethodAsync})
+	require.NoError(t, err)
+	require.Equal(t, payment.StatusPending, res.Status)
+	_, err = p.Capture(ctx, "fake_4", 1000)
+	require.ErrorIs(t, err, payment.ErrInvalidState)
+	body, header, err := p.Webhook("evt_1", payment.EventCaptured, "fake_4")
+	require.NoError(t, err)
+	e, err := p.VerifyWebhook(header, body)
+	require.NoError(t, err)
+	require.Equal(t, &payment.Event{ID: "evt_1", Type: payment.EventCaptured, ProviderRef: "fake_4", AmountMinor: 1000, Currency: "RUB"}, e)
+	// уведомление только для платежа в обработке
+	_, _, err = p.Webhook("evt_2", payment.EventCaptured, "fake_4")
+	require.ErrorIs(t, err, payment.ErrInvalidState)
+
+	_, err = p.VerifyWebhook(header, append(body, ' '))
+	require.ErrorIs(t, err, payment.ErrInvalidWebhook)
+	_, err = payment.NewFakeProvider("other").VerifyWebhook(header, body)
+	require.ErrorIs(t, err, payment.ErrInvalidWebhook)
+}
+
+// TestPayments проверяет оплату заказов: перевод заказа в paid, отказ провайдера,
+// асинхронное подтверждение уведомлением, повтор уведомлений и возврат платежа
+// по отменённому заказу.
+func TestPayments(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	ctx := context.Background()
+
+	userSvc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
+	u, err := userSvc.Create(ctx, &services.RegisterRequest{Name: "Payer", Email: "payer@example.com", Password: "Tr0ub4dor&3x", Age: 30})
+	require.NoError(t, err)
+	other, err := userSvc.Create(ctx, &services.RegisterRequest{Name: "Other", Email: "other@example.com", Password: "Tr0ub4dor&3x", Age: 30})
+	require.NoError(t, err)
+	createTestProduct(t, db, "BOOK-1", "Book", 1999, 100)
+
+	provider := newTestPaymentProvider()
+	orders := services.NewOrderService(db, testConfig(), pricing.NoTax{}, pricing.NoShipping{}, provider)
+	payments := services.NewPaymentService(db, testConfig(), provider)
+	owner := services.Actor{UserID: u.ID, Role: models.RoleUser}
+	newOrder := func() *services.OrderResponse {
+		o, err := orders.Create(ctx, u.ID, &services.CreateOrderRequest{
+			Items: []services.OrderItemRequest{{SKU: "BOOK-1", Quantity: 2}},
+		})
+		require.NoError(t, err)
+		return o
+	}
+	status := func(id uint) string {
+		o, err := orders.Get(ctx, u.ID, id)
+		require.NoError(t, err)
+		return o.Status
+	}
+
+	// хендлер уведомлений — как в роутере, без авторизации
+	r := gin.New()
+	r.POST("/payments/webhook", handlers.NewPaymentHandler(db, testConfig(), provider).Webhook)
+	deliver := func(body []byte, header http.Header) int {
+		req, _ := http.NewRequest("POST", "/payments/webhook", bytes.NewReader(body))
+		req.Header = header
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	// sign подписывает уведомление, собранное вручную, секретом провайдера
+	sign := func(body []byte) http.Header {
+		mac := hmac.New(sha256.New, []byte(testConfig().Payments.WebhookSecret))
+		mac.Write(body)
+		header := http.Header{}
+		header.Set(payment.FakeSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+		return header
+	}
+	admin := services.Actor{UserID: 999, Role: models.RoleAdmin}
+	paymentsOf := func(id uint) []services.PaymentResponse {
+		list, err := payments.ListByOrder(ctx, u.ID, id)
+		require.NoError(t, err)
+		return list
+	}
+
+	t.Run("Paid", func(t *testing.T) {
+		o := newOrder()
+		p, err := payments.Pay(ctx, u.ID, o.ID, owner, &services.PayOrderRequest{})
+		require.NoError(t, err)
+		require.Equal(t, models.PaymentStatusCaptured, p.Status)
+		require.Equal(t, "39.98", p.Amount)
+		require.Equal(t, "RUB", p.Currency)
+		require.Equal(t, "fake", p.Provider)
+		require.NotEmpty(t, p.ProviderRef)
+		require.Equal(t, models.OrderStatusPaid, status(o.ID))
+
+		history, err := orders.StatusHistory(ctx, u.ID, o.ID)
+		require.NoError(t, err)
+		last := history[len(history)-1]
+		require.Equal(t, models.OrderStatusPaid, last.ToStatus)
+		require.Equal(t, u.ID, last.ActorID)
+
+		_, err = payments.Pay(ctx, u.ID, o.ID, owner, &services.PayOrderRequest{})
+		require.ErrorIs(t, err, services.ErrOrderNotPayable)
+		_, err = payments.Pay(ctx, other.ID, o.ID, services.Actor{UserID: other.ID, Role: models.RoleUser}, &services.PayOrderRequest{})
+		require.ErrorIs(t, err, services.ErrOrderNotFound)
+	})
+
+	t.Run("Declined", func(t *testing.T) {
+		o := newOrder()
+		_, err := payments.Pay(ctx, u.ID, o.ID, owner, &services.PayOrderRequest{Method: payment.FakeMethodDeclined})
+		require.ErrorIs(t, err, services.ErrPaymentDeclined)
+		require.Equal(t, models.OrderStatusPending, status(o.ID))
+
+		// после отказа заказ можно оплатить снова
+		p, err := payments.Pay(ctx, u.ID, o.ID, owner, &services.PayOrderRequest{Method: payment.FakeMethodSuccess})
+		require.NoError(t, err)
+		require.Equal(t, models.PaymentStatusCaptured, p.Status)
+
+		list, err := payments.ListByOrder(ctx, u.ID, o.ID)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		require.Equal(t, models.PaymentStatusFailed, list[0].Status)
+		require.NotEmpty(t, list[0].FailureReason)
+		require.Equal(t, models.PaymentStatusCaptured, list[1].Status)
+	})
+
+	t.Run("AsyncWebhook", func(t *testing.T) {
+		o := newOrder()
+		p, err := payments.Pay(ctx, u.ID, o.ID, owner, &services.PayOrderRequest{Method: payment.FakeMethodAsync})
+		require.NoError(t, err)
+		require.Equal(t, models.PaymentStatusPending, p.Status)
+		require.Equal(t, models.OrderStatusPending, status(o.ID))
+
+		// пока платёж в обработке, второй платёж и изменение заказа запрещены
+		_, err = payments.Pay(ctx, u.ID, o.ID, owner, &services.PayOrderRequest{})
+		require.ErrorIs(t, err, services.ErrPaymentInProgress)
+		_, err = orders.Update(ctx, u.ID, o.ID, owner, &services.UpdateOrderRequest{
+			Items: []services.OrderItemRequest{{SKU: "BOOK-1", Quantity: 1}},
+		})
+		require.ErrorIs(t, err, services.ErrPaymentInProgress)
+		require.ErrorIs(t, orders.Delete(ctx, u.ID, o.ID), services.ErrPaymentInProgress)
+
+		body, header, err := provider.Webhook("evt_async", payment.EventCaptured, p.ProviderRef)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNoContent, deliver(body, header))
+		require.Equal(t, models.OrderStatusPaid, status(o.ID))
+
+		// повтор того же уведомления ничего не меняет
+		require.Equal(t, http.StatusNoContent, deliver(body, header))
+		history, err := orders.StatusHistory(ctx, u.ID, o.ID)
+		require.NoError(t, err)
+		paid := 0
+		for _, h := range history {
+			if h.ToStatus == models.OrderStatusPaid {
+				paid++
+				require.Equal(t, "payment", h.ActorRole)
+			}
+		}
+		require.Equal(t, 1, paid)
+
+		list, err := payments.ListByOrder(ctx, u.ID, o.ID)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		require.Equal(t, models.PaymentStatusCaptured, list[0].Status)
+	})
+
+	t.Run("AsyncFailed", func(t *testing.T) {
+		o := newOrder()
+		p, err := payments.Pay(ctx, u.ID, o.ID, owner, &services.PayOrderRequest{Method: payment.FakeMethodAsync})
+		require.NoError(t, err)
+		body, header, err := provider.Webhook("evt_failed", payment.EventFailed, p.ProviderRef)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNoContent, deliver(body, header))
+		require.Equal(t, models.OrderStatusPending, status(o.ID))
+
+		list, err := payments.ListByOrder(ctx, u.ID, o.ID)
+		require.NoError(t, err)
+		require.Equal(t, models.PaymentStatusFailed, list[0].Status)
+	})
+
+	t.Run("TransitionWhilePaying", func(t *testing.T) {
+		o := newOrder()
+		_, err := payments.Pay(ctx, u.ID, o.ID, owner, &services.PayOrderRequest{Method: payment.FakeMethodAsync})
+		require.NoError(t, err)
+		// пока платёж в обработке, заказ нельзя отменить или отметить оплаченным вручную
+		_, err = orders.Transition(ctx, u.ID, o.ID, owner, &services.OrderTransitionRequest{Status: models.OrderStatusCancelled})
+		require.ErrorIs(t, err, services.ErrPaymentInProgress)
+		_, err = orders.Transition(ctx, u.ID, o.ID, admin, &services.OrderTransitionRequest{Status: models.OrderStatusPaid})
+		require.ErrorIs(t, err, services.ErrPaymentInProgress)
+		require.Equal(t, models.OrderStatusPending, status(o.ID))
+	})
+
+	t.Run("InvalidWebhook", func(t *testing.T) {
+		body := []byte(`{"id":"evt_forged","type":"payment.captured","payment_id":"fake_payment-1"}`)
+		header := http.Header{}
+		header.Set(payment.FakeSignatureHeader, "00")
+		require.Equal(t, http.StatusBadRequest, deliver(body, header))
+
+		// подписано верно, но такого платежа нет
+		body = []byte(`{"id":"evt_unknown","type":"payment.captured","payment_id":"fake_missing"}`)
+		header = sign(body)
+		require.Equal(t, http.StatusNotFound, deliver(body, header))
+	})
+	t.Run("AmountMismatch", func(t *testing.T) {
+		o := newOrder()
+		p, err := payments.Pay(ctx, u.ID, o.ID, owner, &services.PayOrderRequest{Method: payment.FakeMethodAsync})
+		require.NoError(t, err)
+		body := []byte(`{"id":"evt_cheap","type":"payment.captured","payment_id":"` + p.ProviderRef + `","amount_minor":1,"currency":"RUB"}`)
+		require.Equal(t, http.StatusBadRequest, deliver(body, sign(body)))
+		require.Equal(t, models.OrderStatusPending, status(o.ID))
+		require.Equal(t, models.PaymentStatusPending, paymentsOf(o.ID)[0].Status)
+	})
+
+	t.Run("CancelPaidRefunds", func(t *testing.T) {
+		o := newOrder()
+		_, err := payments.Pay(ctx, u.ID, o.ID, owner, &services.PayOrderRequest{})
+		require.NoError(t, err)
+		_, err = orders.Transition(ctx, u.ID, o.ID, admin, &services.OrderTransitionRequest{Status: models.OrderStatusCancelled})
+		require.NoError(t, err)
+		require.Equal(t, models.OrderStatusCancelled, status(o.ID))
+		list := paymentsOf(o.ID)
+		require.Len(t, list, 1)
+		require.Equal(t, models.PaymentStatusRefunded, list[0].Status)
+
+		// оплата, отмеченная вручную, записывается платежом и тоже возвращается при отмене
+		o = newOrder()
+		_, err = orders.Transition(ctx, u.ID, o.ID, admin, &services.OrderTransitionRequest{Status: models.OrderStatusPaid})
+		require.NoError(t, err)
+		list = paymentsOf(o.ID)
+		require.Len(t, list, 1)
+		require.Equal(t, "manual", list[0].Provider)
+		require.Equal(t, models.PaymentStatusCaptured, list[0].Status)
+		require.Equal(t, "39.98", list[0].Amount)
+		_, err = orders.Transition(ctx, u.ID, o.ID, admin, &services.OrderTransitionRequest{Status: models.OrderStatusCancelled})
+		require.NoError(t, err)
+		require.Equal(t, models.PaymentStatusRefunded, paymentsOf(o.ID)[0].Status)
+	})
+
+	t.Run("ExpiredPending", func(t *testing.T) {
+		o := newOrder()
+		late, err := payments.Pay(ctx, u.ID, o.ID, owner, &services.PayOrderRequest{Method: payment.FakeMethodAsync})
+		require.NoError(t, err)
+
+		// уведомление не пришло: после PAYMENT_PENDING_TTL платёж закрывается
+		require.NoError(t, payments.Reconcile(ctx, time.Now()))
+		require.Equal(t, models.PaymentStatusPending, paymentsOf(o.ID)[0].Status)
+		require.NoError(t, payments.Reconcile(ctx, time.Now().Add(testConfig().Payments.PendingTTL+time.Minute)))
+		list := paymentsOf(o.ID)
+		require.Equal(t, models.PaymentStatusFailed, list[0].Status)
+		require.NotEmpty(t, list[0].FailureReason)
+
+		// заказ снова можно оплатить
+		_, err = payments.Pay(ctx, u.ID, o.ID, owner, &services.PayOrderRequest{})
+		require.NoError(t, err)
+		require.Equal(t, models.OrderStatusPaid, status(o.ID))
+
+		// запоздавшее списание истёкшего платежа возвращается
+		body, header, err := provider.Webhook("evt_expired", payment.EventCaptured, late.ProviderRef)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNoContent, deliver(body, header))
+		list = paymentsOf(o.ID)
+		require.Len(t, list, 2)
+		require.Equal(t, models.PaymentStatusRefunded, list[0].Status)
+		require.Equal(t, models.PaymentStatusCaptured, list[1].Status)
+		require.Equal(t, models.OrderStatusPaid, status(o.ID))
+	})
+}
@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"kvant_task/internal/bootstrap"
+	"kvant_task/internal/models"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/pricing"
+	"kvant_task/internal/repositories"
+	"kvant_task/internal/services"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestPricing проверяет встроенные расчёты налога и доставки и разбор тарифов из конфигурации.
+func TestPricing(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Distribute", func(t *testing.T) {
+		require.Equal(t, []int64{0, 0}, pricing.Distribute(0, []int64{100, 200}))
+		require.Equal(t, []int64{33, 67}, pricing.Distribute(100, []int64{100, 200}))
+		// остаток — позициям с наибольшей дробной частью, сумма долей не меняется
+		require.Equal(t, []int64{34, 33, 33}, pricing.Distribute(100, []int64{1, 1, 1}))
+		require.Equal(t, []int64{1, 0}, pricing.Distribute(1, []int64{1, 1}))
+	})
+
+	o := &pricing.Order{Currency: "RUB", Lines: []pricing.Line{
+		{TaxCategory: "", Quantity: 2, WeightGrams: 300, AmountMinor: 1999},
+		{TaxCategory: "reduced", Quantity: 1, WeightGrams: 1500, AmountMinor: 1001},
+		{TaxCategory: "unknown", Quantity: 1, AmountMinor: 500},
+	}}
+
+	t.Run("FlatTax", func(t *testing.T) {
+		lines, err := pricing.FlatTax{Name: "VAT", Rate: 2000}.Tax(ctx, o)
+		require.NoError(t, err)
+		require.Equal(t, []pricing.TaxLine{{Name: "VAT", Rate: 2000, BaseMinor: 3500, AmountMinor: 700}}, lines)
+
+		// половина минимальной единицы округляется вверх
+		lines, err = pricing.FlatTax{Name: "VAT", Rate: 750}.Tax(ctx, &pricing.Order{Lines: []pricing.Line{{AmountMinor: 1000}, {AmountMinor: 100}}})
+		require.NoError(t, err)
+		require.Equal(t, int64(83), lines[0].AmountMinor) // 82.5
+
+		lines, err = pricing.NoTax{}.Tax(ctx, o)
+		require.NoError(t, err)
+		require.Empty(t, lines)
+	})
+
+	t.Run("CategoryTax", func(t *testing.T) {
+		calc := pricing.CategoryTax{Name: "VAT", Default: 2000, Rates: map[string]int{"reduced": 1000, "zero": 0}}
+		lines, err := calc.Tax(ctx, o)
+		require.NoError(t, err)
+		// неизвестная категория облагается по ставке по умолчанию
+		require.Equal(t, []pricing.TaxLine{
+			{Name: "VAT", Rate: 2000, BaseMinor: 2499, AmountMinor: 500},
+			{Name: "reduced", Rate: 1000, BaseMinor: 1001, AmountMinor: 100},
+		}, lines)
+	})
+
+	t.Run("TieredShipping", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.Shipping.Calculator = "weight"
+		cfg.Shipping.Currency = "RUB"
+		cfg.Shipping.Tiers = "1000:199, 5000:349.50, *:599"
+		calc, err := bootstrap.ShippingCalculator(cfg)
+		require.NoError(t, err)
+		fee, err := calc.Shipping(ctx, o) // 2100 г
+		require.NoError(t, err)
+		require.Equal(t, int64(34950), fee)
+		fee, err = calc.Shipping(ctx, &pricing.Order{Currency: "RUB", Lines: []pricing.Line{{Quantity: 1, WeightGrams: 1000}}})
+		require.NoError(t, err)
+		require.Equal(t, int64(19900), fee)
+
+		// тарифы в другой валюте
+		_, err = calc.Shipping(ctx, &pricing.Order{Currency: "USD"})
+		require.ErrorIs(t, err, pricing.ErrShippingUnavailable)
+
+		// бесплатная доставка от 30.00
+		cfg.Shipping.Calculator = "price"
+		cfg.Shipping.Tiers = "29.99:150,*:0"
+		calc, err = bootstrap.ShippingCalculator(cfg)
+		require.NoError(t, err)
+		fee, err = calc.Shipping(ctx, o)
+		require.NoError(t, err)
+		require.Equal(t, int64(0), fee)
+
+		// без тарифа для самых тяжёлых заказов
+		cfg.Shipping.Calculator = "weight"
+		cfg.Shipping.Tiers = "1000:199"
+		calc, err = bootstrap.ShippingCalculator(cfg)
+		require.NoError(t, err)
+		_, err = calc.Shipping(ctx, o)
+		require.ErrorIs(t, err, pricing.ErrShippingUnavailable)
+
+		for _, tiers := range []string{"1000", "abc:100", "1000:1.001", "5000:100,1000:50", "1000:-1"} {
+			cfg.Shipping.Tiers = tiers
+			_, err = bootstrap.ShippingCalculator(cfg)
+			require.Error(t, err, tiers)
+		}
+	})
+}
+
+// TestOrderTaxAndShipping проверяет, что налог и доставка сохраняются в заказе,
+// входят в итог и пересчитываются при смене состава.
+func TestOrderTaxAndShipping(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	ctx := context.Background()
+
+	userSvc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
+	u, err := userSvc.Create(ctx, &services.RegisterRequest{Name: "Buyer", Email: "buyer@example.com", Password: "Tr0ub4dor&3x", Age: 30})
+	require.NoError(t, err)
+
+	require.NoError(t, db.Create(&models.Product{SKU: "BOOK-1", Name: "Book", PriceMinor: 1000, Currency: "RUB", Stock: 10, TaxCategory: "reduced", WeightGrams: 400}).Error)
+	require.NoError(t, db.Create(&models.Product{SKU: "LAMP-1", Name: "Lamp", PriceMinor: 3000, Currency: "RUB", Stock: 10, WeightGrams: 1200}).Error)
+	_, err = services.NewCouponService(db, testConfig()).Create(ctx, &services.CreateCouponRequest{Code: "TEN", Type: models.CouponTypePercent, Percent: 10})
+	require.NoError(t, err)
+
+	tax := pricing.CategoryTax{Name: "VAT", Default: 2000, Rates: map[string]int{"reduced": 1000}}
+	shipping := pricing.TieredShipping{Basis: pricing.ByWeight, Currency: "RUB", Tiers: []pricing.Tier{
+		{UpTo: 1000, FeeMinor: 200},
+		{UpTo: 5000, FeeMinor: 500},
+	}}
+	orders := services.NewOrderService(db, testConfig(), tax, shipping, newTestPaymentProvider())
+
+	o, err := orders.Create(ctx, u.ID, &services.CreateOrderRequest{
+		Items:      []services.OrderItemRequest{{SKU: "BOOK-1", Quantity: 2}, {SKU: "LAMP-1", Quantity: 1}},
+		CouponCode: "TEN",
+	})
+	require.NoError(t, err)
+	// 50.00 − 5.00 скидки: книги 18.00 по 10%, лампа 27.00 по 20%; 2 кг — доставка 5.00
+	require.Equal(t, "50.00", o.Subtotal)
+	require.Equal(t, "5.00", o.Discount)
+	require.Equal(t, []services.TaxLineResponse{
+		{Name: "VAT", Rate: "20", Base: "27.00", Amount: "5.40"},
+		{Name: "reduced", Rate: "10", Base: "18.00", Amount: "1.80"},
+	}, o.TaxLines)
+	require.Equal(t, "7.20", o.Tax)
+	require.Equal(t, "5.00", o.Shipping)
+	require.Equal(t, "57.20", o.Total)
+
+	// суммы и строки налога сохранены
+	got, err := orders.Get(ctx, u.ID, o.ID)
+	require.NoError(t, err)
+	require.Equal(t, o.TaxLines, got.TaxLines)
+	require.Equal(t, o.Total, got.Total)
+
+	// при смене состава налог и доставка пересчитываются
+	o, err = orders.Update(ctx, u.ID, o.ID, services.Actor{UserID: u.ID, Role: models.RoleUser},
+		&services.UpdateOrderRequest{Items: []services.OrderItemRequest{{SKU: "BOOK-1", Quantity: 1}}})
+	require.NoError(t, err)
+	require.Equal(t, "1.00", o.Discount)
+	require.Equal(t, []services.TaxLineResponse{{Name: "reduced", Rate: "10", Base: "9.00", Amount: "0.90"}}, o.TaxLines)
+	require.Equal(t, "2.00", o.Shipping)
+	require.Equal(t, "11.90", o.Total)
+	var lines int64
+	require.NoError(t, db.Model(&models.OrderTaxLine{}).Where("order_id = ?", o.ID).Count(&lines).Error)
+	require.Equal(t, int64(1), lines)
+
+	// без тарифа доставки заказ не создаётся и товар не списывается
+	_, err = orders.Create(ctx, u.ID, &services.CreateOrderRequest{Items: []services.OrderItemRequest{{SKU: "LAMP-1", Quantity: 5}}})
+	require.ErrorIs(t, err, services.ErrShippingUnavailable)
+	var lamp models.Product
+	require.NoError(t, db.Where("sku = ?", "LAMP-1").First(&lamp).Error)
+	require.Equal(t, 10, lamp.Stock)
+
+	// удаление заказа удаляет и строки налога
+	require.NoError(t, repositories.NewOrderRepo(db).Delete(ctx, o.ID))
+	require.NoError(t, db.Model(&models.OrderTaxLine{}).Where("order_id = ?", o.ID).Count(&lines).Error)
+	require.Equal(t, int64(0), lines)
+}
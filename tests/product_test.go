@@ -0,0 +1,174 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"kvant_task/internal/models"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/pricing"
+	"kvant_task/internal/services"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestProductCatalog проверяет CRUD каталога и уникальность артикула.
+func TestProductCatalog(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	ctx := context.Background()
+
+	svc := services.NewProductService(db, testConfig())
+	p, err := svc.Create(ctx, &services.CreateProductRequest{SKU: "TEA-1", Name: "Tea", Price: "4.99", Stock: 3})
+	require.NoError(t, err)
+	require.Equal(t, "4.99", p.Price)
+	require.Equal(t, "RUB", p.Currency)
+
+	_, err = svc.Create(ctx, &services.CreateProductRequest{SKU: "TEA-1", Name: "Other tea", Price: "3"})
+	require.ErrorIs(t, err, services.ErrSKUTaken)
+
+	// точность цены проверяется по валюте
+	for _, bad := range []services.CreateProductRequest{
+		{SKU: "BAD-1", Name: "Bad", Price: "4.999"},
+		{SKU: "BAD-2", Name: "Bad", Price: "100.5", Currency: "JPY"},
+		{SKU: "BAD-3", Name: "Bad", Price: "0"},
+		{SKU: "BAD-4", Name: "Bad", Price: "1e3"},
+		{SKU: "BAD-5", Name: "Bad", Price: "10", Currency: "XXX"},
+	} {
+		_, err = svc.Create(ctx, &bad)
+		require.ErrorIs(t, err, services.ErrInvalidProduct, bad.SKU)
+	}
+	kwd, err := svc.Create(ctx, &services.CreateProductRequest{SKU: "OUD-1", Name: "Oud", Price: "12.345", Currency: "kwd"})
+	require.NoError(t, err)
+	require.Equal(t, "12.345", kwd.Price)
+	require.Equal(t, "KWD", kwd.Currency)
+	require.NoError(t, svc.Delete(ctx, kwd.ID))
+
+	// смена валюты без цены отклоняется
+	usd := "USD"
+	_, err = svc.Update(ctx, p.ID, &services.UpdateProductRequest{Currency: &usd})
+	require.ErrorIs(t, err, services.ErrInvalidProduct)
+
+	price, stock := "6.50", 10
+	updated, err := svc.Update(ctx, p.ID, &services.UpdateProductRequest{Price: &price, Stock: &stock})
+	require.NoError(t, err)
+	require.Equal(t, "6.50", updated.Price)
+	require.Equal(t, 10, updated.Stock)
+	require.Equal(t, "Tea", updated.Name)
+
+	list, total, err := svc.List(ctx, 1, 10)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Len(t, list, 1)
+
+	require.NoError(t, svc.Delete(ctx, p.ID))
+	_, err = svc.Get(ctx, p.ID)
+	require.ErrorIs(t, err, services.ErrProductNotFound)
+	require.ErrorIs(t, svc.Delete(ctx, p.ID), services.ErrProductNotFound)
+}
+
+// TestOrderStock проверяет, что заказ берёт цену из каталога, списывает остатки,
+// не допускает продажи сверх остатка (в том числе параллельными заказами)
+// и возвращает товар на склад при отмене.
+func TestOrderStock(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	ctx := context.Background()
+
+	userSvc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
+	user, err := userSvc.Create(ctx, &services.RegisterRequest{
+		Name:     "Stock Buyer",
+		Email:    "stock@example.com",
+		Password: "Tr0ub4dor&3x",
+		Age:      30,
+	})
+	require.NoError(t, err)
+
+	cup := createTestProduct(t, db, "CUP-1", "Cup", 725, 5)
+	orders := services.NewOrderService(db, testConfig(), pricing.NoTax{}, pricing.NoShipping{}, newTestPaymentProvider())
+	products := services.NewProductService(db, testConfig())
+	stockOf := func() int {
+		var p models.Product
+		require.NoError(t, db.First(&p, cup.ID).Error)
+		return p.Stock
+	}
+
+	// один товар в двух позициях: остаток проверяется по сумме
+	o, err := orders.Create(ctx, user.ID, &services.CreateOrderRequest{Items: []services.OrderItemRequest{
+		{ProductID: cup.ID, Quantity: 2},
+		{SKU: "CUP-1", Quantity: 1},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, "7.25", o.Items[0].Price)
+	require.Equal(t, "21.75", o.Total)
+	require.Equal(t, 2, stockOf())
+
+	_, err = orders.Create(ctx, user.ID, &services.CreateOrderRequest{Items: []services.OrderItemRequest{
+		{SKU: "CUP-1", Quantity: 3},
+	}})
+	require.ErrorIs(t, err, services.ErrInsufficientStock)
+
+	// товары в разных валютах в одном заказе не смешиваются
+	mug := &models.Product{SKU: "MUG-1", Name: "Mug", PriceMinor: 500, Currency: "USD", Stock: 5}
+	require.NoError(t, db.Create(mug).Error)
+	_, err = orders.Create(ctx, user.ID, &services.CreateOrderRequest{Items: []services.OrderItemRequest{
+		{SKU: "CUP-1", Quantity: 1},
+		{SKU: "MUG-1", Quantity: 1},
+	}})
+	require.ErrorIs(t, err, services.ErrMixedCurrencies)
+	require.Equal(t, 2, stockOf())
+
+	// из параллельных заказов проходят только те, на которые хватает остатка
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = orders.Create(ctx, user.ID, &services.CreateOrderRequest{Items: []services.OrderItemRequest{
+				{ProductID: cup.ID, Quantity: 1},
+			}})
+		}(i)
+	}
+	wg.Wait()
+	created := 0
+	for _, err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+		require.ErrorIs(t, err, services.ErrInsufficientStock)
+	}
+	require.Equal(t, 2, created)
+	require.Equal(t, 0, stockOf())
+
+	// отмена возвращает товар на склад
+	_, err = orders.Transition(ctx, user.ID, o.ID, services.Actor{UserID: user.ID, Role: models.RoleUser},
+		&services.OrderTransitionRequest{Status: models.OrderStatusCancelled})
+	require.NoError(t, err)
+	require.Equal(t, 3, stockOf())
+
+	// изменение товара параллельно с заказами не возвращает списанный остаток
+	errs = make([]error, 6)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				name := fmt.Sprintf("Cup v%d", i)
+				_, errs[i] = products.Update(ctx, cup.ID, &services.UpdateProductRequest{Name: &name})
+				return
+			}
+			_, errs[i] = orders.Create(ctx, user.ID, &services.CreateOrderRequest{Items: []services.OrderItemRequest{
+				{ProductID: cup.ID, Quantity: 1},
+			}})
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, 0, stockOf())
+}
@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"kvant_task/internal/handlers"
+	"kvant_task/internal/models"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/repositories"
+	"kvant_task/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReports проверяет сводку заказов пользователя и отчёт о выручке:
+// в отчёты входят только оплаченные заказы, суммы считаются по каждой валюте.
+func TestReports(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	ctx := context.Background()
+
+	userSvc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
+	register := func(name, email string) uint {
+		u, err := userSvc.Create(ctx, &services.RegisterRequest{Name: name, Email: email, Password: "Tr0ub4dor&3x", Age: 30})
+		require.NoError(t, err)
+		return u.ID
+	}
+	alice := register("Alice", "alice@example.com")
+	bob := register("Bob", "bob@example.com")
+	newbie := register("Newbie", "newbie@example.com")
+
+	pen := createTestProduct(t, db, "PEN-1", "Pen", 150, 100)
+	lamp := createTestProduct(t, db, "LAMP-1", "Lamp", 5000, 100)
+
+	repo := repositories.NewOrderRepo(db)
+	add := func(userID uint, at time.Time, status, currency string, items ...models.OrderItem) {
+		var total int64
+		for _, it := range items {
+			total += it.PriceMinor * int64(it.Quantity)
+		}
+		require.NoError(t, repo.Create(ctx, &repositories.Order{
+			UserID:        userID,
+			Items:         items,
+			Currency:      currency,
+			SubtotalMinor: total,
+			TotalMinor:    total,
+			Status:        status,
+			CreatedAt:     at,
+		}))
+	}
+	item := func(p *models.Product, qty int) models.OrderItem {
+		return models.OrderItem{ProductID: &p.ID, SKU: p.SKU, Product: p.Name, Quantity: qty, PriceMinor: p.PriceMinor}
+	}
+	jan := func(day, hour int) time.Time { return time.Date(2024, 1, day, hour, 0, 0, 0, time.UTC) }
+
+	add(alice, jan(1, 10), models.OrderStatusPaid, "RUB", item(pen, 2))                     // 3.00
+	add(alice, jan(1, 18), models.OrderStatusDelivered, "RUB", item(lamp, 1), item(pen, 1)) // 51.50
+	add(alice, jan(9, 12), models.OrderStatusShipped, "RUB", item(pen, 1))                  // 1.50
+	add(alice, jan(15, 8), models.OrderStatusCancelled, "RUB", item(lamp, 4))               // не считается
+	add(alice, jan(20, 8), models.OrderStatusPending, "RUB", item(lamp, 1))                 // не считается
+	add(alice, jan(31, 23), models.OrderStatusPaid, "USD", models.OrderItem{SKU: "X", Product: "Import", Quantity: 1, PriceMinor: 999})
+	add(bob, jan(2, 9), models.OrderStatusPaid, "RUB", item(lamp, 2)) // 100.00
+	add(bob, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), models.OrderStatusPaid, "RUB", item(pen, 1))
+
+	reportH := handlers.NewReportHandler(db)
+	r := gin.New()
+	r.GET("/users/:id/orders/summary", reportH.UserSummary)
+	r.GET("/reports/revenue", reportH.Revenue)
+
+	get := func(path string, out interface{}) int {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if out != nil {
+			_ = json.Unmarshal(w.Body.Bytes(), out)
+		}
+		return w.Code
+	}
+
+	t.Run("UserSummary", func(t *testing.T) {
+		var s services.OrderSummaryResponse
+		require.Equal(t, http.StatusOK, get(fmt.Sprintf("/users/%d/orders/summary", alice), &s))
+		require.EqualValues(t, 4, s.OrderCount)
+		require.True(t, jan(1, 10).Equal(*s.FirstOrderAt))
+		require.True(t, jan(31, 23).Equal(*s.LastOrderAt))
+		require.Equal(t, []services.CurrencySpending{
+			{Currency: "RUB", OrderCount: 3, TotalSpent: "56.00", AverageOrder: "18.67"},
+			{Currency: "USD", OrderCount: 1, TotalSpent: "9.99", AverageOrder: "9.99"},
+		}, s.Currencies)
+	})
+
+	t.Run("UserSummary_NoOrders", func(t *testing.T) {
+		var s services.OrderSummaryResponse
+		require.Equal(t, http.StatusOK, get(fmt.Sprintf("/users/%d/orders/summary", newbie), &s))
+		require.Zero(t, s.OrderCount)
+		require.Nil(t, s.FirstOrderAt)
+		require.Empty(t, s.Currencies)
+	})
+
+	t.Run("UserSummary_NotFound", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, get("/users/999999/orders/summary", nil))
+		require.Equal(t, http.StatusBadRequest, get("/users/abc/orders/summary", nil))
+	})
+
+	revenue := func(query url.Values) (int, services.RevenueReport) {
+		var rep services.RevenueReport
+		code := get("/reports/revenue?"+query.Encode(), &rep)
+		return code, rep
+	}
+
+	t.Run("RevenueByDay", func(t *testing.T) {
+		code, rep := revenue(url.Values{"from": {"2024-01-01"}, "to": {"2024-01-31"}, "currency": {"RUB"}})
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "day", rep.Period)
+		require.True(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC).Equal(rep.To))
+		type bucket struct {
+			start   time.Time
+			count   int64
+			revenue string
+		}
+		var got []bucket
+		for _, b := range rep.Buckets {
+			require.Equal(t, "RUB", b.Currency)
+			got = append(got, bucket{b.Start.UTC(), b.OrderCount, b.Revenue})
+		}
+		require.Equal(t, []bucket{
+			{jan(1, 0), 2, "54.50"},
+			{jan(2, 0), 1, "100.00"},
+			{jan(9, 0), 1, "1.50"},
+		}, got)
+	})
+
+	t.Run("RevenueByMonth", func(t *testing.T) {
+		code, rep := revenue(url.Values{"from": {"2024-01-01"}, "to": {"2024-02-29"}, "period": {"month"}})
+		require.Equal(t, http.StatusOK, code)
+		got := map[string]string{}
+		for _, b := range rep.Buckets {
+			got[b.Start.UTC().Format("2006-01")+" "+b.Currency] = b.Revenue
+		}
+		require.Equal(t, map[string]string{
+			"2024-01 RUB": "156.00",
+			"2024-01 USD": "9.99",
+			"2024-02 RUB": "1.50",
+		}, got)
+	})
+
+	t.Run("RevenueByWeekAndProduct", func(t *testing.T) {
+		code, rep := revenue(url.Values{"from": {"2024-01-01"}, "to": {"2024-01-07"}, "period": {"week"}, "group_by": {"product"}})
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, rep.Buckets, 2)
+		// 2024-01-01 — понедельник; внутри периода товары по убыванию выручки
+		for _, b := range rep.Buckets {
+			require.True(t, jan(1, 0).Equal(b.Start))
+		}
+		require.Equal(t, "LAMP-1", rep.Buckets[0].SKU)
+		require.Equal(t, lamp.ID, *rep.Buckets[0].ProductID)
+		require.EqualValues(t, 2, rep.Buckets[0].OrderCount)
+		require.EqualValues(t, 3, rep.Buckets[0].Quantity)
+		require.Equal(t, "150.00", rep.Buckets[0].Revenue)
+		require.Equal(t, "Pen", rep.Buckets[1].Product)
+		require.EqualValues(t, 3, rep.Buckets[1].Quantity)
+		require.Equal(t, "4.50", rep.Buckets[1].Revenue)
+	})
+
+	t.Run("RevenueInvalidParams", func(t *testing.T) {
+		for _, q := range []url.Values{
+			{},
+			{"from": {"2024-01-01"}},
+			{"from": {"2024-02-01"}, "to": {"2024-01-01"}},
+			{"from": {"2024-01-01"}, "to": {"2024-01-31"}, "period": {"year"}},
+			{"from": {"2024-01-01"}, "to": {"2024-01-31"}, "group_by": {"user"}},
+			{"from": {"2024-01-01"}, "to": {"2024-01-31"}, "currency": {"XYZ"}},
+			{"from": {"2000-01-01"}, "to": {"2024-01-31"}},
+			{"from": {"01.01.2024"}, "to": {"2024-01-31"}},
+		} {
+			code, _ := revenue(q)
+			require.Equal(t, http.StatusBadRequest, code, q.Encode())
+		}
+	})
+}
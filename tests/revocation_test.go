@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"kvant_task/internal/revocation"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMemoryRevocationStore проверяет отзыв токенов и удаление истёкших записей
+// в in-memory хранилище, в том числе при конкурентном доступе.
+func TestMemoryRevocationStore(t *testing.T) {
+	ctx := context.Background()
+	store := revocation.NewMemoryStore()
+	now := time.Now()
+
+	require.NoError(t, store.Revoke(ctx, "live", now.Add(time.Hour)))
+	require.NoError(t, store.Revoke(ctx, "expired", now.Add(-time.Minute)))
+
+	revoked, err := store.IsRevoked(ctx, "live")
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = store.IsRevoked(ctx, "unknown")
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	// Purge удаляет только истёкшие записи
+	n, err := store.Purge(ctx, now)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+	revoked, _ = store.IsRevoked(ctx, "live")
+	require.True(t, revoked)
+
+	// конкурентные записи и чтения не должны приводить к гонкам
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = store.Revoke(ctx, "concurrent", now.Add(time.Hour))
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = store.IsRevoked(ctx, "concurrent")
+		}()
+	}
+	wg.Wait()
+}
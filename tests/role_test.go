@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"kvant_task/internal/middleware"
+	"kvant_task/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// setupRoleRouter собирает роутер с Auth и ролевыми middleware поверх заглушек хендлеров.
+func setupRoleRouter() *gin.Engine {
+	r := gin.New()
+	auth := r.Group("/")
+	auth.Use(middleware.Auth(newTestTokenService(), nil, nil))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	auth.GET("/users", middleware.RequireRole(models.RoleAdmin), ok)
+	auth.GET("/users/:id", middleware.RequireSelfOrRole("id", models.RoleAdmin), ok)
+	auth.GET("/users/:id/orders", middleware.RequireSelfOrRole("id", models.RoleAdmin), ok)
+	return r
+}
+
+// TestRoleAuthorization проверяет, что обычный пользователь имеет доступ только к своим
+// ресурсам, а администратор — ко всем.
+func TestRoleAuthorization(t *testing.T) {
+	r := setupRoleRouter()
+	userToken := generateTestToken(1)
+	adminToken := generateTestTokenWithRole(2, models.RoleAdmin)
+
+	cases := []struct {
+		name       string
+		token      string
+		route      string
+		wantStatus int
+	}{
+		{"user lists users", userToken, "/users", http.StatusForbidden},
+		{"admin lists users", adminToken, "/users", http.StatusOK},
+		{"user reads self", userToken, "/users/1", http.StatusOK},
+		{"user reads other", userToken, "/users/2", http.StatusForbidden},
+		{"user reads own orders", userToken, "/users/1/orders", http.StatusOK},
+		{"user reads other orders", userToken, "/users/3/orders", http.StatusForbidden},
+		{"admin reads other", adminToken, "/users/1", http.StatusOK},
+		{"admin reads other orders", adminToken, "/users/1/orders", http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tc.route, nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, tc.wantStatus, w.Code)
+		})
+	}
+}
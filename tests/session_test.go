@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"kvant_task/internal/middleware"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSessions проверяет запись сессий при входе, их список, завершение одной сессии
+// и выход со всех устройств.
+func TestSessions(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	ctx := context.Background()
+
+	tokens := newTestTokenService()
+	svc := services.NewUserService(db, testConfig(), tokens, notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
+	user, err := svc.Create(ctx, &services.RegisterRequest{
+		Name:     "Roamer",
+		Email:    "roamer@example.com",
+		Password: "Tr0ub4dor&3x",
+		Age:      28,
+	})
+	require.NoError(t, err)
+
+	r := gin.New()
+	auth := r.Group("/")
+	auth.Use(middleware.Auth(tokens, nil, services.NewSessionTracker(db, testConfig().Auth.SessionTouchInterval)))
+	auth.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	ping := func(token string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	login := func(ua string) *services.LoginResponse {
+		resp, err := svc.Login(ctx, &services.LoginRequest{Email: "roamer@example.com", Password: "Tr0ub4dor&3x"},
+			services.ClientInfo{IP: "10.0.0.1", UserAgent: ua})
+		require.NoError(t, err)
+		return resp
+	}
+	laptop := login("laptop")
+	phone := login("phone")
+	tablet := login("tablet")
+
+	laptopClaims, err := tokens.Validate(ctx, laptop.Token)
+	require.NoError(t, err)
+	require.NotZero(t, laptopClaims.SessionID)
+
+	t.Run("List", func(t *testing.T) {
+		list, err := svc.ListSessions(ctx, user.ID, laptopClaims.SessionID)
+		require.NoError(t, err)
+		require.Len(t, list, 3)
+		agents := map[string]bool{}
+		for _, s := range list {
+			agents[s.UserAgent] = s.Current
+			require.Equal(t, "10.0.0.1", s.IP)
+		}
+		require.Equal(t, map[string]bool{"laptop": true, "phone": false, "tablet": false}, agents)
+	})
+
+	t.Run("RefreshKeepsSession", func(t *testing.T) {
+		next, err := svc.Refresh(ctx, &services.RefreshRequest{RefreshToken: phone.RefreshToken})
+		require.NoError(t, err)
+		claims, err := tokens.Validate(ctx, next.Token)
+		require.NoError(t, err)
+		phoneClaims, err := tokens.Validate(ctx, phone.Token)
+		require.NoError(t, err)
+		require.Equal(t, phoneClaims.SessionID, claims.SessionID)
+		phone = &services.LoginResponse{TokenResponse: next}
+	})
+
+	t.Run("RevokeOne", func(t *testing.T) {
+		claims, err := tokens.Validate(ctx, phone.Token)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, ping(phone.Token))
+
+		// чужую сессию завершить нельзя
+		require.ErrorIs(t, svc.RevokeSession(ctx, user.ID+1, claims.SessionID), services.ErrNotFound)
+
+		require.NoError(t, svc.RevokeSession(ctx, user.ID, claims.SessionID))
+		require.Equal(t, http.StatusUnauthorized, ping(phone.Token))
+		_, err = svc.Refresh(ctx, &services.RefreshRequest{RefreshToken: phone.RefreshToken})
+		require.Error(t, err)
+		require.Equal(t, http.StatusOK, ping(laptop.Token))
+
+		list, err := svc.ListSessions(ctx, user.ID, 0)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+	})
+
+	t.Run("RevokeAll", func(t *testing.T) {
+		require.NoError(t, svc.RevokeAllSessions(ctx, user.ID))
+		require.Equal(t, http.StatusUnauthorized, ping(laptop.Token))
+		require.Equal(t, http.StatusUnauthorized, ping(tablet.Token))
+		_, err := svc.Refresh(ctx, &services.RefreshRequest{RefreshToken: tablet.RefreshToken})
+		require.Error(t, err)
+
+		list, err := svc.ListSessions(ctx, user.ID, 0)
+		require.NoError(t, err)
+		require.Empty(t, list)
+	})
+}
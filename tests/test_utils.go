@@ -1,14 +1,24 @@
 package tests
 
 import (
+	"context"
 	"fmt"
+	"kvant_task/internal/bootstrap"
+	"kvant_task/internal/config"
+	"kvant_task/internal/lockout"
 	"kvant_task/internal/models"
+	"kvant_task/internal/money"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/password"
+	"kvant_task/internal/payment"
 	"kvant_task/internal/repositories"
+	"kvant_task/internal/revocation"
+	"kvant_task/internal/services"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/stretchr/testify/require"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -28,7 +38,7 @@ func getTestDB(t *testing.T) *gorm.DB {
 	port := getEnv("POSTGRES_PORT", "5432")
 	user := getEnv("POSTGRES_USER", "postgres")
 	pass := getEnv("POSTGRES_PASSWORD", "qwerty")
-	dbname := getEnv("POSTGRES_DB", "rest-api-db") 
+	dbname := getEnv("POSTGRES_DB", "rest-api-db")
 	sslmode := getEnv("POSTGRES_SSLMODE", "disable")
 
 	dsn := fmt.Sprintf(
@@ -46,7 +56,11 @@ func getTestDB(t *testing.T) *gorm.DB {
 		t.Fatalf("gorm.Open вернул nil")
 	}
 
-	require.NoError(t, db.AutoMigrate(&models.User{}, &repositories.Order{}))
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Product{}, &models.Coupon{}, &repositories.Order{}, &models.OrderItem{}, &models.OrderTaxLine{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.OneTimeToken{}, &models.LoginAttempt{}, &models.APIKey{}, &models.Session{}, &models.OrderStatusChange{}, &models.IdempotencyKey{}, &models.CouponRedemption{}, &models.Payment{}, &models.PaymentEvent{}))
+	rub, err := money.Lookup("RUB")
+	require.NoError(t, err)
+	require.NoError(t, bootstrap.ConvertSingleItemOrders(db, rub))
+	require.NoError(t, bootstrap.ConvertMoneyToMinorUnits(db, rub))
 
 	return db
 }
@@ -58,7 +72,7 @@ func GetTestDB(t *testing.T) *gorm.DB {
 
 // cleanUsers очищает таблицы users и orders и сбрасывает последовательности.
 func cleanUsers(t *testing.T, db *gorm.DB) {
-	err := db.Exec("TRUNCATE TABLE orders, users RESTART IDENTITY CASCADE").Error
+	err := db.Exec("TRUNCATE TABLE payment_events, payments, idempotency_keys, coupon_redemptions, coupons, order_tax_lines, order_items, products, order_status_history, sessions, api_keys, login_attempts, one_time_tokens, refresh_tokens, orders, users RESTART IDENTITY CASCADE").Error
 	require.NoError(t, err, "не удалось очистить таблицы users и orders")
 }
 
@@ -67,13 +81,115 @@ func CleanUsers(t *testing.T, db *gorm.DB) {
 	cleanUsers(t, db)
 }
 
-// generateTestToken создаёт JWT токен для тестов сервисов и хендлеров.
-func generateTestToken(userID uint, secret string) string {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour).Unix(),
+// testConfig возвращает конфигурацию для тестов сервисов и хендлеров.
+func testConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.JWT.Algorithm = "HS256"
+	cfg.JWT.Secret = "test-secret"
+	cfg.JWT.Issuer = "kvant_task_test"
+	cfg.JWT.Audience = "kvant_task_test_api"
+	cfg.JWT.AccessTTL = 15 * time.Minute
+	cfg.JWT.RefreshTTL = 24 * time.Hour
+	cfg.Auth.TOTPIssuer = "kvant_task_test"
+	cfg.Auth.TwoFactorChallengeTTL = 5 * time.Minute
+	cfg.Auth.SessionTouchInterval = time.Minute
+	cfg.Lockout.Threshold = 5
+	cfg.Lockout.IPThreshold = 20
+	cfg.Lockout.BaseDelay = 30 * time.Second
+	cfg.Lockout.MaxDelay = time.Hour
+	cfg.Lockout.Window = 15 * time.Minute
+	// дешёвые параметры хэширования, чтобы тесты шли быстро
+	cfg.Password.Algorithm = "argon2id"
+	cfg.Password.Argon2Memory = 1024
+	cfg.Password.Argon2Iterations = 1
+	cfg.Password.Argon2Parallelism = 1
+	cfg.Password.BcryptCost = 4
+	cfg.Password.MinLength = 8
+	cfg.Password.MinCharClasses = 2
+	cfg.Password.RejectPersonal = true
+	cfg.Password.BreachedCheck = true
+	cfg.Money.DefaultCurrency = "RUB"
+	cfg.Idempotency.TTL = 24 * time.Hour
+	cfg.Payments.Provider = "fake"
+	cfg.Payments.WebhookSecret = "test-webhook-secret"
+	cfg.Payments.PendingTTL = 30 * time.Minute
+	return cfg
+}
+
+// newTestGuard создаёт защиту от перебора паролей с in-memory счётчиками.
+func newTestGuard() *lockout.Guard {
+	cfg := testConfig()
+	return lockout.NewGuard(lockout.NewMemoryStore(), lockout.Policy{
+		Threshold:   cfg.Lockout.Threshold,
+		IPThreshold: cfg.Lockout.IPThreshold,
+		BaseDelay:   cfg.Lockout.BaseDelay,
+		MaxDelay:    cfg.Lockout.MaxDelay,
+		Window:      cfg.Lockout.Window,
+	})
+}
+
+// newTestPolicy создаёт политику паролей с тестовой конфигурацией и встроенным списком утёкших паролей.
+func newTestPolicy() *password.Policy {
+	policy, err := bootstrap.PasswordPolicy(testConfig())
+	if err != nil {
+		panic(err)
+	}
+	return policy
+}
+
+// createTestProduct добавляет в каталог товар с ценой в копейках (RUB).
+func createTestProduct(t *testing.T, db *gorm.DB, sku, name string, priceMinor int64, stock int) *models.Product {
+	p := &models.Product{SKU: sku, Name: name, PriceMinor: priceMinor, Currency: "RUB", Stock: stock}
+	require.NoError(t, db.Create(p).Error)
+	return p
+}
+
+// newTestPaymentProvider создаёт встроенный платёжный провайдер с секретом тестовой конфигурации.
+func newTestPaymentProvider() *payment.FakeProvider {
+	return payment.NewFakeProvider(testConfig().Payments.WebhookSecret)
+}
+
+// newTestTokenService создаёт TokenService с тестовой конфигурацией и in-memory отзывом.
+func newTestTokenService() *services.TokenService {
+	tokens, err := services.NewTokenService(testConfig(), revocation.NewMemoryStore())
+	if err != nil {
+		panic(err)
 	}
-	tok, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).
-		SignedString([]byte(secret))
+	return tokens
+}
+
+// generateTestToken создаёт JWT токен для тестов сервисов и хендлеров.
+func generateTestToken(userID uint) string {
+	return generateTestTokenWithRole(userID, models.RoleUser)
+}
+
+// generateTestTokenWithRole создаёт JWT токен с указанной ролью.
+// Токен подписан тем же TokenService, что используется в роутерах тестов.
+func generateTestTokenWithRole(userID uint, role string) string {
+	tok, _, _ := newTestTokenService().Issue(userID, role)
 	return tok
 }
+
+// recordingNotifier запоминает отправленные уведомления для проверки в тестах.
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []notify.Message
+}
+
+// Send запоминает уведомление.
+func (n *recordingNotifier) Send(_ context.Context, msg notify.Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+// last возвращает последнее отправленное уведомление.
+func (n *recordingNotifier) last() notify.Message {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.sent) == 0 {
+		return notify.Message{}
+	}
+	return n.sent[len(n.sent)-1]
+}
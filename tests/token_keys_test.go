@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kvant_task/internal/config"
+	"kvant_task/internal/revocation"
+	"kvant_task/internal/services"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeyPair сохраняет закрытый (PKCS#8) и публичный (PKIX) ключи в PEM-файлы.
+func writeKeyPair(t *testing.T, name string, priv crypto.Signer) (privPath, pubPath string) {
+	dir := t.TempDir()
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(priv.Public())
+	require.NoError(t, err)
+	privPath = filepath.Join(dir, name+".pem")
+	pubPath = filepath.Join(dir, name+".pub.pem")
+	require.NoError(t, os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0o600))
+	require.NoError(t, os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o644))
+	return privPath, pubPath
+}
+
+// asymmetricConfig возвращает тестовую конфигурацию с асимметричной подписью.
+func asymmetricConfig(alg, keyFile, kid string, verification map[string]string) *config.Config {
+	cfg := testConfig()
+	cfg.JWT.Algorithm = alg
+	cfg.JWT.SigningKeyFile = keyFile
+	cfg.JWT.SigningKeyID = kid
+	cfg.JWT.VerificationKeyFiles = verification
+	return cfg
+}
+
+// TestTokenService_AsymmetricKeys проверяет подпись RS256 и EdDSA, заголовок kid,
+// приём токенов старым ключом во время ротации и публикацию JWKS.
+func TestTokenService_AsymmetricKeys(t *testing.T) {
+	ctx := context.Background()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaPriv, rsaPub := writeKeyPair(t, "rsa", rsaKey)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edPriv, _ := writeKeyPair(t, "ed", edKey)
+
+	t.Run("RS256", func(t *testing.T) {
+		tokens, err := services.NewTokenService(asymmetricConfig("RS256", rsaPriv, "rsa-1", nil), revocation.NewMemoryStore())
+		require.NoError(t, err)
+		tok, _, err := tokens.Issue(5, "user")
+		require.NoError(t, err)
+		claims, err := tokens.Validate(ctx, tok)
+		require.NoError(t, err)
+		require.Equal(t, "5", claims.Subject)
+
+		jwks := tokens.JWKS()
+		require.Len(t, jwks.Keys, 1)
+		require.Equal(t, "RSA", jwks.Keys[0].Kty)
+		require.Equal(t, "rsa-1", jwks.Keys[0].Kid)
+		require.Equal(t, "RS256", jwks.Keys[0].Alg)
+		require.NotEmpty(t, jwks.Keys[0].N)
+		require.Equal(t, "AQAB", jwks.Keys[0].E)
+	})
+
+	t.Run("EdDSA_DefaultKid", func(t *testing.T) {
+		tokens, err := services.NewTokenService(asymmetricConfig("EdDSA", edPriv, "", nil), revocation.NewMemoryStore())
+		require.NoError(t, err)
+		tok, _, err := tokens.Issue(6, "user")
+		require.NoError(t, err)
+		_, err = tokens.Validate(ctx, tok)
+		require.NoError(t, err)
+
+		jwks := tokens.JWKS()
+		require.Len(t, jwks.Keys, 1)
+		require.Equal(t, "OKP", jwks.Keys[0].Kty)
+		require.Equal(t, "Ed25519", jwks.Keys[0].Crv)
+		require.NotEmpty(t, jwks.Keys[0].Kid)
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		// старый ключ RSA выпустил токен, новый ключ Ed25519 подписывает,
+		// а старый остаётся в списке ключей проверки
+		oldTokens, err := services.NewTokenService(asymmetricConfig("RS256", rsaPriv, "rsa-1", nil), revocation.NewMemoryStore())
+		require.NoError(t, err)
+		oldTok, _, err := oldTokens.Issue(7, "user")
+		require.NoError(t, err)
+
+		newTokens, err := services.NewTokenService(
+			asymmetricConfig("EdDSA", edPriv, "ed-2", map[string]string{"rsa-1": rsaPub}),
+			revocation.NewMemoryStore(),
+		)
+		require.NoError(t, err)
+		_, err = newTokens.Validate(ctx, oldTok)
+		require.NoError(t, err)
+		require.Len(t, newTokens.JWKS().Keys, 2)
+
+		// без старого ключа токен не принимается
+		edOnly, err := services.NewTokenService(asymmetricConfig("EdDSA", edPriv, "ed-2", nil), revocation.NewMemoryStore())
+		require.NoError(t, err)
+		_, err = edOnly.Validate(ctx, oldTok)
+		require.ErrorIs(t, err, services.ErrInvalidToken)
+	})
+
+	t.Run("Rejects_HS256_InAsymmetricMode", func(t *testing.T) {
+		tokens, err := services.NewTokenService(asymmetricConfig("RS256", rsaPriv, "rsa-1", nil), revocation.NewMemoryStore())
+		require.NoError(t, err)
+		hsTok := generateTestToken(1)
+		_, err = tokens.Validate(ctx, hsTok)
+		require.ErrorIs(t, err, services.ErrInvalidToken)
+		require.Empty(t, newTestTokenService().JWKS().Keys)
+	})
+
+	t.Run("Rejects_KeyAlgorithmMismatch", func(t *testing.T) {
+		_, err := services.NewTokenService(asymmetricConfig("EdDSA", rsaPriv, "x", nil), revocation.NewMemoryStore())
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kvant_task/internal/revocation"
+	"kvant_task/internal/services"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTokenService проверяет выпуск и проверку токенов: набор claims,
+// контроль алгоритма, iss/aud, срока действия и отзыва.
+func TestTokenService(t *testing.T) {
+	ctx := context.Background()
+	tokens := newTestTokenService()
+
+	t.Run("Issue_Validate", func(t *testing.T) {
+		tok, issued, err := tokens.Issue(42, "admin")
+		require.NoError(t, err)
+
+		claims, err := tokens.Validate(ctx, tok)
+		require.NoError(t, err)
+		uid, err := claims.UserID()
+		require.NoError(t, err)
+		require.Equal(t, uint(42), uid)
+		require.Equal(t, "42", claims.Subject)
+		require.Equal(t, "admin", claims.Role)
+		require.Equal(t, issued.Id, claims.Id)
+		require.Equal(t, "kvant_task_test", claims.Issuer)
+		require.Equal(t, "kvant_task_test_api", claims.Audience)
+		require.NotZero(t, claims.IssuedAt)
+		require.NotZero(t, claims.NotBefore)
+	})
+
+	t.Run("Rejects_OtherAlgorithm", func(t *testing.T) {
+		// тот же секрет, но HS512 — алгоритм должен совпадать с настроенным
+		claims := services.Claims{StandardClaims: jwt.StandardClaims{
+			Subject: "1", Id: "x", Issuer: "kvant_task_test", Audience: "kvant_task_test_api",
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		}}
+		tok, err := jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString([]byte("test-secret"))
+		require.NoError(t, err)
+		_, err = tokens.Validate(ctx, tok)
+		require.ErrorIs(t, err, services.ErrInvalidToken)
+
+		none, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		require.NoError(t, err)
+		_, err = tokens.Validate(ctx, none)
+		require.ErrorIs(t, err, services.ErrInvalidToken)
+	})
+
+	t.Run("Rejects_ForeignAudience", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.JWT.Audience = "other_api"
+		other, err := services.NewTokenService(cfg, revocation.NewMemoryStore())
+		require.NoError(t, err)
+		tok, _, err := other.Issue(1, "user")
+		require.NoError(t, err)
+		_, err = tokens.Validate(ctx, tok)
+		require.ErrorIs(t, err, services.ErrInvalidToken)
+	})
+
+	t.Run("Rejects_Expired", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.JWT.AccessTTL = -time.Minute
+		expired, err := services.NewTokenService(cfg, revocation.NewMemoryStore())
+		require.NoError(t, err)
+		tok, _, err := expired.Issue(1, "user")
+		require.NoError(t, err)
+		_, err = tokens.Validate(ctx, tok)
+		require.ErrorIs(t, err, services.ErrInvalidToken)
+	})
+
+	t.Run("Rejects_Revoked", func(t *testing.T) {
+		tok, claims, err := tokens.Issue(7, "user")
+		require.NoError(t, err)
+		require.NoError(t, tokens.Revoke(ctx, claims.Id, claims.ExpiresAtTime()))
+		_, err = tokens.Validate(ctx, tok)
+		require.ErrorIs(t, err, services.ErrTokenRevoked)
+	})
+
+	t.Run("Challenge_NotAccessToken", func(t *testing.T) {
+		challenge, _, err := tokens.IssueChallenge(5)
+		require.NoError(t, err)
+		_, err = tokens.Validate(ctx, challenge)
+		require.ErrorIs(t, err, services.ErrInvalidToken)
+
+		claims, err := tokens.ValidateChallenge(ctx, challenge)
+		require.NoError(t, err)
+		require.Equal(t, "5", claims.Subject)
+
+		access, _, err := tokens.Issue(5, "user")
+		require.NoError(t, err)
+		_, err = tokens.ValidateChallenge(ctx, access)
+		require.ErrorIs(t, err, services.ErrInvalidToken)
+	})
+
+	t.Run("EffectiveRole_RequiresTwoFactor", func(t *testing.T) {
+		require.Equal(t, "user", tokens.EffectiveRole(&services.Claims{}))
+		require.Equal(t, "admin", tokens.EffectiveRole(&services.Claims{Role: "admin"}))
+
+		cfg := testConfig()
+		cfg.Auth.RequireTwoFactorForAdmins = true
+		strict, err := services.NewTokenService(cfg, revocation.NewMemoryStore())
+		require.NoError(t, err)
+		require.Equal(t, "user", strict.EffectiveRole(&services.Claims{Role: "admin", AMR: []string{services.AMRPassword}}))
+		require.Equal(t, "admin", strict.EffectiveRole(&services.Claims{Role: "admin", AMR: []string{services.AMRPassword, services.AMROTP}}))
+	})
+}
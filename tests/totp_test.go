@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"kvant_task/internal/totp"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestTOTP проверяет коды по тестовым векторам RFC 6238 (SHA1, 6 младших цифр),
+// допуск рассинхронизации часов и формат otpauth-ссылки.
+func TestTOTP(t *testing.T) {
+	// base32("12345678901234567890") — секрет из приложения B RFC 6238
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, want := range vectors {
+		got, err := totp.Code(secret, time.Unix(ts, 0))
+		require.NoError(t, err)
+		require.Equal(t, want, got, "t=%d", ts)
+	}
+
+	now := time.Unix(1111111111, 0)
+	prev, err := totp.Code(secret, now.Add(-totp.Period*time.Second))
+	require.NoError(t, err)
+
+	step, ok := totp.Verify(secret, prev, now, 1)
+	require.True(t, ok)
+	require.Equal(t, totp.Step(now)-1, step)
+
+	_, ok = totp.Verify(secret, prev, now, 0)
+	require.False(t, ok)
+	_, ok = totp.Verify(secret, "12345", now, 1)
+	require.False(t, ok)
+	_, ok = totp.Verify("not base32!", "287082", now, 1)
+	require.False(t, ok)
+
+	generated, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	require.Len(t, generated, 32)
+
+	u, err := url.Parse(totp.URI("kvant_task", "alice@example.com", generated))
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", u.Scheme)
+	require.Equal(t, "totp", u.Host)
+	require.Equal(t, "/kvant_task:alice@example.com", u.Path)
+	require.Equal(t, generated, u.Query().Get("secret"))
+	require.Equal(t, "kvant_task", u.Query().Get("issuer"))
+}
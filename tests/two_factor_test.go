@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kvant_task/internal/notify"
+	"kvant_task/internal/services"
+	"kvant_task/internal/totp"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestTwoFactor проверяет подключение TOTP, двухшаговый вход, защиту от повторного
+// использования кода, коды восстановления и отключение 2FA.
+func TestTwoFactor(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	ctx := context.Background()
+
+	tokens := newTestTokenService()
+	svc := services.NewUserService(db, testConfig(), tokens, notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
+
+	user, err := svc.Create(ctx, &services.RegisterRequest{
+		Name:     "Admin",
+		Email:    "2fa@example.com",
+		Password: "Tr0ub4dor&3x",
+		Age:      40,
+	})
+	require.NoError(t, err)
+	login := &services.LoginRequest{Email: "2fa@example.com", Password: "Tr0ub4dor&3x"}
+
+	enroll, err := svc.EnrollTwoFactor(ctx, user.ID)
+	require.NoError(t, err)
+	require.Contains(t, enroll.OTPAuthURI, "otpauth://totp/")
+
+	var recovery []string
+
+	t.Run("Confirm", func(t *testing.T) {
+		_, err := svc.ConfirmTwoFactor(ctx, user.ID, &services.TwoFactorCodeRequest{Code: "000000"})
+		require.ErrorIs(t, err, services.ErrInvalidTwoFactorCode)
+
+		code, err := totp.Code(enroll.Secret, time.Now())
+		require.NoError(t, err)
+		resp, err := svc.ConfirmTwoFactor(ctx, user.ID, &services.TwoFactorCodeRequest{Code: code})
+		require.NoError(t, err)
+		require.Len(t, resp.RecoveryCodes, 10)
+		recovery = resp.RecoveryCodes
+
+		u, err := svc.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		require.True(t, u.TwoFactorEnabled)
+	})
+
+	t.Run("Login_TwoSteps", func(t *testing.T) {
+		first, err := svc.Login(ctx, login, services.ClientInfo{})
+		require.NoError(t, err)
+		require.True(t, first.TwoFactorRequired)
+		require.Nil(t, first.TokenResponse)
+		require.NotEmpty(t, first.ChallengeToken)
+
+		// challenge-токен не даёт доступа к API
+		_, err = tokens.Validate(ctx, first.ChallengeToken)
+		require.ErrorIs(t, err, services.ErrInvalidToken)
+
+		// код следующего шага: код подтверждения подключения уже израсходован
+		code, err := totp.Code(enroll.Secret, time.Now().Add(totp.Period*time.Second))
+		require.NoError(t, err)
+		tok, err := svc.LoginTwoFactor(ctx, &services.TwoFactorLoginRequest{
+			ChallengeToken: first.ChallengeToken,
+			Code:           code,
+		}, services.ClientInfo{})
+		require.NoError(t, err)
+		claims, err := tokens.Validate(ctx, tok.Token)
+		require.NoError(t, err)
+		require.True(t, claims.HasAMR(services.AMROTP))
+
+		// challenge одноразовый, код тоже
+		_, err = svc.LoginTwoFactor(ctx, &services.TwoFactorLoginRequest{
+			ChallengeToken: first.ChallengeToken,
+			Code:           code,
+		}, services.ClientInfo{})
+		require.ErrorIs(t, err, services.ErrInvalidChallenge)
+
+		second, err := svc.Login(ctx, login, services.ClientInfo{})
+		require.NoError(t, err)
+		_, err = svc.LoginTwoFactor(ctx, &services.TwoFactorLoginRequest{
+			ChallengeToken: second.ChallengeToken,
+			Code:           code,
+		}, services.ClientInfo{})
+		require.ErrorIs(t, err, services.ErrInvalidTwoFactorCode)
+
+		// код восстановления принимается один раз
+		_, err = svc.LoginTwoFactor(ctx, &services.TwoFactorLoginRequest{
+			ChallengeToken: second.ChallengeToken,
+			Code:           recovery[0],
+		}, services.ClientInfo{})
+		require.NoError(t, err)
+
+		third, err := svc.Login(ctx, login, services.ClientInfo{})
+		require.NoError(t, err)
+		_, err = svc.LoginTwoFactor(ctx, &services.TwoFactorLoginRequest{
+			ChallengeToken: third.ChallengeToken,
+			Code:           recovery[0],
+		}, services.ClientInfo{})
+		require.ErrorIs(t, err, services.ErrInvalidTwoFactorCode)
+	})
+
+	t.Run("RegenerateRecoveryCodes", func(t *testing.T) {
+		resp, err := svc.RegenerateRecoveryCodes(ctx, user.ID, &services.TwoFactorCodeRequest{Code: recovery[1]})
+		require.NoError(t, err)
+		require.Len(t, resp.RecoveryCodes, 10)
+
+		// старые коды больше не действуют
+		_, err = svc.RegenerateRecoveryCodes(ctx, user.ID, &services.TwoFactorCodeRequest{Code: recovery[2]})
+		require.ErrorIs(t, err, services.ErrInvalidTwoFactorCode)
+		recovery = resp.RecoveryCodes
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		err := svc.DisableTwoFactor(ctx, user.ID, &services.TwoFactorDisableRequest{Password: "wrong", Code: recovery[0]})
+		require.ErrorIs(t, err, services.ErrWrongPassword)
+
+		require.NoError(t, svc.DisableTwoFactor(ctx, user.ID, &services.TwoFactorDisableRequest{
+			Password: "Tr0ub4dor&3x",
+			Code:     recovery[0],
+		}))
+
+		resp, err := svc.Login(ctx, login, services.ClientInfo{})
+		require.NoError(t, err)
+		require.False(t, resp.TwoFactorRequired)
+		require.NotEmpty(t, resp.Token)
+	})
+}
@@ -4,16 +4,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"testing"
 
 	"kvant_task/internal/handlers"
+	"kvant_task/internal/idempotency"
 	"kvant_task/internal/middleware"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/pricing"
+	"kvant_task/internal/router"
 	"kvant_task/internal/services"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
 )
@@ -23,7 +27,8 @@ func setupUserRouter(t *testing.T) *gin.Engine {
 	db := getTestDB(t)
 	cleanUsers(t, db)
 
-	userH := handlers.NewUserHandler(db, "test-secret")
+	tokens := newTestTokenService()
+	userH := handlers.NewUserHandler(db, testConfig(), tokens, notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
 
 	r := gin.New()
 	// Public
@@ -32,7 +37,8 @@ func setupUserRouter(t *testing.T) *gin.Engine {
 
 	// Protected
 	auth := r.Group("/")
-	auth.Use(middleware.Auth("test-secret"))
+	auth.Use(middleware.Auth(tokens, nil, nil))
+	auth.POST("/auth/logout", userH.Logout)
 	auth.GET("/users", userH.List)
 	auth.GET("/users/:id", userH.GetByID)
 	auth.PUT("/users/:id", userH.Update)
@@ -51,7 +57,7 @@ func Test_CreateUser_Success(t *testing.T) {
 		"name":     "Test User",
 		"email":    "test@example.com",
 		"age":      25,
-		"password": "password123",
+		"password": "Tr0ub4dor&3x",
 	}
 	jsonBody, _ := json.Marshal(body)
 
@@ -77,11 +83,11 @@ func Test_Login_Success(t *testing.T) {
 	cleanUsers(t, db)
 
 	// Создаём пользователя напрямую через сервис
-	svc := services.NewUserService(db, "test-secret")
+	svc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
 	created, err := svc.Create(context.Background(), &services.RegisterRequest{
 		Name:     "John",
 		Email:    "john@example.com",
-		Password: "pass1234",
+		Password: "Tr0ub4dor&3x",
 		Age:      28,
 	})
 	require.NoError(t, err)
@@ -89,7 +95,7 @@ func Test_Login_Success(t *testing.T) {
 	// Логинимся
 	loginBody := map[string]string{
 		"email":    "john@example.com",
-		"password": "pass1234",
+		"password": "Tr0ub4dor&3x",
 	}
 	jsonLogin, _ := json.Marshal(loginBody)
 	req, _ := http.NewRequest("POST", "/auth/login", bytes.NewBuffer(jsonLogin))
@@ -102,16 +108,12 @@ func Test_Login_Success(t *testing.T) {
 	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokResp))
 	require.NotEmpty(t, tokResp.Token)
 
-	// Парсим JWT напрямую
-	parsed, err := jwt.Parse(tokResp.Token, func(token *jwt.Token) (interface{}, error) {
-		return []byte("test-secret"), nil
-	})
+	// Проверяем JWT тем же сервисом, что и middleware
+	claims, err := newTestTokenService().Validate(context.Background(), tokResp.Token)
+	require.NoError(t, err)
+	userID, err := claims.UserID()
 	require.NoError(t, err)
-	require.True(t, parsed.Valid)
-	claims := parsed.Claims.(jwt.MapClaims)
-	userIDf, ok := claims["user_id"].(float64)
-	require.True(t, ok)
-	require.Equal(t, float64(created.ID), userIDf)
+	require.Equal(t, created.ID, userID)
 }
 
 func Test_ListUsers(t *testing.T) {
@@ -120,22 +122,22 @@ func Test_ListUsers(t *testing.T) {
 	// Подготовка чистой БД и создание двух пользователей
 	db := getTestDB(t)
 	cleanUsers(t, db)
-	svc := services.NewUserService(db, "test-secret")
+	svc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
 	_, _ = svc.Create(context.Background(), &services.RegisterRequest{
 		Name:     "A",
 		Email:    "a@example.com",
-		Password: "p1",
+		Password: "Tr0ub4dor&3x",
 		Age:      20,
 	})
 	_, _ = svc.Create(context.Background(), &services.RegisterRequest{
 		Name:     "B",
 		Email:    "b@example.com",
-		Password: "p2",
+		Password: "Tr0ub4dor&3x",
 		Age:      30,
 	})
 
 	// Получаем валидный токен
-	token := generateTestToken(1, "test-secret")
+	token := generateTestToken(1)
 
 	req, _ := http.NewRequest("GET", "/users?page=1&limit=10", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
@@ -166,15 +168,15 @@ func Test_GetUpdateDeleteUser(t *testing.T) {
 	cleanUsers(t, db)
 
 	// создаём пользователя
-	svc := services.NewUserService(db, "test-secret")
+	svc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
 	created, err := svc.Create(context.Background(), &services.RegisterRequest{
 		Name:     "C",
 		Email:    "c@example.com",
-		Password: "p3",
+		Password: "Tr0ub4dor&3x",
 		Age:      40,
 	})
 	require.NoError(t, err)
-	token := generateTestToken(created.ID, "test-secret")
+	token := generateTestToken(created.ID)
 
 	// GET /users/:id
 	reqGet, _ := http.NewRequest("GET", "/users/"+strconv.Itoa(int(created.ID)), nil)
@@ -237,11 +239,11 @@ func Test_Endpoints_Unauthorized(t *testing.T) {
 	// создаём пользователя, чтобы знать id
 	db := getTestDB(t)
 	cleanUsers(t, db)
-	svc := services.NewUserService(db, "test-secret")
+	svc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
 	user, err := svc.Create(context.Background(), &services.RegisterRequest{
 		Name:     "ForAuth",
 		Email:    "auth@example.com",
-		Password: "password",
+		Password: "Tr0ub4dor&3x",
 		Age:      30,
 	})
 	require.NoError(t, err)
@@ -361,15 +363,15 @@ func Test_UpdateUser_InvalidData(t *testing.T) {
 	db := getTestDB(t)
 	cleanUsers(t, db)
 
-	svc := services.NewUserService(db, "test-secret")
+	svc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
 	created, err := svc.Create(context.Background(), &services.RegisterRequest{
 		Name:     "Test User",
 		Email:    "test@example.com",
-		Password: "password123",
+		Password: "Tr0ub4dor&3x",
 		Age:      25,
 	})
 	require.NoError(t, err)
-	token := generateTestToken(created.ID, "test-secret")
+	token := generateTestToken(created.ID)
 
 	cases := []struct {
 		name       string
@@ -397,7 +399,7 @@ func Test_UpdateUser_InvalidData(t *testing.T) {
 // Тесты для проверки удаления несуществующего пользователя
 func Test_DeleteUser_NotFound(t *testing.T) {
 	r := setupUserRouter(t)
-	token := generateTestToken(9999, "test-secret") // Несуществующий ID
+	token := generateTestToken(9999) // Несуществующий ID
 
 	req, _ := http.NewRequest("DELETE", "/users/9999", nil)
 	req.Header.Set("Authorization", "Bearer "+token)
@@ -407,3 +409,104 @@ func Test_DeleteUser_NotFound(t *testing.T) {
 	require.Equal(t, http.StatusNotFound, w.Code)
 	require.Contains(t, w.Body.String(), "пользователь не найден")
 }
+
+// Test_Logout_RevokesToken проверяет, что после выхода access-токен отклоняется,
+// а refresh-токен сессии больше нельзя обменять.
+func Test_Logout_RevokesToken(t *testing.T) {
+	r := setupUserRouter(t)
+
+	// регистрация и логин через HTTP
+	body, _ := json.Marshal(map[string]interface{}{
+		"name": "Logout", "email": "logout@example.com", "password": "Tr0ub4dor&3x", "age": 30,
+	})
+	req, _ := http.NewRequest("POST", "/users", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created services.UserResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+
+	body, _ = json.Marshal(map[string]string{"email": "logout@example.com", "password": "Tr0ub4dor&3x"})
+	req, _ = http.NewRequest("POST", "/auth/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var tok services.TokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tok))
+
+	// выход с отзывом refresh-токена
+	body, _ = json.Marshal(map[string]string{"refresh_token": tok.RefreshToken})
+	req, _ = http.NewRequest("POST", "/auth/logout", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+tok.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	// тот же токен больше не принимается
+	req, _ = http.NewRequest("GET", "/users/"+strconv.Itoa(int(created.ID)), nil)
+	req.Header.Set("Authorization", "Bearer "+tok.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Contains(t, w.Body.String(), "токен отозван")
+}
+
+// Test_Login_Lockout проверяет, что после серии неудачных попыток вход
+// блокируется с кодом 429 и заголовком Retry-After даже при верном пароле.
+func Test_Login_Lockout(t *testing.T) {
+	r := setupUserRouter(t)
+	db := getTestDB(t)
+
+	svc := services.NewUserService(db, testConfig(), newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
+	_, err := svc.Create(context.Background(), &services.RegisterRequest{
+		Name:     "Locked",
+		Email:    "locked@example.com",
+		Password: "Tr0ub4dor&3x",
+		Age:      28,
+	})
+	require.NoError(t, err)
+
+	login := func(password string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"email": "locked@example.com", "password": password})
+		req, _ := http.NewRequest("POST", "/auth/login", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < testConfig().Lockout.Threshold; i++ {
+		require.NotEqual(t, http.StatusOK, login("wrongpass").Code)
+	}
+	w := login("Tr0ub4dor&3x")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "30", w.Header().Get("Retry-After"))
+}
+
+// Test_Login_SpoofedForwardedFor проверяет, что подменой X-Forwarded-For нельзя обойти
+// блокировку входа по IP: без доверенных прокси заголовок не меняет IP клиента.
+func Test_Login_SpoofedForwardedFor(t *testing.T) {
+	db := getTestDB(t)
+	cleanUsers(t, db)
+	cfg := testConfig()
+	r := router.New(db, cfg, newTestTokenService(), notify.NewLogNotifier(), newTestGuard(), newTestPolicy(),
+		idempotency.NewMemoryStore(), pricing.NoTax{}, pricing.NoShipping{}, newTestPaymentProvider())
+
+	login := func(i int) int {
+		body, _ := json.Marshal(map[string]string{"email": fmt.Sprintf("spray%d@example.com", i), "password": "wrongpass"})
+		req, _ := http.NewRequest("POST", "/auth/login", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i))
+		req.RemoteAddr = "198.51.100.7:40000"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	for i := 0; i < cfg.Lockout.IPThreshold; i++ {
+		require.NotEqual(t, http.StatusTooManyRequests, login(i))
+	}
+	require.Equal(t, http.StatusTooManyRequests, login(cfg.Lockout.IPThreshold))
+}
@@ -6,9 +6,9 @@ import (
 	"testing"
 	"time"
 
+	"kvant_task/internal/notify"
 	"kvant_task/internal/services"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/stretchr/testify/require"
 )
 
@@ -18,7 +18,8 @@ func TestUserService(t *testing.T) {
 	db := getTestDB(t)
 	cleanUsers(t, db)
 
-	svc := services.NewUserService(db, "test-secret")
+	tokens := newTestTokenService()
+	svc := services.NewUserService(db, testConfig(), tokens, notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
 
 	// 1. Create success
 	t.Run("Create_Success", func(t *testing.T) {
@@ -27,7 +28,7 @@ func TestUserService(t *testing.T) {
 		u, err := svc.Create(context.Background(), &services.RegisterRequest{
 			Name:     "Alice",
 			Email:    "alice@example.com",
-			Password: "password123",
+			Password: "Tr0ub4dor&3x",
 			Age:      25,
 		})
 		require.NoError(t, err)
@@ -44,7 +45,7 @@ func TestUserService(t *testing.T) {
 		_, err := svc.Create(context.Background(), &services.RegisterRequest{
 			Name:     "Alice",
 			Email:    "alice@example.com",
-			Password: "password123",
+			Password: "Tr0ub4dor&3x",
 			Age:      25,
 		})
 		require.ErrorIs(t, err, services.ErrUserExists)
@@ -56,24 +57,47 @@ func TestUserService(t *testing.T) {
 		// Убедимся, что возвращается корректный JWT-токен.
 		tokResp, err := svc.Login(context.Background(), &services.LoginRequest{
 			Email:    "alice@example.com",
-			Password: "password123",
-		})
+			Password: "Tr0ub4dor&3x",
+		}, services.ClientInfo{})
 		require.NoError(t, err)
 		require.NotEmpty(t, tokResp.Token)
 
-		parsed, err := jwt.Parse(tokResp.Token, func(token *jwt.Token) (interface{}, error) {
-			return []byte("test-secret"), nil
-		})
+		claims, err := tokens.Validate(context.Background(), tokResp.Token)
 		require.NoError(t, err)
-		require.True(t, parsed.Valid)
-		claims := parsed.Claims.(jwt.MapClaims)
-		uid, ok := claims["user_id"].(float64)
-		require.True(t, ok)
-		require.Equal(t, float64(1), uid)
-		// exp should be ~now+72h
-		exp, ok := claims["exp"].(float64)
-		require.True(t, ok)
-		require.True(t, int64(exp) > time.Now().Unix())
+		uid, err := claims.UserID()
+		require.NoError(t, err)
+		require.Equal(t, uint(1), uid)
+		require.NotEmpty(t, claims.Id)
+		// exp should be ~now+AccessTTL
+		require.True(t, claims.ExpiresAt > time.Now().Unix())
+	})
+
+	// 3.1 Refresh rotation
+	t.Run("Refresh_Rotation", func(t *testing.T) {
+		// Проверяем, что refresh-токен обменивается на новую пару,
+		// а повторное использование старого токена отзывает всё семейство.
+		first, err := svc.Login(context.Background(), &services.LoginRequest{
+			Email:    "alice@example.com",
+			Password: "Tr0ub4dor&3x",
+		}, services.ClientInfo{})
+		require.NoError(t, err)
+		require.NotEmpty(t, first.RefreshToken)
+
+		second, err := svc.Refresh(context.Background(), &services.RefreshRequest{RefreshToken: first.RefreshToken})
+		require.NoError(t, err)
+		require.NotEmpty(t, second.Token)
+		require.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+		// старый токен уже обменян — это повторное использование
+		_, err = svc.Refresh(context.Background(), &services.RefreshRequest{RefreshToken: first.RefreshToken})
+		require.ErrorIs(t, err, services.ErrRefreshTokenReused)
+
+		// семейство отозвано, новый токен тоже больше не работает
+		_, err = svc.Refresh(context.Background(), &services.RefreshRequest{RefreshToken: second.RefreshToken})
+		require.ErrorIs(t, err, services.ErrInvalidRefreshToken)
+
+		_, err = svc.Refresh(context.Background(), &services.RefreshRequest{RefreshToken: "unknown"})
+		require.ErrorIs(t, err, services.ErrInvalidRefreshToken)
 	})
 
 	// 4. Login invalid
@@ -81,7 +105,7 @@ func TestUserService(t *testing.T) {
 		_, err := svc.Login(context.Background(), &services.LoginRequest{
 			Email:    "alice@example.com",
 			Password: "wrongpass",
-		})
+		}, services.ClientInfo{})
 		require.ErrorIs(t, err, services.ErrInvalidCredentials)
 	})
 
@@ -92,7 +116,7 @@ func TestUserService(t *testing.T) {
 			_, err := svc.Create(context.Background(), &services.RegisterRequest{
 				Name:     fmt.Sprintf("User%d", i+1),
 				Email:    fmt.Sprintf("user%d@example.com", i+1),
-				Password: "pwd",
+				Password: "Tr0ub4dor&3x",
 				Age:      age,
 			})
 			require.NoError(t, err)
@@ -8,6 +8,8 @@ import (
 
 	"kvant_task/internal/handlers"
 	"kvant_task/internal/middleware"
+	"kvant_task/internal/notify"
+	"kvant_task/internal/pricing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
@@ -19,8 +21,9 @@ func setupTestRouter(t *testing.T) *gin.Engine {
 	db := GetTestDB(t)
 	CleanUsers(t, db)
 
-	userHandler := handlers.NewUserHandler(db, "test-secret")
-	orderHandler := handlers.NewOrderHandler(db)
+	tokens := newTestTokenService()
+	userHandler := handlers.NewUserHandler(db, testConfig(), tokens, notify.NewLogNotifier(), newTestGuard(), newTestPolicy())
+	orderHandler := handlers.NewOrderHandler(db, testConfig(), pricing.NoTax{}, pricing.NoShipping{}, newTestPaymentProvider())
 
 	r := gin.New()
 	// эндпоинты без авторизации
@@ -28,7 +31,7 @@ func setupTestRouter(t *testing.T) *gin.Engine {
 
 	// Группа с авторизацией
 	auth := r.Group("/")
-	auth.Use(middleware.Auth("test-secret"))
+	auth.Use(middleware.Auth(tokens, nil, nil))
 	auth.GET("/users/:id", userHandler.GetByID)
 	auth.DELETE("/users/:id", userHandler.Delete)
 	auth.POST("/users/:id/orders", orderHandler.CreateForUser)
@@ -40,7 +43,7 @@ func setupTestRouter(t *testing.T) *gin.Engine {
 func TestNegativeIDValidation(t *testing.T) {
 	r := setupTestRouter(t)
 
-	token := generateTestToken(1, "test-secret")
+	token := generateTestToken(1)
 
 	// GetByID c отрицательным ID
 	req, _ := http.NewRequest(http.MethodGet, "/users/-1", nil)
@@ -73,7 +76,7 @@ func TestNegativeIDValidation(t *testing.T) {
 func TestCreateOrderForNonExistentUser(t *testing.T) {
 	r := setupTestRouter(t)
 
-	token := generateTestToken(999, "test-secret")
+	token := generateTestToken(999)
 
 	req, _ := http.NewRequest(http.MethodPost, "/users/999/orders", nil)
 	req.Header.Set("Authorization", "Bearer "+token)